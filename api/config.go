@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config is the lambda configuration, loaded from environment variables set by the CloudFormation template
+type Config struct {
+	// The name of the environment, which is live in production
+	Platform string `env:"PLATFORM" required:"true"`
+	// Build information, used if it was not set at compile time
+	Release   string `env:"RELEASE"`
+	Commit    string `env:"COMMIT"`
+	Branch    string `env:"BRANCH"`
+	BuildTime string `env:"BUILD_TIME"`
+	// The max-age of successful responses
+	CacheTTL time.Duration `env:"CACHE_TTL" default:"60s"`
+	// The time reserved at the end of an invocation for returning the response
+	DeadlineMargin time.Duration `env:"DEADLINE_MARGIN" default:"500ms"`
+	// The time remaining below which the health check warns
+	MinRemainingTime time.Duration `env:"MIN_REMAINING_TIME" default:"1s"`
+	// If true, panic messages are returned to clients on platforms other than live. They never are on live
+	Debug bool `env:"DEBUG" default:"false"`
+	// Set to stdout to write trace spans to standard output
+	Tracing          string `env:"TRACING"`
+	MetricsNamespace string `env:"METRICS_NAMESPACE" default:"SampleAPI"`
+	// The access log format: combined, json or a text/template of an accesslog.Entry
+	AccessLogFormat string `env:"ACCESS_LOG_FORMAT" default:"json"`
+	// Headers whose values are never written to the access log, as well as the accesslog.CredentialHeaders
+	RedactHeaders []string `env:"REDACT_HEADERS"`
+	// Query parameters whose values are never written to the access log
+	RedactQuery []string `env:"REDACT_QUERY"`
+	// The fraction of successful requests written to the access log. Errors are always written
+	AccessLogSampleRate float64 `env:"ACCESS_LOG_SAMPLE_RATE" default:"1"`
+}
+
+func (config Config) Validate() error {
+
+	var problems []string
+
+	if config.Tracing != "" && config.Tracing != "stdout" {
+		problems = append(problems, fmt.Sprintf("TRACING must be empty or stdout, not %v", config.Tracing))
+	}
+
+	if config.CacheTTL < 0 || config.CacheTTL%time.Second != 0 {
+		problems = append(problems, fmt.Sprintf("CACHE_TTL must be a whole number of seconds, not %v", config.CacheTTL))
+	}
+
+	if config.AccessLogSampleRate <= 0 || config.AccessLogSampleRate > 1 {
+		problems = append(problems, fmt.Sprintf("ACCESS_LOG_SAMPLE_RATE must be greater than 0 and at most 1, not %v", config.AccessLogSampleRate))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Getenv returns the value of an environment variable of the build information
+func (config Config) Getenv(key string) string {
+
+	switch key {
+	case "RELEASE":
+		return config.Release
+	case "COMMIT":
+		return config.Commit
+	case "BRANCH":
+		return config.Branch
+	case "BUILD_TIME":
+		return config.BuildTime
+	}
+
+	return ""
+}
@@ -3,35 +3,92 @@
 package front
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/health"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/metrics"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
 )
 
+// The Operations interface and the routing of requests to its methods are generated from the API definition
+//go:generate go run ../../cmd/routes --template ../../api.yaml --out front_routes.go
+
+// The default time reserved at the end of a lambda invocation for building and returning the response
+const DefaultDeadlineMargin = 500 * time.Millisecond
+
 type Front struct {
-	status      models.Status
-	router      func(route string) innerHandler
-	cacheMaxAge int
+	status         models.Status
+	router         func(route string) innerHandler
+	cacheMaxAge    int
+	deadlineMargin time.Duration
+	metrics        *metrics.Logger
+	tracer         trace.Tracer
+	panicReporters []PanicReporter
+	debug          bool
+	middleware     []Middleware
+	healthChecks   []health.Check
 }
 
-type FrontHandler func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
-type innerHandler func(request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+type FrontHandler func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+type innerHandler func(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+
+// A response is data returned by an innerHandler with a status code other than 200 or extra headers
+type response struct {
+	data       interface{}
+	statusCode int
+	// Headers which are added to or replace the default headers
+	headers map[string]string
+	// A body returned as it is instead of data encoded as JSON, such as HTML
+	body string
+}
+
+// A Middleware wraps a FrontHandler to add behaviour around every request
+type Middleware func(next FrontHandler) FrontHandler
+
+// An Option configures optional behaviour of a Front object
+type Option func(front *Front)
+
+// WithMiddleware adds middleware around the handling of every request. The first middleware added is the outermost
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(front *Front) {
+		front.middleware = append(front.middleware, middleware...)
+	}
+}
+
+// WithDeadlineMargin sets the time reserved at the end of a lambda invocation for returning the response
+//
+// Requests arriving with less than this time remaining are rejected with a 504, and handlers receive a context
+// whose deadline falls this much before the lambda deadline
+func WithDeadlineMargin(margin time.Duration) Option {
+	return func(front *Front) {
+		front.deadlineMargin = margin
+	}
+}
 
 // NewFront Create a new Front object
 //
-func NewFront(status models.Status, cacheMaxAge int) Front {
+func NewFront(status models.Status, cacheMaxAge int, opts ...Option) Front {
 
 	f := Front{
-		status:      status,
-		cacheMaxAge: cacheMaxAge,
+		status:         status,
+		cacheMaxAge:    cacheMaxAge,
+		deadlineMargin: DefaultDeadlineMargin,
+	}
+
+	for _, opt := range opts {
+		opt(&f)
 	}
 
 	f.router = f.getHandlerForRoute
@@ -39,39 +96,105 @@ func NewFront(status models.Status, cacheMaxAge int) Front {
 	return f
 }
 
-// Receive a APIGatewayProxyRequest and returns a APIGatewayProxyResponse with nil error
+// Receive a context and APIGatewayProxyRequest and returns a APIGatewayProxyResponse with nil error
 //
-// Any panic should be recovered and wrapped into an ApiErrorBody, and the trace logged
-func (front Front) Handler(request events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse, err error) {
+// The context is passed through to the routed handler with its deadline brought forward by the deadline margin.
+// Any panic should be recovered, the trace logged and reported, and a generic ApiErrorBody returned with an incident ID
+func (front Front) Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+	handler := FrontHandler(front.handle)
+
+	for i := len(front.middleware) - 1; i >= 0; i-- {
+		handler = front.middleware[i](handler)
+	}
+
+	return handler(ctx, request)
+}
+
+func (front Front) handle(ctx context.Context, request events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse, err error) {
+
+	route := getRoute(request)
+
+	ctx, finishMetrics := front.startMetrics(ctx, route)
+
+	defer func() {
+		finishMetrics(response)
+	}()
+
+	ctx, endSpan := front.startSpan(ctx, request, route)
+
+	defer func() {
+		endSpan(response)
+	}()
 
 	defer func() {
 
 		if r := recover(); r != nil {
-			log.Println(utils.JsonStack(r, debug.Stack()))
-			apiErr := models.ConstructApiError(http.StatusInternalServerError, "%v", r)
-			response = front.buildResponse(nil, apiErr)
+			response = front.buildResponse(nil, front.handlePanic(ctx, route, request, r, debug.Stack()))
 		}
 
 	}()
 
-	route := getRoute(request)
-	log.Printf("Handling a request for %v.\n", route)
+	log.Printf("Handling a request for %v%v.\n", route, requestIdSuffix(ctx))
+
+	if apiErr := front.checkDeadline(ctx); apiErr != nil {
+		response = front.buildResponse(nil, apiErr)
+		return
+	}
 
-	response = front.buildResponse(front.router(route)(request))
+	handlerCtx, cancel := front.handlerContext(ctx)
+	defer cancel()
+
+	response = front.buildResponse(front.invoke(handlerCtx, route, request))
 
 	return
 }
 
-func (front *Front) getHandlerForRoute(route string) innerHandler {
+// Returns an ApiError if the context is already done or has too little time remaining to handle a request
+func (front Front) checkDeadline(ctx context.Context) models.ApiError {
 
-	switch route {
+	if err := ctx.Err(); err != nil {
+		return models.ConstructApiError(http.StatusServiceUnavailable, "Request cancelled: %v", err)
+	}
 
-	case "GET/status":
-		return front.statusHandler
+	deadline, ok := ctx.Deadline()
 
-	case "GET/calc/{op}":
-		return front.calcHandler
+	if !ok {
+		return nil
+	}
 
+	if remaining := time.Until(deadline); remaining < front.deadlineMargin {
+		return models.ConstructApiError(http.StatusGatewayTimeout, "Insufficient time remaining to handle request: %v", remaining)
+	}
+
+	return nil
+}
+
+// Returns a context for handlers whose deadline allows the deadline margin for returning the response
+func (front Front) handlerContext(ctx context.Context) (context.Context, context.CancelFunc) {
+
+	deadline, ok := ctx.Deadline()
+
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-front.deadlineMargin))
+}
+
+func requestIdSuffix(ctx context.Context) string {
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+		return " (request ID " + lc.AwsRequestID + ")"
+	}
+
+	return ""
+}
+
+func (front *Front) getHandlerForRoute(route string) innerHandler {
+
+	if handler := operationHandler(front, route); handler != nil {
+		return handler
 	}
 
 	return front.unknownRouteHandler
@@ -82,7 +205,23 @@ func getRoute(request events.APIGatewayProxyRequest) string {
 	return request.RequestContext.HTTPMethod + request.RequestContext.ResourcePath
 }
 
-func (front Front) unknownRouteHandler(request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+// Returns the value of a request header, matching its name case-insensitively
+func headerValue(headers map[string]string, name string) string {
+
+	if value, ok := headers[name]; ok {
+		return value
+	}
+
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (front Front) unknownRouteHandler(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
 
 	return nil, models.ConstructApiError(http.StatusNotFound, "No such route as %v", getRoute(request))
 }
@@ -96,8 +235,13 @@ func (front *Front) buildResponse(data interface{}, err models.ApiError) events.
 	var (
 		body       string
 		statusCode int
+		custom     response
 	)
 
+	if r, ok := data.(response); ok {
+		custom, data = r, r.data
+	}
+
 	if err != nil {
 
 		body = utils.JsonStringify(err.ErrorBody())
@@ -106,8 +250,16 @@ func (front *Front) buildResponse(data interface{}, err models.ApiError) events.
 
 	} else {
 
-		body = utils.JsonStringify(data)
+		body = custom.body
 		statusCode = http.StatusOK
+
+		if body == "" {
+			body = utils.JsonStringify(data)
+		}
+
+		if custom.statusCode != 0 {
+			statusCode = custom.statusCode
+		}
 	}
 
 	// handle unlikely case where json.Marshall fails for the data argument
@@ -117,12 +269,19 @@ func (front *Front) buildResponse(data interface{}, err models.ApiError) events.
 		log.Printf("ERROR: Returning %v: %v", statusCode, "Unmarshallable data")
 	}
 
+	headers := map[string]string{
+		"Cache-Control":               "max-age=" + strconv.Itoa(front.cacheMaxAge),
+		"Access-Control-Allow-Origin": "*",
+		"X-Timestamp":                 time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	for k, v := range custom.headers {
+		headers[k] = v
+	}
+
 	return events.APIGatewayProxyResponse{
 		Body:       body,
 		StatusCode: statusCode,
-		Headers: map[string]string{
-			"Cache-Control":               "max-age=" + strconv.Itoa(front.cacheMaxAge),
-			"Access-Control-Allow-Origin": "*",
-			"X-Timestamp":                 time.Now().UTC().Format(time.RFC3339Nano),
-		}}
+		Headers:    headers,
+	}
 }
@@ -0,0 +1,50 @@
+package front
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/accesslog"
+)
+
+// AccessLog returns middleware which writes an access log entry for every request
+func AccessLog(logger *accesslog.Logger) Middleware {
+
+	return func(next FrontHandler) FrontHandler {
+
+		return func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+			start := time.Now()
+			response, err := next(ctx, request)
+
+			logger.Log(accessLogEntry(request, response, start, time.Since(start)))
+
+			return response, err
+		}
+	}
+}
+
+func accessLogEntry(request events.APIGatewayProxyRequest, response events.APIGatewayProxyResponse, start time.Time, latency time.Duration) accesslog.Entry {
+
+	identity := request.RequestContext.Identity
+
+	return accesslog.Entry{
+		Time:      start.UTC(),
+		RequestID: request.RequestContext.RequestID,
+		SourceIP:  identity.SourceIP,
+		User:      identity.User,
+		UserAgent: identity.UserAgent,
+		Referer:   headerValue(request.Headers, "Referer"),
+		Method:    request.RequestContext.HTTPMethod,
+		Path:      request.Path,
+		Protocol:  request.RequestContext.Protocol,
+		Route:     getRoute(request),
+		Query:     request.QueryStringParameters,
+		Headers:   request.Headers,
+		Status:    response.StatusCode,
+		Bytes:     len(response.Body),
+		Latency:   latency,
+	}
+}
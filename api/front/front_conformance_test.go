@@ -0,0 +1,38 @@
+package front
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/conformance"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+func TestConformsToTemplate(t *testing.T) {
+
+	Convey("When driving requests generated from the API definition through the handler", t, func() {
+
+		template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+		So(err, ShouldBeNil)
+
+		doc, err := swagger.ParseTemplate(template, nil)
+		So(err, ShouldBeNil)
+
+		harness := conformance.Harness{
+			Definition: doc,
+			Handler:    testFront.Handler,
+			Probes:     []string{"GET/", "GET/calc", "GET/whatever"},
+			Stage:      "test",
+		}
+
+		findings := harness.Run(context.Background())
+
+		Convey("Then the responses should all conform to it", func() {
+			So(findings, ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,153 @@
+package front
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// The OpenAPI definition served by /openapi.json is converted from the API definition, leaving the platform to be set
+// when it is served
+//go:generate go run ../../cmd/convert --template ../../api.yaml --platform "" --out openapi.json
+
+//go:embed openapi.json
+var openAPIJSON []byte
+
+// The documentation page served by /docs, which renders the definition from /openapi.json
+//
+//go:embed docs.html
+var docsPage string
+
+// The prefix of the API Gateway extensions, which are not served as they describe the deployment, not the API
+const gatewayExtensionPrefix = "x-amazon-apigateway-"
+
+// The served definition, without its API Gateway extensions
+var openAPIDefinition, openAPIErr = readOpenAPIDefinition(openAPIJSON)
+
+func readOpenAPIDefinition(raw []byte) (map[string]interface{}, error) {
+
+	var definition map[string]interface{}
+
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		return nil, err
+	}
+
+	removeGatewayExtensions(definition)
+
+	return definition, nil
+}
+
+func removeGatewayExtensions(value interface{}) {
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if strings.HasPrefix(key, gatewayExtensionPrefix) {
+				delete(value, key)
+			} else {
+				removeGatewayExtensions(item)
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			removeGatewayExtensions(item)
+		}
+	}
+}
+
+// GetOpenAPI returns the OpenAPI definition of the API, with the platform in its info and the URL of the stage the
+// request was made to as its server
+func (front Front) GetOpenAPI(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+	if openAPIErr != nil {
+		return nil, models.ConstructApiError(http.StatusInternalServerError, "Invalid OpenAPI definition: %v", openAPIErr)
+	}
+
+	definition := make(map[string]interface{}, len(openAPIDefinition)+1)
+
+	for key, value := range openAPIDefinition {
+		definition[key] = value
+	}
+
+	if info, ok := openAPIDefinition["info"].(map[string]interface{}); ok {
+
+		withPlatform := make(map[string]interface{}, len(info))
+
+		for key, value := range info {
+			if s, ok := value.(string); ok {
+				value = strings.ReplaceAll(s, "{Platform}", front.status.Platform)
+			}
+			withPlatform[key] = value
+		}
+
+		definition["info"] = withPlatform
+	}
+
+	definition["servers"] = []map[string]string{{"url": serverURL(request)}}
+
+	return response{
+		data:    definition,
+		headers: map[string]string{"Content-Type": "application/json"},
+	}, nil
+}
+
+// GetDocs returns an HTML page documenting the API
+func (front Front) GetDocs(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+	return response{
+		body:    docsPage,
+		headers: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+	}, nil
+}
+
+// Returns the URL of the stage a request was made to, from its Host header, or the domain name of its context, and
+// its stage. The stage is only in the URL on the execute-api domain, or where the path of the request starts with it,
+// as a custom domain maps its root to the stage
+func serverURL(request events.APIGatewayProxyRequest) string {
+
+	host := headerValue(request.Headers, "Host")
+
+	if host == "" {
+		host = request.RequestContext.DomainName
+	}
+
+	scheme := headerValue(request.Headers, "X-Forwarded-Proto")
+
+	if scheme == "" {
+		scheme = "https"
+	}
+
+	var url string
+
+	if host != "" {
+		url = scheme + "://" + host
+	}
+
+	if stage := request.RequestContext.Stage; stage != "" && (isExecuteAPIHost(host) || strings.HasPrefix(request.Path, "/"+stage+"/")) {
+		url += "/" + stage
+	}
+
+	if url == "" {
+		return "/"
+	}
+
+	return url
+}
+
+// Returns whether a host is the default domain of an API, such as abc123.execute-api.eu-west-1.amazonaws.com
+func isExecuteAPIHost(host string) bool {
+
+	host = strings.ToLower(host)
+
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+
+	return strings.Contains(host, ".execute-api.") && strings.HasSuffix(host, ".amazonaws.com")
+}
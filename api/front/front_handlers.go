@@ -1,89 +1,91 @@
 package front
 
 import (
-	"strconv"
-	"fmt"
+	"context"
 	"math"
 
 	"golang.org/x/text/message"
 	"golang.org/x/text/language"
 
 	"github.com/aws/aws-lambda-go/events"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/metrics"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
 )
 
-func (front Front) statusHandler(request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+// GetStatus returns the status of the API
+func (front Front) GetStatus(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
 
 	return front.status, nil
 }
 
-func (front Front) calcHandler(request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+// GetCalcOp performs a calculation, formatting the result for the locale of the Accept-Language header
+func (front Front) GetCalcOp(ctx context.Context, request events.APIGatewayProxyRequest, params GetCalcOpParams) (interface{}, models.ApiError) {
 
 	var (
 		result float64
 		fullop string
+		locale string
 	)
 
-	locale, ok := request.Headers["Accept-Language"]
+	if params.AcceptLanguage != nil {
+		locale = *params.AcceptLanguage
+	}
 
 	p := message.NewPrinter(language.Make(locale))
 
-	if !ok {
+	if params.AcceptLanguage == nil {
 		locale = "undefined"
 	}
 
-	op := request.PathParameters["op"]
-
-	val1, err := getFloatFromRequest(request, "val1")
-
-	if err != nil {
-		return nil, models.ConstructApiError(400, err.Error())
-	}
-
-	val2, err := getFloatFromRequest(request, "val2")
-
-	if err != nil {
-		return nil, models.ConstructApiError(400, err.Error())
-	}
+	op := params.Op
+	val1 := params.Val1
+	val2 := params.Val2
 
-	switch op[0:3] {
+	switch op {
 
 	case "add":
 
 		result = val1 + val2
 		fullop = "add"
 
-	case "sub":
+	case "sub", "subtract":
 
 		result = val1 - val2
 		fullop = "subtract"
 
-	case "mul":
+	case "mul", "multiply":
 
 		result = val1 * val2
 		fullop = "multiply"
 
-	case "div":
+	case "div", "divide":
 
 		result = val1 / val2
 		fullop = "divide"
 
-	case "pow":
+	case "pow", "power":
 
 		result = math.Pow(val1, val2)
 		fullop = "power"
 
-	case "roo":
+	case "roo", "root":
 
 		result = math.Pow(val1, 1/val2)
 		fullop = "root"
 
 	default:
 
+		// The routes reject operations outside the enum of the definition, so this is only reached when the method is
+		// called directly, or if the enum gains an operation which is not implemented here
 		return nil, models.ConstructApiError(400, "Unknown calc operation: %v", op)
 	}
 
+	metrics.FromContext(ctx).SetDimension("Op", fullop)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("calc.op", fullop))
+
 	if math.IsNaN(result) || math.IsInf(result, 1) || math.IsInf(result, -1) {
 		return nil, models.ConstructApiError(400, "Out of limits: %v %v %v", val1, fullop, val2)
 	}
@@ -96,17 +98,3 @@ func (front Front) calcHandler(request events.APIGatewayProxyRequest) (interface
 		Result: p.Sprintf("%v", result),
 	}, nil
 }
-
-func getFloatFromRequest(request events.APIGatewayProxyRequest, key string) (result float64, err error) {
-
-	val, ok := request.QueryStringParameters[key]
-
-	if ! ok {
-		err = fmt.Errorf("Missing parameter %v", key)
-		return
-	}
-
-	result, err = strconv.ParseFloat(val, 64)
-
-	return
-}
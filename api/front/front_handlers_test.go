@@ -1,6 +1,10 @@
 package front
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 	"fmt"
 	"strings"
@@ -8,8 +12,14 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/golang/mock/gomock"
 	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/health"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/metrics"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/tracing"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
 )
 
@@ -19,6 +29,11 @@ var testFront = NewFront(models.Status{
 	Commit:    "a00eaaf45694163c9b728a7b5668e3d510eb3eb0",
 	Release:   "v1.0.1",
 	Timestamp: "2019-01-02T14:52:36.951375973Z",
+	BuildTime: "2019-01-02T14:50:00Z",
+	GoVersion: "go1.18.2",
+	Dependencies: []models.Dependency{
+		{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+	},
 }, 123)
 
 
@@ -33,6 +48,11 @@ func TestStatusRoute(t *testing.T) {
 		Commit:    "a00eaaf45694163c9b728a7b5668e3d510eb3eb0",
 		Release:   "v1.0.1",
 		Timestamp: "2019-01-02T14:52:36.951375973Z",
+		BuildTime: "2019-01-02T14:50:00Z",
+		GoVersion: "go1.18.2",
+		Dependencies: []models.Dependency{
+			{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+		},
 	}
 
 	Convey("When sending an request with the /status route", t, func() {
@@ -45,7 +65,7 @@ func TestStatusRoute(t *testing.T) {
 		}
 
 		Convey("Then it should return the status", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, utils.JsonStringify(expected))
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.Headers["Cache-Control"], ShouldEqual, "max-age=123")
@@ -88,7 +108,7 @@ func testCalc(t *testing.T, val1, val2 float64, locale, result, op, fullop strin
 		}
 
 		Convey("Then it should return the correct result", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 
 			// Do not differentiate non-breaking spaces from ordinary spaces for testing purposes
 			body := strings.Replace(response.Body, "\u00A0", " ", -1)
@@ -127,6 +147,10 @@ func TestCalcRouteMultFr(t *testing.T) {
 	testCalc(t, 1.5, 7000, "fr-FR", "10 500", "mul", "multiply")
 }
 
+func TestCalcRouteMultFullEn(t *testing.T) {
+	testCalc(t, 1.5, 7000, "en-GB", "10,500", "multiply", "multiply")
+}
+
 func TestCalcRoutePowEn(t *testing.T) {
 	testCalc(t, 2, 3, "en-GB","8", "pow", "power")
 }
@@ -136,7 +160,7 @@ func TestCalcRouteRootEn(t *testing.T) {
 	testCalc(t, 16, 2, "en-GB","4", "roo", "root")
 }
 
-func testCalcRouteBad(t *testing.T, val1, val2 float64, op, context, msg string) {
+func testCalcRouteBad(t *testing.T, val1, val2 float64, op, scenario, msg string) {
 
 	mockController := gomock.NewController(t)
 	defer mockController.Finish()
@@ -146,7 +170,7 @@ func testCalcRouteBad(t *testing.T, val1, val2 float64, op, context, msg string)
 		Code:    400,
 	}
 
-	Convey(context, t, func() {
+	Convey(scenario, t, func() {
 
 		request := events.APIGatewayProxyRequest{
 			QueryStringParameters: map[string]string{
@@ -166,7 +190,7 @@ func testCalcRouteBad(t *testing.T, val1, val2 float64, op, context, msg string)
 		}
 
 		Convey("Then it should return the correct error", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, utils.JsonStringify(expected))
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.Headers["Cache-Control"], ShouldEqual, "max-age=123")
@@ -177,7 +201,15 @@ func testCalcRouteBad(t *testing.T, val1, val2 float64, op, context, msg string)
 }
 
 func TestCalcRouteBadOp(t *testing.T) {
-	testCalcRouteBad(t, 1,2, "bad", "When sending a request to the /calc route with a bad operator", "Unknown calc operation: bad")
+	testCalcRouteBad(t, 1,2, "bad", "When sending a request to the /calc route with a bad operator", "Invalid parameter op: bad is not one of add, subtract, sub, multiply, mul, divide, div, power, pow, root, roo")
+}
+
+func TestCalcRouteUnknownOp(t *testing.T) {
+	testCalcRouteBad(t, 1,2, "multiplication", "When sending a request to the /calc route with an operator which is not in the API definition", "Invalid parameter op: multiplication is not one of add, subtract, sub, multiply, mul, divide, div, power, pow, root, roo")
+}
+
+func TestCalcRouteShortOp(t *testing.T) {
+	testCalcRouteBad(t, 1,2, "ad", "When sending a request to the /calc route with an operator shorter than 3 letters", "Invalid parameter op: ad is not one of add, subtract, sub, multiply, mul, divide, div, power, pow, root, roo")
 }
 
 func TestCalcRouteInf(t *testing.T) {
@@ -190,4 +222,443 @@ func TestCalcRouteNegInf(t *testing.T) {
 
 func TestCalcRouteNaN(t *testing.T) {
 	testCalcRouteBad(t, -1,2, "root", "When sending a request to the /calc route with NaN result", "Out of limits: -1 root 2")
-}
\ No newline at end of file
+}
+func TestCalcRouteMetrics(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	buf := &bytes.Buffer{}
+
+	metricsFront := NewFront(models.Status{}, 123, WithMetrics(metrics.NewLogger(metrics.Config{
+		Namespace:     "Test",
+		DimensionSets: MetricDimensionSets,
+		BatchSize:     25,
+		Writer:        buf,
+	})))
+
+	Convey("When sending a request to the /calc route with metrics enabled", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{
+				"val1": "1",
+				"val2": "2",
+			},
+			PathParameters: map[string]string{
+				"op": "add",
+			},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/calc/{op}`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		Convey("Then it should log one EMF line of metrics for the route, status class and operation before returning", func() {
+			response, err := metricsFront.Handler(context.Background(), request)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(err, ShouldBeNil)
+
+			line := struct {
+				Route        string
+				StatusClass  string
+				Op           string
+				Requests     float64
+				ServerErrors float64
+				CacheMiss    *float64
+				Aws          struct {
+					CloudWatchMetrics []struct {
+						Dimensions [][]string
+					}
+				} `json:"_aws"`
+			}{}
+
+			So(json.Unmarshal(buf.Bytes(), &line), ShouldBeNil)
+			So(line.Route, ShouldEqual, "GET/calc/{op}")
+			So(line.StatusClass, ShouldEqual, "2xx")
+			So(line.Op, ShouldEqual, "add")
+			So(line.Requests, ShouldEqual, 1)
+			So(line.ServerErrors, ShouldEqual, 0)
+			So(line.CacheMiss, ShouldBeNil)
+			So(strings.Count(buf.String(), "\n"), ShouldEqual, 1)
+			So(line.Aws.CloudWatchMetrics[0].Dimensions, ShouldResemble, MetricDimensionSets)
+		})
+	})
+}
+
+// Returns a front tracing requests to an in-memory exporter
+func tracingFront() (Front, *tracetest.InMemoryExporter) {
+
+	exporter := tracetest.NewInMemoryExporter()
+
+	return NewFront(models.Status{}, 123, WithTracer(tracing.NewProvider(exporter).Tracer(tracing.InstrumentationName))), exporter
+}
+
+// Returns the attributes of an exported span by key
+func spanAttributes(span tracetest.SpanStub) map[string]interface{} {
+
+	attributes := map[string]interface{}{}
+
+	for _, kv := range span.Attributes {
+		attributes[string(kv.Key)] = kv.Value.AsInterface()
+	}
+
+	return attributes
+}
+
+func TestCalcRouteTracing(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	tracingFront, exporter := tracingFront()
+
+	Convey("When sending a traced request to the /calc route", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{
+				"val1": "1",
+				"val2": "2",
+			},
+			PathParameters: map[string]string{
+				"op": "mul",
+			},
+			Headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/calc/{op}`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		Convey("Then it should export request, routing and handler spans continuing the incoming trace", func() {
+			response, err := tracingFront.Handler(context.Background(), request)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(err, ShouldBeNil)
+
+			spans := exporter.GetSpans()
+			So(len(spans), ShouldEqual, 3)
+
+			routeSpan, handlerSpan, requestSpan := spans[0], spans[1], spans[2]
+
+			So(routeSpan.Name, ShouldEqual, "route")
+			So(handlerSpan.Name, ShouldEqual, "handler GET/calc/{op}")
+			So(requestSpan.Name, ShouldEqual, "Handler")
+			So(requestSpan.SpanKind, ShouldEqual, trace.SpanKindServer)
+
+			So(requestSpan.SpanContext.TraceID().String(), ShouldEqual, "4bf92f3577b34da6a3ce929d0e0e4736")
+			So(requestSpan.Parent.SpanID().String(), ShouldEqual, "00f067aa0ba902b7")
+			So(routeSpan.Parent.SpanID(), ShouldEqual, requestSpan.SpanContext.SpanID())
+			So(handlerSpan.Parent.SpanID(), ShouldEqual, requestSpan.SpanContext.SpanID())
+
+			attributes := spanAttributes(requestSpan)
+
+			So(attributes["http.method"], ShouldEqual, "GET")
+			So(attributes["http.route"], ShouldEqual, "/calc/{op}")
+			So(attributes["http.status_code"], ShouldEqual, int64(200))
+			So(attributes, ShouldNotContainKey, "error")
+			So(requestSpan.Status.Code, ShouldEqual, codes.Unset)
+			So(spanAttributes(handlerSpan)["calc.op"], ShouldEqual, "multiply")
+		})
+	})
+}
+
+func TestCalcRouteTracingClientError(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	tracingFront, exporter := tracingFront()
+
+	Convey("When sending a traced request to the /calc route with a bad operator", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			QueryStringParameters: map[string]string{
+				"val1": "1",
+				"val2": "2",
+			},
+			PathParameters: map[string]string{
+				"op": "bad",
+			},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/calc/{op}`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		Convey("Then the handler span should record the error without failing the spans", func() {
+			response, err := tracingFront.Handler(context.Background(), request)
+			So(response.StatusCode, ShouldEqual, 400)
+			So(err, ShouldBeNil)
+
+			spans := exporter.GetSpans()
+			So(len(spans), ShouldEqual, 3)
+			So(spans[1].Status.Code, ShouldEqual, codes.Unset)
+			So(len(spans[1].Events), ShouldEqual, 1)
+			So(spans[1].Events[0].Name, ShouldEqual, "exception")
+			So(spanAttributes(spans[1])["http.status_code"], ShouldEqual, int64(400))
+			So(spans[2].Status.Code, ShouldEqual, codes.Unset)
+			So(spanAttributes(spans[2]), ShouldNotContainKey, "error")
+			So(spanAttributes(spans[2])["http.status_code"], ShouldEqual, int64(400))
+		})
+	})
+}
+
+func TestTracingServerError(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	tracingFront, exporter := tracingFront()
+
+	tracingFront.router = func(route string) innerHandler {
+		return func(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+			return nil, models.ConstructApiError(503, "Simulated outage")
+		}
+	}
+
+	Convey("When a traced request fails with a server error", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		Convey("Then the handler and request spans should have the error status", func() {
+			response, err := tracingFront.Handler(context.Background(), request)
+			So(response.StatusCode, ShouldEqual, 503)
+			So(err, ShouldBeNil)
+
+			spans := exporter.GetSpans()
+			So(len(spans), ShouldEqual, 3)
+			So(spans[1].Status.Code, ShouldEqual, codes.Error)
+			So(spans[1].Status.Description, ShouldEqual, "Simulated outage")
+			So(spans[2].Status.Code, ShouldEqual, codes.Error)
+			So(spanAttributes(spans[2])["error"], ShouldEqual, true)
+			So(spanAttributes(spans[2])["http.status_code"], ShouldEqual, int64(503))
+		})
+	})
+}
+
+func TestHealthRoute(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	request := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			ResourcePath: `/health`,
+			HTTPMethod:   `GET`,
+		},
+	}
+
+	status := models.Status{Release: "v1.0.1", Commit: "a00eaaf456941631"}
+
+	failing := health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("Connection refused")
+	})
+
+	Convey("When sending a request with the /health route", t, func() {
+
+		Convey("Then a healthy service should return pass", func() {
+			healthFront := NewFront(status, 123, WithHealthChecks(
+				health.Check{Name: "config", Checker: health.Required(map[string]string{"COMMIT": status.Commit}), Critical: true},
+			))
+			response, err := healthFront.Handler(context.Background(), request)
+			var report models.Health
+			So(json.Unmarshal([]byte(response.Body), &report), ShouldBeNil)
+			So(report.Status, ShouldEqual, health.StatusPass)
+			So(report.Version, ShouldEqual, "v1.0.1")
+			So(report.ReleaseId, ShouldEqual, "a00eaaf456941631")
+			So(report.Checks["config:responseTime"][0].Status, ShouldEqual, health.StatusPass)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(response.Headers["Content-Type"], ShouldEqual, "application/health+json")
+			So(response.Headers["Cache-Control"], ShouldEqual, "no-store")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then a failing non-critical check should return warn", func() {
+			healthFront := NewFront(status, 123, WithHealthChecks(health.Check{Name: "cache", Checker: failing}))
+			response, _ := healthFront.Handler(context.Background(), request)
+			var report models.Health
+			So(json.Unmarshal([]byte(response.Body), &report), ShouldBeNil)
+			So(report.Status, ShouldEqual, health.StatusWarn)
+			So(report.Checks["cache:responseTime"][0].Output, ShouldEqual, "Connection refused")
+			So(response.StatusCode, ShouldEqual, 200)
+		})
+
+		Convey("Then a failing critical check should return fail with a 503", func() {
+			healthFront := NewFront(status, 123, WithHealthChecks(
+				health.Check{Name: "cache", Checker: failing},
+				health.Check{Name: "database", Checker: failing, Critical: true},
+			))
+			response, _ := healthFront.Handler(context.Background(), request)
+			var report models.Health
+			So(json.Unmarshal([]byte(response.Body), &report), ShouldBeNil)
+			So(report.Status, ShouldEqual, health.StatusFail)
+			So(response.StatusCode, ShouldEqual, 503)
+		})
+	})
+}
+
+func TestCalcRouteParameters(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	calcRequest := func(query map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			QueryStringParameters: query,
+			PathParameters: map[string]string{
+				"op": "add",
+			},
+			Headers: map[string]string{
+				"accept-language": "fr-FR",
+			},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/calc/{op}`,
+				HTTPMethod:   `GET`,
+			},
+		}
+	}
+
+	Convey("When sending a request to the /calc route", t, func() {
+
+		Convey("Then a missing parameter should return a bad request", func() {
+			response, err := testFront.Handler(context.Background(), calcRequest(map[string]string{"val1": "1"}))
+			So(response.Body, ShouldEqual, utils.JsonStringify(models.ApiErrorBody{Message: "Missing parameter val2", Code: 400}))
+			So(response.StatusCode, ShouldEqual, 400)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Then a parameter which is not a number should return a bad request", func() {
+			response, _ := testFront.Handler(context.Background(), calcRequest(map[string]string{"val1": "one", "val2": "2"}))
+			So(response.Body, ShouldEqual, utils.JsonStringify(models.ApiErrorBody{Message: "Invalid parameter val1: one is not a number", Code: 400}))
+			So(response.StatusCode, ShouldEqual, 400)
+		})
+
+		Convey("Then the Accept-Language header should be matched case-insensitively", func() {
+			response, _ := testFront.Handler(context.Background(), calcRequest(map[string]string{"val1": "1.5", "val2": "2"}))
+			var result models.CalculationResult
+			So(json.Unmarshal([]byte(response.Body), &result), ShouldBeNil)
+			So(result.Locale, ShouldEqual, "fr-FR")
+			So(result.Result, ShouldEqual, "3,5")
+		})
+	})
+}
+
+func TestOpenAPIRoute(t *testing.T) {
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"host": "abc123.execute-api.eu-west-1.amazonaws.com"},
+		RequestContext: events.APIGatewayProxyRequestContext{
+			ResourcePath: `/openapi.json`,
+			HTTPMethod:   `GET`,
+			Stage:        `live`,
+		},
+	}
+
+	Convey("When sending a request with the /openapi.json route", t, func() {
+
+		docsFront := NewFront(models.Status{Platform: "live"}, 123)
+		response, err := docsFront.Handler(context.Background(), request)
+
+		var definition map[string]interface{}
+
+		So(err, ShouldBeNil)
+		So(json.Unmarshal([]byte(response.Body), &definition), ShouldBeNil)
+
+		Convey("Then it should return the OpenAPI definition with the server of the request's host and stage", func() {
+			So(response.StatusCode, ShouldEqual, 200)
+			So(response.Headers["Content-Type"], ShouldEqual, "application/json")
+			So(definition["openapi"], ShouldEqual, "3.1.0")
+			So(definition["servers"], ShouldResemble, []interface{}{
+				map[string]interface{}{"url": "https://abc123.execute-api.eu-west-1.amazonaws.com/live"},
+			})
+			So(definition["info"], ShouldResemble, map[string]interface{}{
+				"title":       "Sample-API-live",
+				"version":     "live",
+				"description": "Sample API",
+			})
+			So(definition["paths"], ShouldContainKey, "/calc/{op}")
+		})
+
+		Convey("Then it should not include the API Gateway extensions", func() {
+			So(response.Body, ShouldNotContainSubstring, "x-amazon-apigateway-")
+		})
+	})
+}
+
+func TestOpenAPIRouteCustomDomain(t *testing.T) {
+
+	request := events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Host": "api.example.com"},
+		Path:    `/openapi.json`,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			ResourcePath: `/openapi.json`,
+			HTTPMethod:   `GET`,
+			Stage:        `live`,
+		},
+	}
+
+	Convey("When sending a request with the /openapi.json route to a custom domain", t, func() {
+
+		docsFront := NewFront(models.Status{Platform: "live"}, 123)
+		response, err := docsFront.Handler(context.Background(), request)
+
+		var definition map[string]interface{}
+
+		So(err, ShouldBeNil)
+		So(json.Unmarshal([]byte(response.Body), &definition), ShouldBeNil)
+
+		Convey("Then its server should be the root of the domain, which is mapped to the stage", func() {
+			So(definition["servers"], ShouldResemble, []interface{}{
+				map[string]interface{}{"url": "https://api.example.com"},
+			})
+		})
+	})
+
+	Convey("When sending a request with the /openapi.json route to a domain with the stage in its path", t, func() {
+
+		request.Path = `/live/openapi.json`
+
+		docsFront := NewFront(models.Status{Platform: "live"}, 123)
+		response, err := docsFront.Handler(context.Background(), request)
+
+		var definition map[string]interface{}
+
+		So(err, ShouldBeNil)
+		So(json.Unmarshal([]byte(response.Body), &definition), ShouldBeNil)
+
+		Convey("Then its server should include the stage", func() {
+			So(definition["servers"], ShouldResemble, []interface{}{
+				map[string]interface{}{"url": "https://api.example.com/live"},
+			})
+		})
+	})
+}
+
+func TestDocsRoute(t *testing.T) {
+
+	request := events.APIGatewayProxyRequest{
+		RequestContext: events.APIGatewayProxyRequestContext{
+			ResourcePath: `/docs`,
+			HTTPMethod:   `GET`,
+		},
+	}
+
+	Convey("When sending a request with the /docs route", t, func() {
+
+		response, err := testFront.Handler(context.Background(), request)
+
+		Convey("Then it should return the HTML documentation page loading the definition", func() {
+			So(err, ShouldBeNil)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(response.Headers["Content-Type"], ShouldEqual, "text/html; charset=utf-8")
+			So(response.Body, ShouldStartWith, "<!DOCTYPE html>")
+			So(response.Body, ShouldContainSubstring, `fetch("openapi.json")`)
+		})
+	})
+}
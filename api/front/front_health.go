@@ -0,0 +1,44 @@
+package front
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/health"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// WithHealthChecks adds checks run by the /health endpoint
+//
+// The endpoint returns the aggregated results as application/health+json, with a 503 status if any critical check
+// fails. Health responses are never cached
+func WithHealthChecks(checks ...health.Check) Option {
+	return func(front *Front) {
+		front.healthChecks = append(front.healthChecks, checks...)
+	}
+}
+
+// GetHealth runs the health checks
+func (front Front) GetHealth(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+	report := health.Run(ctx, front.healthChecks)
+	report.Version = front.status.Release
+	report.ReleaseId = front.status.Commit
+
+	statusCode := http.StatusOK
+
+	if report.Status == health.StatusFail {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	return response{
+		data:       report,
+		statusCode: statusCode,
+		headers: map[string]string{
+			"Content-Type":  health.ContentType,
+			"Cache-Control": "no-store",
+		},
+	}, nil
+}
@@ -0,0 +1,68 @@
+package front
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/metrics"
+)
+
+// The dimension sets under which Front publishes its per-request metrics
+var MetricDimensionSets = [][]string{
+	{"Route"},
+	{"Route", "StatusClass"},
+	{"Route", "Op"},
+}
+
+// WithMetrics enables per-request metrics logged in CloudWatch Embedded Metric Format
+//
+// Each request records Requests, Latency, ClientErrors and ServerErrors metrics with Route and StatusClass dimensions,
+// which are written as one EMF line before the invocation returns. Handlers may add their own metrics and dimensions to
+// the scope returned by metrics.FromContext. As the logger is flushed at the end of every invocation, its BatchSize and
+// MaxBatchAge only batch metrics within one invocation.
+//
+// There is no cache hit metric: responses cached by API Gateway are served without invoking the lambda, so Front only
+// ever sees cache misses. API Gateway publishes CacheHitCount and CacheMissCount itself when caching is enabled
+func WithMetrics(logger *metrics.Logger) Option {
+	return func(front *Front) {
+		front.metrics = logger
+	}
+}
+
+// Starts a metrics scope for a request, returning a context carrying the scope and a function to complete it
+//
+// Completing the scope flushes the logger, as Lambda may freeze or recycle the container once the invocation returns
+func (front Front) startMetrics(ctx context.Context, route string) (context.Context, func(response events.APIGatewayProxyResponse)) {
+
+	if front.metrics == nil {
+		return ctx, func(events.APIGatewayProxyResponse) {}
+	}
+
+	start := time.Now()
+	scope := front.metrics.NewScope()
+	scope.SetDimension("Route", route)
+
+	return metrics.NewContext(ctx, scope), func(response events.APIGatewayProxyResponse) {
+
+		scope.SetDimension("StatusClass", strconv.Itoa(response.StatusCode/100)+"xx")
+		scope.Count("Requests", 1)
+		scope.Put("Latency", float64(time.Since(start))/float64(time.Millisecond), metrics.Milliseconds)
+		scope.Count("ClientErrors", boolToInt(response.StatusCode >= 400 && response.StatusCode < 500))
+		scope.Count("ServerErrors", boolToInt(response.StatusCode >= 500))
+
+		front.metrics.Record(scope)
+		front.metrics.Flush()
+	}
+}
+
+func boolToInt(b bool) int {
+
+	if b {
+		return 1
+	}
+
+	return 0
+}
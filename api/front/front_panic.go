@@ -0,0 +1,93 @@
+package front
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// PanicReport describes a panic recovered while handling a request
+type PanicReport struct {
+	IncidentID string
+	Route      string
+	Value      interface{}
+	Message    string
+	Stack      []byte
+	Request    events.APIGatewayProxyRequest
+}
+
+// A PanicReporter is called with every recovered panic, e.g. to forward it to an error tracker
+type PanicReporter func(ctx context.Context, report PanicReport)
+
+// WithPanicReporter adds a hook which is called with every recovered panic
+func WithPanicReporter(reporter PanicReporter) Option {
+	return func(front *Front) {
+		front.panicReporters = append(front.panicReporters, reporter)
+	}
+}
+
+// WithDebug sets whether panic messages are included in responses to clients. It should not be enabled on live
+func WithDebug(debug bool) Option {
+	return func(front *Front) {
+		front.debug = debug
+	}
+}
+
+// Logs and reports a recovered panic, returning an ApiError with a generic message referring to the incident
+func (front Front) handlePanic(ctx context.Context, route string, request events.APIGatewayProxyRequest, r interface{}, stack []byte) models.ApiError {
+
+	report := PanicReport{
+		IncidentID: incidentID(ctx),
+		Route:      route,
+		Value:      r,
+		Message:    utils.PanicMessage(r),
+		Stack:      stack,
+		Request:    request,
+	}
+
+	log.Printf("PANIC (incident %v) %v\n", report.IncidentID, utils.JsonStack(r, stack))
+
+	for _, reporter := range front.panicReporters {
+		reportPanic(ctx, reporter, report)
+	}
+
+	if front.debug {
+		return models.ConstructApiError(http.StatusInternalServerError, "Internal server error (incident %v): %v", report.IncidentID, report.Message)
+	}
+
+	return models.ConstructApiError(http.StatusInternalServerError, "Internal server error (incident %v)", report.IncidentID)
+}
+
+// Calls a reporter with the report, logging rather than propagating any panic of the reporter so that the response
+// is still returned
+func reportPanic(ctx context.Context, reporter PanicReporter, report PanicReport) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("ERROR: Panic reporter failed for incident %v: %v\n", report.IncidentID, utils.PanicMessage(r))
+		}
+	}()
+
+	reporter(ctx, report)
+}
+
+// Returns the lambda request ID, or a random ID if there is none
+func incidentID(ctx context.Context) string {
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok && lc.AwsRequestID != "" {
+		return lc.AwsRequestID
+	}
+
+	raw := make([]byte, 8)
+	rand.Read(raw)
+
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,161 @@
+// Code generated from the Swagger definition of the API. DO NOT EDIT.
+
+package front
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// Operations handles the operations of the API. Adding an operation to the API adds a method which must be implemented
+type Operations interface {
+	// GetCalcOp handles GET /calc/{op}
+	GetCalcOp(ctx context.Context, request events.APIGatewayProxyRequest, params GetCalcOpParams) (interface{}, models.ApiError)
+	// GetDocs handles GET /docs
+	GetDocs(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+	// GetHealth handles GET /health
+	GetHealth(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+	// GetOpenAPI handles GET /openapi.json
+	GetOpenAPI(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+	// GetStatus handles GET /status
+	GetStatus(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)
+}
+
+// GetCalcOpParams are the parameters of GET /calc/{op}
+type GetCalcOpParams struct {
+	// op in the path
+	Op string
+	// val1 in the query
+	Val1 float64
+	// val2 in the query
+	Val2 float64
+	// Accept-Language in the header, or nil if it is not given
+	AcceptLanguage *string
+}
+
+// Decodes the parameters of GET /calc/{op}, returning a bad request error if any are missing or invalid
+func decodeGetCalcOpParams(request events.APIGatewayProxyRequest) (params GetCalcOpParams, apiErr models.ApiError) {
+
+	if params.Op, apiErr = requiredParameter(request.PathParameters, "op", false); apiErr != nil {
+		return params, apiErr
+	}
+
+	if apiErr = enumParameter("op", params.Op, "add", "subtract", "sub", "multiply", "mul", "divide", "div", "power", "pow", "root", "roo"); apiErr != nil {
+		return params, apiErr
+	}
+
+	rawVal1, apiErr := requiredParameter(request.QueryStringParameters, "val1", false)
+
+	if apiErr != nil {
+		return params, apiErr
+	}
+
+	if params.Val1, apiErr = parseNumber("val1", rawVal1); apiErr != nil {
+		return params, apiErr
+	}
+
+	rawVal2, apiErr := requiredParameter(request.QueryStringParameters, "val2", false)
+
+	if apiErr != nil {
+		return params, apiErr
+	}
+
+	if params.Val2, apiErr = parseNumber("val2", rawVal2); apiErr != nil {
+		return params, apiErr
+	}
+
+	params.AcceptLanguage = optionalParameter(request.Headers, "Accept-Language", true)
+
+	return params, nil
+}
+
+// Returns the handler of the operation of a route of the form METHOD/path, or nil if the API has no such operation
+func operationHandler(operations Operations, route string) innerHandler {
+
+	switch route {
+
+	case "GET/calc/{op}":
+		return func(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+			params, apiErr := decodeGetCalcOpParams(request)
+
+			if apiErr != nil {
+				return nil, apiErr
+			}
+
+			return operations.GetCalcOp(ctx, request, params)
+		}
+
+	case "GET/docs":
+		return operations.GetDocs
+
+	case "GET/health":
+		return operations.GetHealth
+
+	case "GET/openapi.json":
+		return operations.GetOpenAPI
+
+	case "GET/status":
+		return operations.GetStatus
+
+	}
+
+	return nil
+}
+
+// Returns a parameter, or an error if it is not given. Header names are matched case-insensitively
+func requiredParameter(values map[string]string, name string, header bool) (string, models.ApiError) {
+
+	if value := optionalParameter(values, name, header); value != nil {
+		return *value, nil
+	}
+
+	return "", models.ConstructApiError(http.StatusBadRequest, "Missing parameter %v", name)
+}
+
+// Returns a parameter, or nil if it is not given. Header names are matched case-insensitively
+func optionalParameter(values map[string]string, name string, header bool) *string {
+
+	if value, ok := values[name]; ok {
+		return &value
+	}
+
+	if header {
+		for k, v := range values {
+			if strings.EqualFold(k, name) {
+				return &v
+			}
+		}
+	}
+
+	return nil
+}
+
+// Returns an error if a parameter is not one of its allowed values
+func enumParameter(name, value string, allowed ...string) models.ApiError {
+
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	return models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not one of %v", name, value, strings.Join(allowed, ", "))
+}
+
+func parseNumber(name, value string) (float64, models.ApiError) {
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not a number", name, value)
+	}
+
+	return number, nil
+}
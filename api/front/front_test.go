@@ -1,13 +1,21 @@
 package front
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
 	"github.com/golang/mock/gomock"
 	. "github.com/smartystreets/goconvey/convey"
 
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/accesslog"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
 )
 
 func makeFront() Front {
@@ -31,7 +39,7 @@ func TestUnknownRoute(t *testing.T) {
 		}
 
 		Convey("Then it should return a bad request status code", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, `{"message":"No such route as GET/unknownpath","code":404}`)
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.StatusCode, ShouldEqual, 404)
@@ -45,7 +53,7 @@ func (front *Front) dummyDataRouter(route string) innerHandler {
 	return front.dummyDataHandler
 }
 
-func (front Front) dummyDataHandler(request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+func (front Front) dummyDataHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
 
 	return struct{Data string `json:"data"`}{Data: "Dummy"}, nil
 }
@@ -69,7 +77,7 @@ func TestFrontDummyData(t *testing.T) {
 		}
 
 		Convey("Then front should return a 200 request status code, and a JSON encoded string of the data", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, `{"data":"Dummy"}`)
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.Headers["Cache-Control"], ShouldEqual, "max-age=123")
@@ -84,7 +92,7 @@ func (front *Front) errorRouter(route string) innerHandler {
 	return front.errorHandler
 }
 
-func (front Front) errorHandler(request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+func (front Front) errorHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
 
 	return nil, models.ConstructApiError(345, "A simulated error: %v", "error")
 }
@@ -108,7 +116,7 @@ func TestFrontErrorData(t *testing.T) {
 		}
 
 		Convey("Then front should return the ApiError code and a JSON encoded error body with the ApiError message", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, `{"message":"A simulated error: error","code":345}`)
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.StatusCode, ShouldEqual, 345)
@@ -122,7 +130,7 @@ func (front *Front) unmarshallableRouter(route string) innerHandler {
 	return front.unmarshallableHandler
 }
 
-func (front Front) unmarshallableHandler(request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+func (front Front) unmarshallableHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
 
 	return func(){}, nil
 }
@@ -146,7 +154,7 @@ func TestFrontUnparseableData(t *testing.T) {
 		}
 
 		Convey("Then front should return 500 and a JSON encoded error body with an 'Unmarshallable data' message", func() {
-			response, err := testFront.Handler(request)
+			response, err := testFront.Handler(context.Background(), request)
 			So(response.Body, ShouldEqual, `{"message":"Unmarshallable data","code":500}`)
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.StatusCode, ShouldEqual, 500)
@@ -160,10 +168,9 @@ func (front *Front) panickyRouter(route string) innerHandler {
 	return front.panickyHandler
 }
 
-func (front Front) panickyHandler(request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+func (front Front) panickyHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
 
 	panic("Simulated panic")
-	return
 }
 
 func TestFrontPanicRecovery(t *testing.T) {
@@ -184,12 +191,293 @@ func TestFrontPanicRecovery(t *testing.T) {
 			},
 		}
 
-		Convey("Then front should return a 500 request status code and a JSON encoded error body with the panic message", func() {
-			response, err := testFront.Handler(request)
-			So(response.Body, ShouldEqual, `{"message":"Simulated panic","code":500}`)
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "test-request"})
+
+		Convey("Then front should return a 500 request status code and a JSON encoded error body with a generic message and incident ID", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldEqual, `{"message":"Internal server error (incident test-request)","code":500}`)
 			So(response.Headers["Access-Control-Allow-Origin"], ShouldEqual, "*")
 			So(response.StatusCode, ShouldEqual, 500)
 			So(err, ShouldBeNil)
 		})
 	})
 }
+
+func (front *Front) errorPanickyRouter(route string) innerHandler {
+
+	return front.errorPanickyHandler
+}
+
+func (front Front) errorPanickyHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+
+	var values []string
+
+	return values[1], nil
+}
+
+func TestFrontPanicReporting(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	var reports []PanicReport
+
+	testFront := NewFront(models.Status{}, 123, WithDebug(true), WithPanicReporter(func(ctx context.Context, report PanicReport) {
+		reports = append(reports, report)
+	}))
+
+	testFront.router = testFront.errorPanickyRouter
+
+	Convey("When encountering a runtime error panic in debug mode", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "test-request"})
+
+		Convey("Then front should report the panic and include its message in the error body", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldEqual, `{"message":"Internal server error (incident test-request): runtime error: index out of range [1] with length 0","code":500}`)
+			So(response.StatusCode, ShouldEqual, 500)
+			So(err, ShouldBeNil)
+			So(len(reports), ShouldEqual, 1)
+			So(reports[0].IncidentID, ShouldEqual, "test-request")
+			So(reports[0].Route, ShouldEqual, "GET/whatever")
+			So(reports[0].Message, ShouldEqual, "runtime error: index out of range [1] with length 0")
+			So(string(reports[0].Stack), ShouldContainSubstring, "errorPanickyHandler")
+		})
+	})
+}
+
+func TestFrontPanickingReporter(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	var reported bool
+
+	testFront := NewFront(models.Status{}, 123,
+		WithPanicReporter(func(ctx context.Context, report PanicReport) {
+			panic("reporter unavailable")
+		}),
+		WithPanicReporter(func(ctx context.Context, report PanicReport) {
+			reported = true
+		}),
+	)
+
+	testFront.router = testFront.errorPanickyRouter
+
+	Convey("When a panic reporter panics", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "test-request"})
+
+		Convey("Then front should still call the other reporters and return the 500 response", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldEqual, `{"message":"Internal server error (incident test-request)","code":500}`)
+			So(response.StatusCode, ShouldEqual, 500)
+			So(err, ShouldBeNil)
+			So(reported, ShouldBeTrue)
+		})
+	})
+}
+
+func TestFrontInsufficientTime(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	testFront := NewFront(models.Status{}, 123, WithDeadlineMargin(time.Second))
+
+	testFront.router = testFront.dummyDataRouter
+
+	Convey("When a request arrives with less than the deadline margin remaining", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		Convey("Then front should return a 504 request status code without calling the handler", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldStartWith, `{"message":"Insufficient time remaining to handle request`)
+			So(response.StatusCode, ShouldEqual, 504)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestFrontCancelledContext(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	testFront := makeFront()
+
+	testFront.router = testFront.dummyDataRouter
+
+	Convey("When a request arrives with a cancelled context", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		Convey("Then front should return a 503 request status code", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldEqual, `{"message":"Request cancelled: context canceled","code":503}`)
+			So(response.StatusCode, ShouldEqual, 503)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func (front *Front) deadlineRouter(route string) innerHandler {
+
+	return front.deadlineHandler
+}
+
+func (front Front) deadlineHandler(ctx context.Context, request events.APIGatewayProxyRequest) (result interface{}, apiError models.ApiError) {
+
+	deadline, ok := ctx.Deadline()
+
+	if !ok {
+		return nil, models.ConstructApiError(500, "No deadline")
+	}
+
+	return struct {
+		Remaining bool `json:"remaining"`
+	}{Remaining: time.Until(deadline) < 10*time.Second}, nil
+}
+
+func TestFrontHandlerDeadline(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	testFront := NewFront(models.Status{}, 123, WithDeadlineMargin(5*time.Second))
+
+	testFront.router = testFront.deadlineRouter
+
+	Convey("When a request arrives with a lambda deadline", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+
+		Convey("Then the handler context deadline should be brought forward by the deadline margin", func() {
+			response, err := testFront.Handler(ctx, request)
+			So(response.Body, ShouldEqual, `{"remaining":true}`)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestFrontAccessLog(t *testing.T) {
+
+	mockController := gomock.NewController(t)
+	defer mockController.Finish()
+
+	buf := &bytes.Buffer{}
+
+	logger, _ := accesslog.New(accesslog.Config{
+		Format:        `{{.SourceIP}} {{.UserAgent}} {{.Route}} {{.Status}} {{.Bytes}} {{index .Headers "Authorization"}}`,
+		Writer:        buf,
+		RedactHeaders: []string{"authorization"},
+	})
+
+	testFront := NewFront(models.Status{}, 123, WithMiddleware(AccessLog(logger)))
+
+	testFront.router = testFront.dummyDataRouter
+
+	Convey("When access logging middleware is added", t, func() {
+
+		request := events.APIGatewayProxyRequest{
+			Headers: map[string]string{
+				"Authorization": "Bearer secret",
+			},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				ResourcePath: `/whatever`,
+				HTTPMethod:   `GET`,
+				Identity: events.APIGatewayRequestIdentity{
+					SourceIP:  "192.0.2.1",
+					UserAgent: "curl/7.64.1",
+				},
+			},
+		}
+
+		Convey("Then front should write an access log line for the request with sensitive headers redacted", func() {
+			response, err := testFront.Handler(context.Background(), request)
+			So(response.StatusCode, ShouldEqual, 200)
+			So(err, ShouldBeNil)
+			So(buf.String(), ShouldEqual, "192.0.2.1 curl/7.64.1 GET/whatever 200 16 [REDACTED]\n")
+		})
+	})
+}
+
+func TestRoutesMatchTemplate(t *testing.T) {
+
+	Convey("When generating the routes from the API definition", t, func() {
+
+		template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+		So(err, ShouldBeNil)
+
+		doc, err := swagger.ParseTemplate(template, nil)
+		So(err, ShouldBeNil)
+
+		generated, err := swagger.GenerateRoutes("front", "github.com/merlincox/aws-api-gateway-deploy/pkg/models", doc)
+		So(err, ShouldBeNil)
+
+		Convey("Then they should match front_routes.go, which go generate ./api/front updates", func() {
+			current, err := ioutil.ReadFile("front_routes.go")
+			So(err, ShouldBeNil)
+			So(string(generated), ShouldEqual, string(current))
+		})
+	})
+}
+
+func TestOpenAPIMatchesTemplate(t *testing.T) {
+
+	Convey("When converting the API definition to OpenAPI", t, func() {
+
+		template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+		So(err, ShouldBeNil)
+
+		definition, err := swagger.FromTemplate(template, nil)
+		So(err, ShouldBeNil)
+
+		converted, err := swagger.ToOpenAPI(definition, swagger.OpenAPIVersion)
+		So(err, ShouldBeNil)
+
+		Convey("Then it should match openapi.json, which go generate ./api/front updates", func() {
+			So(string(converted)+"\n", ShouldEqual, string(openAPIJSON))
+		})
+	})
+}
@@ -0,0 +1,99 @@
+package front
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/tracing"
+)
+
+// WithTracer enables tracing of requests with an OpenTelemetry tracer, such as one of a tracing.NewProvider
+//
+// Each request has a server span, continuing any trace from incoming traceparent or X-Amzn-Trace-Id headers, with
+// child spans for routing and for the handler. Handlers may start their own child spans from the context they receive.
+// As in the OpenTelemetry conventions for HTTP servers, only server errors set the error status of a span
+func WithTracer(tracer trace.Tracer) Option {
+	return func(front *Front) {
+		front.tracer = tracer
+	}
+}
+
+// Returns the tracer of requests, which does nothing unless tracing is enabled
+func (front Front) requestTracer() trace.Tracer {
+
+	if front.tracer == nil {
+		return trace.NewNoopTracerProvider().Tracer(tracing.InstrumentationName)
+	}
+
+	return front.tracer
+}
+
+// Starts the span for a request, returning a context carrying the span and a function to end it
+func (front Front) startSpan(ctx context.Context, request events.APIGatewayProxyRequest, route string) (context.Context, func(response events.APIGatewayProxyResponse)) {
+
+	if front.tracer == nil {
+		return ctx, func(events.APIGatewayProxyResponse) {}
+	}
+
+	ctx, span := front.tracer.Start(tracing.Extract(ctx, request.Headers), "Handler",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(request.RequestContext.HTTPMethod),
+			semconv.HTTPRoute(request.RequestContext.ResourcePath),
+			semconv.HTTPTarget(request.Path),
+			attribute.String("route", route),
+		),
+	)
+
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		span.SetAttributes(semconv.FaaSExecution(lc.AwsRequestID))
+	}
+
+	return ctx, func(response events.APIGatewayProxyResponse) {
+
+		span.SetAttributes(semconv.HTTPStatusCode(response.StatusCode))
+
+		// Client errors leave the status unset, as the server did not fail
+		if response.StatusCode >= http.StatusInternalServerError {
+			span.SetAttributes(attribute.Bool("error", true))
+			span.SetStatus(codes.Error, http.StatusText(response.StatusCode))
+		}
+
+		span.End()
+	}
+}
+
+// Routes a request and calls its handler, each within a child span
+func (front Front) invoke(ctx context.Context, route string, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+	tracer := front.requestTracer()
+
+	_, routeSpan := tracer.Start(ctx, "route")
+	handler := front.router(route)
+	routeSpan.End()
+
+	ctx, handlerSpan := tracer.Start(ctx, "handler "+route)
+	defer handlerSpan.End()
+
+	data, apiErr := handler(ctx, request)
+
+	if apiErr != nil {
+
+		handlerSpan.SetAttributes(semconv.HTTPStatusCode(apiErr.StatusCode()))
+		handlerSpan.RecordError(apiErr)
+
+		if apiErr.StatusCode() >= http.StatusInternalServerError {
+			handlerSpan.SetStatus(codes.Error, apiErr.Error())
+		}
+	}
+
+	return data, apiErr
+}
@@ -2,31 +2,97 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
-	"runtime"
 	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 
 	"github.com/merlincox/aws-api-gateway-deploy/api/front"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/accesslog"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/buildinfo"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/config"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/health"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/metrics"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/tracing"
 )
 
-const cacheTtlSeconds = 60
+const livePlatform = "live"
 
 func main() {
 
-	log.Printf("Starting %v API using Go %v\n", os.Getenv("RELEASE"), runtime.Version())
-	log.Printf("Commit %v Timestamp %v\n", os.Getenv("COMMIT"), os.Getenv("TIMESTAMP"))
+	var cfg Config
+
+	if err := config.Load(context.Background(), &cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	build := buildinfo.Read(cfg.Getenv)
+
+	log.Printf("Starting %v API using %v\n", build.Release, build.GoVersion)
+	log.Printf("Commit %v Dirty %v Built %v\n", build.Commit, build.Dirty, build.BuildTime)
 
 	status := models.Status{
-		Platform:  os.Getenv("PLATFORM"),
-		Commit:    os.Getenv("COMMIT"),
-		Branch:    os.Getenv("BRANCH"),
-		Release:   os.Getenv("RELEASE"),
-		Timestamp: time.Now().Format(time.RFC3339Nano),
+		Platform:     cfg.Platform,
+		Commit:       build.Commit,
+		Branch:       build.Branch,
+		Release:      build.Release,
+		Timestamp:    time.Now().Format(time.RFC3339Nano),
+		BuildTime:    build.BuildTime,
+		Dirty:        build.Dirty,
+		GoVersion:    build.GoVersion,
+		Dependencies: make([]models.Dependency, len(build.Dependencies)),
+	}
+
+	for i, dep := range build.Dependencies {
+		status.Dependencies[i] = models.Dependency{Path: dep.Path, Version: dep.Version}
+	}
+
+	metricsLogger := metrics.NewLogger(metrics.Config{
+		Namespace:         cfg.MetricsNamespace,
+		DimensionSets:     front.MetricDimensionSets,
+		DefaultDimensions: map[string]string{"Platform": status.Platform},
+	})
+
+	accessLogger, err := accesslog.New(accesslog.Config{
+		Format:        cfg.AccessLogFormat,
+		SampleRate:    cfg.AccessLogSampleRate,
+		RedactHeaders: cfg.RedactHeaders,
+		RedactQuery:   cfg.RedactQuery,
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	opts := []front.Option{
+		front.WithMiddleware(front.AccessLog(accessLogger)),
+		front.WithMetrics(metricsLogger),
+		front.WithDeadlineMargin(cfg.DeadlineMargin),
+		front.WithDebug(cfg.Debug && status.Platform != livePlatform),
+		front.WithHealthChecks(
+			health.Check{Name: "lambda", Checker: health.RemainingTime(cfg.MinRemainingTime)},
+			health.Check{Name: "configuration", Checker: health.Required(map[string]string{
+				"RELEASE":  status.Release,
+				"COMMIT":   status.Commit,
+				"BRANCH":   status.Branch,
+				"PLATFORM": status.Platform,
+			})},
+		),
+	}
+
+	if cfg.Tracing == "stdout" {
+
+		exporter, err := tracing.NewWriterExporter(os.Stdout)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		opts = append(opts, front.WithTracer(tracing.NewProvider(exporter).Tracer(tracing.InstrumentationName)))
 	}
 
-	lambda.Start(front.NewFront(status, cacheTtlSeconds).Handler)
+	lambda.Start(front.NewFront(status, int(cfg.CacheTTL/time.Second), opts...).Handler)
 }
@@ -0,0 +1,118 @@
+// The build command builds the lambda into a reproducible zip named by its SHA-256 digest, with a manifest of its
+// source commit, Go version, target and files, or verifies a zip against its manifest
+//
+// Usage: build [--arch amd64|arm64] [--dir bin] | build --verify bin/<sha256>.zip
+//
+// The deploy command builds the same way, so a zip built here from the same commit with the same Go version is
+// identical to the one deployed
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/deploy"
+)
+
+const mainPackage = "./api"
+
+func main() {
+
+	arch := flag.String("arch", "amd64", "target architecture: amd64 or arm64")
+	dir := flag.String("dir", "bin", "directory to write the zip and manifest to")
+	verify := flag.String("verify", "", "zip to verify against the manifest alongside it")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--arch amd64|arm64] [--dir bin] | %v --verify bin/<sha256>.zip\n", filepath.Base(os.Args[0]), filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var err error
+
+	if *verify != "" {
+		err = verifyZip(*verify)
+	} else {
+		err = build(context.Background(), *arch, *dir)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func build(ctx context.Context, arch, dir string) error {
+
+	runner := command.ExecRunner{Log: os.Stderr}
+
+	info, err := deploy.CLIGitGuard{Runner: command.ExecRunner{}}.Info(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	builder := artifact.Builder{
+		Runner:     runner,
+		Main:       mainPackage,
+		Executable: artifact.Bootstrap,
+		GOARCH:     arch,
+		Tags:       []string{artifact.NoRPCTag},
+		Dir:        dir,
+	}
+
+	built, err := builder.Build(ctx, artifact.Source{Release: info.Tag, Branch: info.Branch, Commit: info.Commit, CommitTime: info.Time})
+
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(built.Manifest, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(raw))
+	fmt.Fprintf(os.Stderr, "Built %v\n", built.Path)
+
+	return nil
+}
+
+func verifyZip(path string) error {
+
+	manifest, err := artifact.ReadManifest(strings.TrimSuffix(path, ".zip") + ".json")
+
+	if err != nil {
+		return err
+	}
+
+	zipped, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := artifact.Verify(zipped, manifest); err != nil {
+		return err
+	}
+
+	fmt.Printf("%v matches its manifest: %v (%v) built with %v for %v/%v\n",
+		path, manifest.Release, manifest.Commit, manifest.GoVersion, manifest.GOOS, manifest.GOARCH)
+
+	return nil
+}
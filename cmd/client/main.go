@@ -0,0 +1,73 @@
+// The client command generates the operations of the API client in pkg/client from the Swagger definition of the API
+// in the CloudFormation template. It is run by go generate in pkg/client
+//
+// Usage: client [--template api.yaml] [--package client] [--models import/path] [--out file.go]
+//
+// Every operation which integrates with the lambda becomes a method of the Client, returning the model of its
+// successful response. Without --out, the operations are printed
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+const modelsImport = "github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+
+func main() {
+
+	template := flag.String("template", "api.yaml", "CloudFormation template defining the API")
+	pkg := flag.String("package", "client", "package of the generated client")
+	models := flag.String("models", modelsImport, "import path of the models package")
+	out := flag.String("out", "", "file to write the client operations to (default standard output)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--template api.yaml] [--package client] [--models import/path] [--out file.go]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*template, *pkg, *models, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(template, pkg, models, out string) error {
+
+	raw, err := ioutil.ReadFile(template)
+
+	if err != nil {
+		return err
+	}
+
+	doc, err := swagger.ParseTemplate(raw, nil)
+
+	if err != nil {
+		return err
+	}
+
+	operations, err := swagger.GenerateClient(pkg, models, doc)
+
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(operations)
+		return err
+	}
+
+	return ioutil.WriteFile(out, operations, 0644)
+}
@@ -0,0 +1,99 @@
+// The convert command converts the definition of the API between Swagger 2.0 and OpenAPI 3, keeping the API Gateway
+// extensions such as x-amazon-apigateway-integration
+//
+// Usage: convert [--template api.yaml | --definition file] [--platform test] [--to openapi|swagger] [--version 3.1.0] [--out file.json]
+//
+// By default the Swagger definition in the CloudFormation template is converted to OpenAPI, with intrinsic functions
+// resolved as by the models command. References to the Platform parameter are left as {Platform} if --platform is
+// empty. With --definition, a Swagger 2.0 or OpenAPI 3 definition in JSON or YAML is converted instead. Without --out,
+// the converted definition is printed
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+func main() {
+
+	template := flag.String("template", "api.yaml", "CloudFormation template defining the API")
+	definition := flag.String("definition", "", "Swagger 2.0 or OpenAPI 3 definition of the API, instead of the template")
+	platform := flag.String("platform", "test", "value of the Platform template parameter, which is left as {Platform} if empty")
+	to := flag.String("to", "openapi", "openapi or swagger")
+	version := flag.String("version", swagger.OpenAPIVersion, "version of OpenAPI to convert to")
+	out := flag.String("out", "", "file to write the converted definition to (default standard output)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--template api.yaml | --definition file] [--platform test] [--to openapi|swagger] [--version 3.1.0] [--out file.json]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 0 || (*to != "openapi" && *to != "swagger") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*template, *definition, *platform, *to, *version, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(template, definition, platform, to, version, out string) error {
+
+	raw, err := readDefinition(template, definition, platform)
+
+	if err != nil {
+		return err
+	}
+
+	var converted []byte
+
+	if to == "swagger" {
+		converted, err = swagger.ToSwagger(raw)
+	} else {
+		converted, err = swagger.ToOpenAPI(raw, version)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	converted = append(converted, '\n')
+
+	if out == "" {
+		_, err = os.Stdout.Write(converted)
+		return err
+	}
+
+	return ioutil.WriteFile(out, converted, 0644)
+}
+
+// Reads the API definition from a definition file if one is given, and otherwise from the template
+func readDefinition(template, definition, platform string) ([]byte, error) {
+
+	if definition != "" {
+		return ioutil.ReadFile(definition)
+	}
+
+	raw, err := ioutil.ReadFile(template)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+
+	if platform != "" {
+		values["Platform"] = platform
+	}
+
+	return swagger.FromTemplate(raw, values)
+}
@@ -0,0 +1,162 @@
+// The deploy command deploys the API to a platform at a subdomain of a domain hosted in Route 53
+//
+// Usage: deploy [--config deploy.yaml] [--plan [--format text|json]] [--skip-tests] subdomain_base domain [platform]
+//
+// The platforms are the environments defined in the project configuration file. With --plan, every input to the
+// deployment is resolved and printed, and nothing is built or changed, nor is the git remote fetched. A deployment runs
+// the tests once before building the lambda, unless --skip-tests is given. Each deployment is recorded in the history
+// configured in the project configuration file. The lambda is kept in the artifact bucket of each region, which is
+// created if need be
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/deploy"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+const (
+	mainPackage = "./api"
+	artifactDir = "bin"
+)
+
+func main() {
+
+	configPath := flag.String("config", project.DefaultPath, "project configuration file")
+	planOnly := flag.Bool("plan", false, "print the deployment plan without building or deploying")
+	format := flag.String("format", "text", "plan output format: text or json")
+	skipTests := flag.Bool("skip-tests", false, "deploy without running the tests first")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--config deploy.yaml] [--plan [--format text|json]] [--skip-tests] subdomain_base domain [platform]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() < 2 || flag.NArg() > 3 || (*format != "text" && *format != "json") {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	request := deploy.Request{
+		SubdomainBase: flag.Arg(0),
+		Domain:        flag.Arg(1),
+		Platform:      flag.Arg(2),
+	}
+
+	config, err := project.Load(*configPath)
+
+	if err == nil {
+		if *planOnly {
+			err = plan(context.Background(), config, request, *format)
+		} else {
+			err = run(context.Background(), config, request, *skipTests)
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Returns a deployer for a request, whose git checks do not fetch from the remote if planOnly is true
+func newDeployer(config project.Config, request deploy.Request, planOnly bool) (deploy.Deployer, error) {
+
+	env, err := config.Environment(request.Platform)
+
+	if err != nil {
+		return deploy.Deployer{}, err
+	}
+
+	runner := command.ExecRunner{Env: env.AwsEnv(), Log: os.Stdout}
+	quiet := command.ExecRunner{Env: env.AwsEnv()}
+
+	return deploy.Deployer{
+		Git:          deploy.CLIGitGuard{Runner: quiet, NoFetch: planOnly},
+		Domains:      deploy.CLIDomainResolver{Runner: quiet},
+		Certificates: deploy.CLICertificateResolver{Runner: quiet},
+		Packager: deploy.CLIPackager{
+			Runner:     runner,
+			Executable: artifact.Bootstrap,
+			Main:       mainPackage,
+			GOARCH:     env.GOARCH(),
+			Tags:       []string{artifact.NoRPCTag},
+			Dir:        artifactDir,
+			Artifacts:  deploy.CLIArtifactStore{Runner: quiet, Project: config},
+			Prefix:     config.ArtifactPrefix(env.Name),
+		},
+		Stacks:  deploy.CLIStackDeployer{Runner: runner},
+		Project: config,
+		Confirm: confirm,
+		History: history.Open(config.History, deploy.CLIObjectClient{Runner: quiet}),
+		User:    deploy.CurrentUser(),
+		Out:     os.Stdout,
+	}, nil
+}
+
+func run(ctx context.Context, config project.Config, request deploy.Request, skipTests bool) error {
+
+	if err := deploy.CheckPrerequisites("go", "aws", "git"); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(config.Template); err != nil {
+		return fmt.Errorf("%v not found", config.Template)
+	}
+
+	deployer, err := newDeployer(config, request, false)
+
+	if err != nil {
+		return err
+	}
+
+	deployer.SkipTests = skipTests
+
+	return deployer.Deploy(ctx, request)
+}
+
+func plan(ctx context.Context, config project.Config, request deploy.Request, format string) error {
+
+	if err := deploy.CheckPrerequisites("aws", "git"); err != nil {
+		return err
+	}
+
+	deployer, err := newDeployer(config, request, true)
+
+	if err != nil {
+		return err
+	}
+
+	p, err := deployer.Plan(ctx, request)
+
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return p.WriteJSON(os.Stdout)
+	}
+
+	return p.WriteText(os.Stdout)
+}
+
+func confirm(prompt string) bool {
+
+	fmt.Print(prompt + " :")
+
+	reply, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(reply)), "y")
+}
@@ -0,0 +1,116 @@
+// The export command exports the Swagger definition of the API deployed to a platform as JSON and YAML, converts it
+// to OpenAPI as JSON, generates Go models from it and compares them with pkg/models/api.go
+//
+// Usage: export [--config deploy.yaml] [--dir export] [--openapi 3.1.0] [--check] [platform]
+//
+// If the models differ, the differences are printed and the models file may be replaced, keeping the previous one in
+// the export directory. With --check, nothing is replaced and the command fails if they differ
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/deploy"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/export"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+const (
+	modelsFile    = "pkg/models/api.go"
+	modelsPackage = "models"
+)
+
+func main() {
+
+	configPath := flag.String("config", project.DefaultPath, "project configuration file")
+	dir := flag.String("dir", "export", "directory to write the Swagger definitions to")
+	openAPI := flag.String("openapi", swagger.OpenAPIVersion, "version of the OpenAPI definition")
+	check := flag.Bool("check", false, "fail if the models differ from the API instead of offering to replace them")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--config deploy.yaml] [--dir export] [--openapi 3.1.0] [--check] [platform]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config, err := project.Load(*configPath)
+
+	if err == nil {
+		err = run(context.Background(), config, flag.Arg(0), *dir, *openAPI, *check)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, config project.Config, platform, dir, openAPI string, check bool) error {
+
+	env, err := config.Environment(platform)
+
+	if err != nil {
+		return err
+	}
+
+	if err := deploy.CheckPrerequisites("aws"); err != nil {
+		return err
+	}
+
+	exporter := export.Exporter{
+		Gateway:        export.CLIGateway{Runner: command.ExecRunner{Env: env.AwsEnv()}},
+		Dir:            dir,
+		OpenAPIVersion: openAPI,
+		Models:         modelsFile,
+		Package:        modelsPackage,
+	}
+
+	result, err := exporter.Export(ctx, env.Name)
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %v and %v, and converted it to %v\n", result.JSON, result.YAML, result.OpenAPI)
+
+	if !result.Drifted() {
+		fmt.Printf("Exported API models for %v match current API models at %v\n", env.Name, modelsFile)
+		return nil
+	}
+
+	fmt.Print(result.Diff)
+
+	if check {
+		return fmt.Errorf("The API models at %v do not match the API deployed to %v", modelsFile, env.Name)
+	}
+
+	if !confirm("Update current API models?") {
+		fmt.Println("Not overwritten")
+		return nil
+	}
+
+	return exporter.Update(result)
+}
+
+func confirm(prompt string) bool {
+
+	fmt.Print(prompt + " :")
+
+	reply, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(reply)), "y")
+}
@@ -0,0 +1,87 @@
+// The gc command removes lambda artifacts which neither a recorded deployment nor a deployed stack refers to from the
+// artifact buckets
+//
+// Usage: gc [--config deploy.yaml] [--dry-run] [--min-age 24h] [platform]
+//
+// Without a platform, the artifacts of every platform are collected. Artifacts uploaded within the minimum age are
+// kept, so that those of deployments in progress are not removed. With --dry-run, the artifacts which would be
+// removed are printed and nothing is changed. A platform with no recorded deployments is not collected, as its history
+// may be missing: naming it is an error, and without a platform it is skipped
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/deploy"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+func main() {
+
+	configPath := flag.String("config", project.DefaultPath, "project configuration file")
+	dryRun := flag.Bool("dry-run", false, "print the artifacts which would be removed without removing them")
+	minAge := flag.Duration("min-age", deploy.DefaultMinAge, "keep artifacts uploaded more recently than this")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--config deploy.yaml] [--dry-run] [--min-age 24h] [platform]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 1 || *minAge < 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config, err := project.Load(*configPath)
+
+	if err == nil {
+		err = run(context.Background(), config, flag.Arg(0), *minAge, *dryRun)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, config project.Config, platform string, minAge time.Duration, dryRun bool) error {
+
+	if err := deploy.CheckPrerequisites("aws"); err != nil {
+		return err
+	}
+
+	collectorFor := func(env *project.Environment) deploy.Collector {
+
+		runner := command.ExecRunner{Env: env.AwsEnv()}
+
+		return deploy.Collector{
+			Artifacts: deploy.CLIArtifactStore{Runner: runner, Project: config},
+			History:   history.Open(config.History, deploy.CLIObjectClient{Runner: runner}),
+			Stacks:    deploy.CLIStackInspector{Runner: runner},
+			Project:   config,
+			MinAge:    minAge,
+			Out:       os.Stdout,
+		}
+	}
+
+	if platform == "" {
+		return deploy.CollectAll(ctx, config, collectorFor, dryRun)
+	}
+
+	env, err := config.Environment(platform)
+
+	if err != nil {
+		return err
+	}
+
+	return collectorFor(env).Collect(ctx, env.Name, dryRun)
+}
@@ -0,0 +1,91 @@
+// The models command generates Go models from the Swagger definition of the API in the CloudFormation template,
+// without a deployed API. It is run by go generate in pkg/models
+//
+// Usage: models [--template api.yaml | --definition file] [--platform test] [--package models] [--out file.go]
+//
+// CloudFormation intrinsic functions in the definition are resolved with the template's parameter defaults and the
+// platform, and other references are left as placeholders. With --definition, the models are generated from a
+// Swagger 2.0 or OpenAPI 3 definition in JSON or YAML instead, such as an export. Without --out, the models are
+// printed
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+func main() {
+
+	template := flag.String("template", "api.yaml", "CloudFormation template defining the API")
+	definition := flag.String("definition", "", "Swagger 2.0 or OpenAPI 3 definition of the API, instead of the template")
+	platform := flag.String("platform", "test", "value of the Platform template parameter")
+	pkg := flag.String("package", "models", "package of the generated models")
+	out := flag.String("out", "", "file to write the models to (default standard output)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--template api.yaml | --definition file] [--platform test] [--package models] [--out file.go]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*template, *definition, *platform, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(template, definition, platform, pkg, out string) error {
+
+	doc, err := readDocument(template, definition, platform)
+
+	if err != nil {
+		return err
+	}
+
+	models, err := swagger.GenerateModels(pkg, doc.Definitions)
+
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(models)
+		return err
+	}
+
+	return ioutil.WriteFile(out, models, 0644)
+}
+
+// Reads the API definition from a definition file if one is given, and otherwise from the template
+func readDocument(template, definition, platform string) (swagger.Document, error) {
+
+	if definition != "" {
+
+		raw, err := ioutil.ReadFile(definition)
+
+		if err != nil {
+			return swagger.Document{}, err
+		}
+
+		return swagger.Parse(raw)
+	}
+
+	raw, err := ioutil.ReadFile(template)
+
+	if err != nil {
+		return swagger.Document{}, err
+	}
+
+	return swagger.ParseTemplate(raw, map[string]string{"Platform": platform})
+}
@@ -0,0 +1,85 @@
+// The release command tags HEAD with the next semantic version
+//
+// Usage: release [--pre label] [--dry-run] [--push] [major|minor|patch]
+//
+// Without a bump, the bump is computed from the conventional commit messages since the latest release: major for a
+// breaking change, minor for a feat and patch otherwise. With --pre, the version is the next numbered pre-release
+// such as v1.3.0-rc.1, for deploying to stage. The deploy command passes the tag to the API, so the /status endpoint
+// reports it as the release
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/guard"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/release"
+)
+
+func main() {
+
+	label := flag.String("pre", "", "pre-release label, such as rc or beta")
+	dryRun := flag.Bool("dry-run", false, "print the next version without tagging")
+	push := flag.Bool("push", false, "push the tag to origin")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--pre label] [--dry-run] [--push] [major|minor|patch]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	bump := release.None
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var err error
+
+	if flag.NArg() == 1 {
+		bump, err = release.ParseBump(flag.Arg(0))
+	}
+
+	if err == nil {
+		err = run(context.Background(), bump, *label, *dryRun, *push)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, bump release.Bump, label string, dryRun, push bool) error {
+
+	releaser := release.Releaser{}
+
+	if err := guard.CleanTree().Check(ctx, releaser.Repo); err != nil {
+		return err
+	}
+
+	plan, err := releaser.Plan(ctx, bump, label)
+
+	if err != nil {
+		return err
+	}
+
+	previous := plan.Previous
+
+	if previous == "" {
+		previous = "no previous release"
+	}
+
+	fmt.Printf("%v (%v bump from %v, %d commits)\n", plan.Version, plan.Bump, previous, len(plan.Commits))
+
+	if dryRun {
+		return nil
+	}
+
+	return releaser.Tag(ctx, plan, push)
+}
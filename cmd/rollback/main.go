@@ -0,0 +1,120 @@
+// The rollback command redeploys an earlier recorded deployment of the API to a platform without rebuilding it
+//
+// Usage: rollback [--config deploy.yaml] [--list] [--to id] [platform]
+//
+// By default it redeploys the most recent deployment of a commit other than the current one. Only deployments whose
+// packaged template was recorded can be redeployed. With --list, the recorded deployments are printed, most recent
+// first, and nothing is changed
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/deploy"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+func main() {
+
+	configPath := flag.String("config", project.DefaultPath, "project configuration file")
+	list := flag.Bool("list", false, "list the recorded deployments without rolling back")
+	to := flag.String("to", "", "the ID of the deployment to roll back to (default the previous one)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--config deploy.yaml] [--list] [--to id] [platform]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	config, err := project.Load(*configPath)
+
+	if err == nil {
+		err = run(context.Background(), config, flag.Arg(0), *to, *list)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, config project.Config, platform, to string, list bool) error {
+
+	env, err := config.Environment(platform)
+
+	if err != nil {
+		return err
+	}
+
+	if err := deploy.CheckPrerequisites("aws"); err != nil {
+		return err
+	}
+
+	runner := command.ExecRunner{Env: env.AwsEnv(), Log: os.Stdout}
+	quiet := command.ExecRunner{Env: env.AwsEnv()}
+	store := history.Open(config.History, deploy.CLIObjectClient{Runner: quiet})
+
+	if list {
+		return printHistory(ctx, store, env.Name)
+	}
+
+	deployer := deploy.Deployer{
+		Stacks:  deploy.CLIStackDeployer{Runner: runner},
+		Project: config,
+		Confirm: confirm,
+		History: store,
+		User:    deploy.CurrentUser(),
+		Out:     os.Stdout,
+	}
+
+	return deployer.Rollback(ctx, env.Name, to)
+}
+
+func printHistory(ctx context.Context, store history.Store, platform string) error {
+
+	records, err := store.List(ctx, platform)
+
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		fmt.Printf("No deployments to %v have been recorded\n", platform)
+	}
+
+	for _, record := range records {
+
+		retained := ""
+
+		if !record.CanRollback() {
+			retained = " (not retained)"
+		}
+
+		fmt.Printf("%v%v\n", record, retained)
+	}
+
+	return nil
+}
+
+func confirm(prompt string) bool {
+
+	fmt.Print(prompt + " :")
+
+	reply, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(reply)), "y")
+}
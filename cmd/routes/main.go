@@ -0,0 +1,73 @@
+// The routes command generates the Operations interface of the API's handlers and the routing of requests to them from
+// the Swagger definition of the API in the CloudFormation template. It is run by go generate in api/front
+//
+// Usage: routes [--template api.yaml] [--package front] [--models import/path] [--out file.go]
+//
+// Adding an operation to the definition adds a method to the interface, so the API does not compile until the
+// operation is implemented. Without --out, the routes are printed
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+const modelsImport = "github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+
+func main() {
+
+	template := flag.String("template", "api.yaml", "CloudFormation template defining the API")
+	pkg := flag.String("package", "front", "package of the generated routes")
+	models := flag.String("models", modelsImport, "import path of the models package")
+	out := flag.String("out", "", "file to write the routes to (default standard output)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "USAGE %v [--template api.yaml] [--package front] [--models import/path] [--out file.go]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := run(*template, *pkg, *models, *out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(template, pkg, models, out string) error {
+
+	raw, err := ioutil.ReadFile(template)
+
+	if err != nil {
+		return err
+	}
+
+	doc, err := swagger.ParseTemplate(raw, nil)
+
+	if err != nil {
+		return err
+	}
+
+	routes, err := swagger.GenerateRoutes(pkg, models, doc)
+
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err = os.Stdout.Write(routes)
+		return err
+	}
+
+	return ioutil.WriteFile(out, routes, 0644)
+}
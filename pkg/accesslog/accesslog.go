@@ -0,0 +1,300 @@
+// The accesslog package writes one line per request in Apache combined, JSON or custom template format, with sampling
+// and redaction of sensitive headers and query parameters.
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+const (
+	FormatCombined = "combined"
+	FormatJSON     = "json"
+
+	// The replacement for redacted header and query parameter values
+	Redacted = "[REDACTED]"
+
+	combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Headers carrying credentials, whose values are always redacted
+var CredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Amz-Security-Token"}
+
+// Entry describes a handled request
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	RequestID string            `json:"requestId,omitempty"`
+	SourceIP  string            `json:"sourceIp"`
+	User      string            `json:"user,omitempty"`
+	UserAgent string            `json:"userAgent"`
+	Referer   string            `json:"referer,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Protocol  string            `json:"protocol,omitempty"`
+	Route     string            `json:"route"`
+	Query     map[string]string `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Status    int               `json:"status"`
+	Bytes     int               `json:"bytes"`
+	Latency   time.Duration     `json:"-"`
+	LatencyMs float64           `json:"latencyMs"`
+}
+
+// RequestURI returns the path and query string, with query parameters in sorted order
+func (entry Entry) RequestURI() string {
+
+	if len(entry.Query) == 0 {
+		return entry.Path
+	}
+
+	keys := make([]string, 0, len(entry.Query))
+
+	for k := range entry.Query {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+
+	for i, k := range keys {
+		parts[i] = url.QueryEscape(k) + "=" + url.QueryEscape(entry.Query[k])
+	}
+
+	return entry.Path + "?" + strings.Join(parts, "&")
+}
+
+// Config configures a Logger
+type Config struct {
+	// FormatCombined, FormatJSON or a text/template executed with an Entry. Defaults to FormatCombined
+	Format string
+	// Where access log lines are written. Defaults to os.Stdout
+	Writer io.Writer
+	// The fraction of successful requests to log, from 0 to 1. Zero is treated as 1. Client and server errors are always
+	// logged
+	SampleRate float64
+	// Header names, matched case-insensitively, whose values are redacted as well as the CredentialHeaders
+	RedactHeaders []string
+	// Query parameter names whose values are redacted
+	RedactQuery []string
+	// Source of random numbers in [0, 1) for sampling. Defaults to math/rand
+	Random func() float64
+}
+
+// Logger writes access log entries
+type Logger struct {
+	config   Config
+	template *template.Template
+	mu       sync.Mutex
+}
+
+// New Create a new Logger object, returning an error if a custom format is not a valid template
+func New(config Config) (*Logger, error) {
+
+	if config.Format == "" {
+		config.Format = FormatCombined
+	}
+
+	if config.Writer == nil {
+		config.Writer = os.Stdout
+	}
+
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		config.SampleRate = 1
+	}
+
+	if config.Random == nil {
+		config.Random = rand.Float64
+	}
+
+	logger := &Logger{config: config}
+
+	if config.Format != FormatCombined && config.Format != FormatJSON {
+
+		tmpl, err := template.New("accesslog").Parse(config.Format)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid access log format: %v", err)
+		}
+
+		logger.template = tmpl
+	}
+
+	return logger, nil
+}
+
+// Log writes an entry, subject to sampling and with sensitive values redacted
+func (logger *Logger) Log(entry Entry) {
+
+	if entry.Status < 400 && logger.config.SampleRate < 1 && logger.config.Random() >= logger.config.SampleRate {
+		return
+	}
+
+	entry = logger.redact(entry)
+	entry.LatencyMs = float64(entry.Latency) / float64(time.Millisecond)
+
+	line, err := logger.format(entry)
+
+	if err != nil {
+		line = fmt.Sprintf("Access log format error: %v", err)
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	io.WriteString(logger.config.Writer, strings.TrimRight(line, "\n")+"\n")
+}
+
+func (logger *Logger) format(entry Entry) (string, error) {
+
+	switch logger.config.Format {
+
+	case FormatCombined:
+		return formatCombined(entry), nil
+
+	case FormatJSON:
+
+		raw, err := json.Marshal(entry)
+
+		return string(raw), err
+	}
+
+	buf := &bytes.Buffer{}
+	err := logger.template.Execute(buf, entry)
+
+	return buf.String(), err
+}
+
+// Formats an entry in Apache combined log format, followed by the latency in microseconds as with Apache's %D
+//
+// As with Apache, request values are escaped so that a client cannot break out of a quoted field or forge a log line
+func formatCombined(entry Entry) string {
+
+	protocol := entry.Protocol
+
+	if protocol == "" {
+		protocol = "HTTP/1.1"
+	}
+
+	return fmt.Sprintf(`%v - %v [%v] "%v %v %v" %v %v "%v" "%v" %v`,
+		dash(escape(entry.SourceIP)),
+		dash(escape(entry.User)),
+		entry.Time.Format(combinedTimeLayout),
+		escape(entry.Method),
+		escape(entry.RequestURI()),
+		escape(protocol),
+		entry.Status,
+		entry.Bytes,
+		dash(escape(entry.Referer)),
+		dash(escape(entry.UserAgent)),
+		entry.Latency.Microseconds(),
+	)
+}
+
+// Escapes a value as Apache's ap_escape_logitem does: quotes and backslashes are escaped with a backslash, common control
+// characters use their C escapes and any other byte which is not printable ASCII is written as \xHH
+func escape(s string) string {
+
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+
+		c := s[i]
+
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\b':
+			b.WriteString(`\b`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\v':
+			b.WriteString(`\v`)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+func dash(s string) string {
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+func (logger *Logger) redact(entry Entry) Entry {
+
+	if len(entry.Headers) > 0 {
+
+		headers := make(map[string]string, len(entry.Headers))
+
+		for k, v := range entry.Headers {
+			if containsFold(CredentialHeaders, k) || containsFold(logger.config.RedactHeaders, k) {
+				v = Redacted
+			}
+			headers[k] = v
+		}
+
+		entry.Headers = headers
+	}
+
+	if len(entry.Query) > 0 {
+
+		query := make(map[string]string, len(entry.Query))
+
+		for k, v := range entry.Query {
+			if contains(logger.config.RedactQuery, k) {
+				v = Redacted
+			}
+			query[k] = v
+		}
+
+		entry.Query = query
+	}
+
+	return entry
+}
+
+func contains(list []string, s string) bool {
+
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+
+	return false
+}
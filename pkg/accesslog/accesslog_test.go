@@ -0,0 +1,149 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func testEntry() Entry {
+	return Entry{
+		Time:      time.Date(2019, 1, 2, 14, 52, 36, 0, time.UTC),
+		RequestID: "test-request",
+		SourceIP:  "192.0.2.1",
+		UserAgent: "curl/7.64.1",
+		Method:    "GET",
+		Path:      "/calc/add",
+		Route:     "GET/calc/{op}",
+		Query:     map[string]string{"val2": "2", "val1": "1", "token": "secret"},
+		Headers:   map[string]string{"authorization": "Bearer secret", "cookie": "session=secret", "X-Debug-Token": "secret", "Accept-Language": "en-GB"},
+		Status:    200,
+		Bytes:     85,
+		Latency:   1500 * time.Microsecond,
+	}
+}
+
+func TestCombinedFormat(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+
+	logger, err := New(Config{
+		Writer:      buf,
+		RedactQuery: []string{"token"},
+	})
+
+	utils.AssertNoError(t, "Combined logger", err)
+
+	logger.Log(testEntry())
+
+	expected := `192.0.2.1 - - [02/Jan/2019:14:52:36 +0000] "GET /calc/add?token=%5BREDACTED%5D&val1=1&val2=2 HTTP/1.1" 200 85 "-" "curl/7.64.1" 1500` + "\n"
+
+	utils.AssertEquals(t, "Combined log line", expected, buf.String())
+}
+
+func TestCombinedFormatEscaping(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+
+	logger, err := New(Config{Writer: buf})
+
+	utils.AssertNoError(t, "Combined logger", err)
+
+	entry := testEntry()
+	entry.Query = nil
+	entry.Referer = `https://example.com/\"`
+	entry.UserAgent = "evil\" 200 0 \"-\" \"-\" 0\n192.0.2.2 - - [forged]\x00\x1b[31m\xff"
+
+	logger.Log(entry)
+
+	expected := `192.0.2.1 - - [02/Jan/2019:14:52:36 +0000] "GET /calc/add HTTP/1.1" 200 85 "https://example.com/\\\"" ` +
+		`"evil\" 200 0 \"-\" \"-\" 0\n192.0.2.2 - - [forged]\x00\x1b[31m\xff" 1500` + "\n"
+
+	utils.AssertEquals(t, "Escaped combined log line", expected, buf.String())
+}
+
+func TestJSONFormat(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+
+	logger, err := New(Config{
+		Format:        FormatJSON,
+		Writer:        buf,
+		RedactHeaders: []string{"x-debug-token"},
+	})
+
+	utils.AssertNoError(t, "JSON logger", err)
+
+	logger.Log(testEntry())
+
+	entry := Entry{}
+	err = json.Unmarshal(buf.Bytes(), &entry)
+
+	utils.AssertNoError(t, "JSON log line parses", err)
+	utils.AssertEquals(t, "JSON source IP", "192.0.2.1", entry.SourceIP)
+	utils.AssertEquals(t, "JSON route", "GET/calc/{op}", entry.Route)
+	utils.AssertEquals(t, "JSON latency", 1.5, entry.LatencyMs)
+	utils.AssertEquals(t, "JSON credential header redacted by default", Redacted, entry.Headers["authorization"])
+	utils.AssertEquals(t, "JSON cookie redacted by default", Redacted, entry.Headers["cookie"])
+	utils.AssertEquals(t, "JSON redacted header", Redacted, entry.Headers["X-Debug-Token"])
+	utils.AssertEquals(t, "JSON unredacted header", "en-GB", entry.Headers["Accept-Language"])
+	utils.AssertEquals(t, "JSON unredacted query", "secret", entry.Query["token"])
+}
+
+func TestTemplateFormat(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+
+	logger, err := New(Config{
+		Format:        `{{.RequestID}} {{.Route}} {{.Status}} {{.LatencyMs}}ms {{index .Headers "authorization"}}`,
+		Writer:        buf,
+		RedactHeaders: []string{"authorization"},
+	})
+
+	utils.AssertNoError(t, "Template logger", err)
+
+	logger.Log(testEntry())
+
+	utils.AssertEquals(t, "Template log line", "test-request GET/calc/{op} 200 1.5ms [REDACTED]\n", buf.String())
+
+	_, err = New(Config{Format: "{{.Unclosed"})
+
+	utils.AssertErrorEquals(t, "Invalid template", `Invalid access log format: template: accesslog:1: unclosed action`, err)
+}
+
+func TestSampling(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	random := 0.75
+
+	logger, _ := New(Config{
+		Format:     "{{.Status}}",
+		Writer:     buf,
+		SampleRate: 0.5,
+		Random:     func() float64 { return random },
+	})
+
+	entry := testEntry()
+	logger.Log(entry)
+
+	utils.AssertEquals(t, "Unsampled success is not logged", "", buf.String())
+
+	entry.Status = 502
+	logger.Log(entry)
+
+	utils.AssertEquals(t, "Server error is always logged", "502\n", buf.String())
+
+	entry.Status = 404
+	logger.Log(entry)
+
+	utils.AssertEquals(t, "Client error is always logged", "502\n404\n", buf.String())
+
+	random = 0.25
+	entry.Status = 200
+	logger.Log(entry)
+
+	utils.AssertEquals(t, "Sampled success is logged", "502\n404\n200\n", buf.String())
+}
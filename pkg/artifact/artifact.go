@@ -0,0 +1,352 @@
+// The artifact package builds the lambda into a reproducible zip, named by its SHA-256 digest and described by a
+// manifest of its source commit, Go version, target and files. The same source, build settings and Go version always
+// produce the same zip, whatever the commit, as nothing about the commit is built into the executable, so an unchanged
+// lambda need not be uploaded again, and a zip can be verified against its manifest.
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+)
+
+// The modification time of every zip entry: the earliest time a zip can represent
+var ModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// The name of the executable which the provided.al2 and provided.al2023 custom runtimes run
+const Bootstrap = "bootstrap"
+
+// The build tag which leaves out the RPC support of aws-lambda-go, which only the go1.x runtime needs
+const NoRPCTag = "lambda.norpc"
+
+// File is a file to add to a zip
+type File struct {
+	// The name in the zip, with / separators
+	Name string
+	// The path of the file to read
+	Path string
+	// If true, the file is executable
+	Executable bool
+}
+
+// FileDigest describes a file in a zip
+type FileDigest struct {
+	Name       string `json:"name"`
+	SHA256     string `json:"sha256"`
+	Size       int64  `json:"size"`
+	Executable bool   `json:"executable"`
+}
+
+// Manifest describes a zip and the source it was built from
+type Manifest struct {
+	// The name of the zip, which is its SHA-256 digest
+	Name       string       `json:"name"`
+	SHA256     string       `json:"sha256"`
+	Size       int64        `json:"size"`
+	Release    string       `json:"release"`
+	Branch     string       `json:"branch"`
+	Commit     string       `json:"commit"`
+	CommitTime time.Time    `json:"commitTime"`
+	GoVersion  string       `json:"goVersion"`
+	GOOS       string       `json:"goos"`
+	GOARCH     string       `json:"goarch"`
+	Tags       []string     `json:"tags,omitempty"`
+	Files      []FileDigest `json:"files"`
+}
+
+// Artifact is a built zip and its manifest
+type Artifact struct {
+	// The path of the zip
+	Path string
+	// The path of the manifest, alongside the zip
+	ManifestPath string
+	Manifest     Manifest
+}
+
+// Source identifies the source a zip is built from, for its manifest. As commits which do not change the lambda give
+// the same zip, the manifest of a zip describes the commit it was first built from
+type Source struct {
+	Release    string
+	Branch     string
+	Commit     string
+	CommitTime time.Time
+}
+
+// WriteZip writes a zip of files in name order, with fixed modification times and permissions, so that the same
+// contents always give the same zip. It returns digests of the files
+func WriteZip(w io.Writer, files []File) ([]FileDigest, error) {
+
+	sorted := append([]File{}, files...)
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	archive := zip.NewWriter(w)
+	digests := make([]FileDigest, len(sorted))
+
+	for i, file := range sorted {
+
+		raw, err := ioutil.ReadFile(file.Path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		header := &zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: ModTime}
+		header.SetMode(mode(file.Executable))
+
+		entry, err := archive.CreateHeader(header)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := entry.Write(raw); err != nil {
+			return nil, err
+		}
+
+		digests[i] = FileDigest{Name: file.Name, SHA256: digest(raw), Size: int64(len(raw)), Executable: file.Executable}
+	}
+
+	return digests, archive.Close()
+}
+
+func mode(executable bool) os.FileMode {
+
+	if executable {
+		return 0755
+	}
+
+	return 0644
+}
+
+func digest(raw []byte) string {
+
+	sum := sha256.Sum256(raw)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Builder builds a lambda executable for Linux and zips it
+type Builder struct {
+	// Runs go. Its environment must not set GOOS, GOARCH or CGO_ENABLED
+	Runner command.Runner
+	// The main package of the lambda
+	Main string
+	// The name of the executable in the zip, which must match the handler of the lambda
+	Executable string
+	// The target architecture: amd64 (the default) or arm64
+	GOARCH string
+	// Build tags, such as NoRPCTag
+	Tags []string
+	// The directory the zip and manifest are written to
+	Dir string
+}
+
+// Build builds the lambda from source and writes the zip and its manifest to the builder's directory, named by the
+// SHA-256 digest of the zip. The build is reproducible: paths are trimmed, the build ID is empty and no VCS information
+// or release is stamped, so commits which do not change the lambda's code give the same zip. The lambda reads its
+// release, branch, commit and build time from its environment instead (see buildinfo.Read)
+func (builder Builder) Build(ctx context.Context, source Source) (Artifact, error) {
+
+	goarch := builder.GOARCH
+
+	if goarch == "" {
+		goarch = "amd64"
+	}
+
+	if goarch != "amd64" && goarch != "arm64" {
+		return Artifact{}, fmt.Errorf("Architecture must be amd64 or arm64, not %v", goarch)
+	}
+
+	out, err := builder.Runner.Run(ctx, "go", "env", "GOVERSION")
+
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	manifest := Manifest{
+		Release:    source.Release,
+		Branch:     source.Branch,
+		Commit:     source.Commit,
+		CommitTime: source.CommitTime.UTC(),
+		GoVersion:  strings.TrimSpace(string(out)),
+		GOOS:       "linux",
+		GOARCH:     goarch,
+		Tags:       builder.Tags,
+	}
+
+	dir, err := ioutil.TempDir("", "artifact")
+
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	executable := filepath.Join(dir, "executable")
+	args := []string{"GOOS=linux", "GOARCH=" + goarch, "CGO_ENABLED=0", "go", "build", "-trimpath", "-buildvcs=false"}
+
+	if len(builder.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(builder.Tags, ","))
+	}
+
+	if _, err := builder.Runner.Run(ctx, "env", append(args, "-ldflags", "-buildid=", "-o", executable, builder.Main)...); err != nil {
+		return Artifact{}, err
+	}
+
+	buf := &bytes.Buffer{}
+
+	if manifest.Files, err = WriteZip(buf, []File{{Name: builder.Executable, Path: executable, Executable: true}}); err != nil {
+		return Artifact{}, err
+	}
+
+	manifest.SHA256 = digest(buf.Bytes())
+	manifest.Size = int64(buf.Len())
+	manifest.Name = manifest.SHA256 + ".zip"
+
+	return Write(builder.Dir, buf.Bytes(), manifest)
+}
+
+// Write writes a zip and its manifest to a directory
+func Write(dir string, zipped []byte, manifest Manifest) (Artifact, error) {
+
+	artifact := Artifact{
+		Path:         filepath.Join(dir, manifest.Name),
+		ManifestPath: filepath.Join(dir, manifest.SHA256+".json"),
+		Manifest:     manifest,
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return Artifact{}, err
+	}
+
+	raw, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	if err := ioutil.WriteFile(artifact.Path, zipped, 0644); err != nil {
+		return Artifact{}, err
+	}
+
+	return artifact, ioutil.WriteFile(artifact.ManifestPath, raw, 0644)
+}
+
+// ReadManifest reads a manifest file
+func ReadManifest(path string) (Manifest, error) {
+
+	var manifest Manifest
+
+	raw, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return manifest, err
+	}
+
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return manifest, fmt.Errorf("Cannot parse manifest %v: %v", path, err)
+	}
+
+	return manifest, nil
+}
+
+// Verify checks that a zip matches its manifest: its digest and size, and the name, digest, size and mode of every file
+// in it. It returns an error listing every difference
+func Verify(zipped []byte, manifest Manifest) error {
+
+	var problems []string
+
+	if sum := digest(zipped); sum != manifest.SHA256 {
+		problems = append(problems, fmt.Sprintf("zip SHA-256 is %v, not %v", sum, manifest.SHA256))
+	}
+
+	if int64(len(zipped)) != manifest.Size {
+		problems = append(problems, fmt.Sprintf("zip size is %v, not %v", len(zipped), manifest.Size))
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(zipped), int64(len(zipped)))
+
+	if err != nil {
+		return fmt.Errorf("Invalid artifact: %v", err)
+	}
+
+	expected := map[string]FileDigest{}
+
+	for _, file := range manifest.Files {
+		expected[file.Name] = file
+	}
+
+	for _, entry := range archive.File {
+
+		want, ok := expected[entry.Name]
+		delete(expected, entry.Name)
+
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%v is not in the manifest", entry.Name))
+			continue
+		}
+
+		got, err := entryDigest(entry)
+
+		if err != nil {
+			return fmt.Errorf("Invalid artifact: %v", err)
+		}
+
+		if got != want {
+			problems = append(problems, fmt.Sprintf("%v does not match the manifest", entry.Name))
+		}
+	}
+
+	for _, file := range manifest.Files {
+		if _, missing := expected[file.Name]; missing {
+			problems = append(problems, fmt.Sprintf("%v is missing", file.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Artifact does not match its manifest: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func entryDigest(entry *zip.File) (FileDigest, error) {
+
+	reader, err := entry.Open()
+
+	if err != nil {
+		return FileDigest{}, err
+	}
+
+	defer reader.Close()
+
+	raw, err := ioutil.ReadAll(reader)
+
+	if err != nil {
+		return FileDigest{}, err
+	}
+
+	return FileDigest{
+		Name:       entry.Name,
+		SHA256:     digest(raw),
+		Size:       int64(len(raw)),
+		Executable: entry.Mode()&0111 != 0,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// fakeGo answers go env and writes the command line of go build as the executable, so that builds with the same
+// settings produce the same executable
+type fakeGo struct {
+	calls []string
+}
+
+func (runner *fakeGo) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	command := strings.Join(append([]string{name}, args...), " ")
+	runner.calls = append(runner.calls, command)
+
+	if command == "go env GOVERSION" {
+		return []byte("go1.21.0\n"), nil
+	}
+
+	for i, arg := range args {
+		if arg == "-o" {
+			// The executable path is temporary, so is left out of its contents
+			contents := strings.Replace(command, args[i+1], "", 1)
+			return nil, ioutil.WriteFile(args[i+1], []byte(contents), 0755)
+		}
+	}
+
+	return nil, nil
+}
+
+var testSource = Source{
+	Release:    "v1.0.1",
+	Branch:     "master",
+	Commit:     "a00eaaf456941631",
+	CommitTime: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+}
+
+func TestWriteZip(t *testing.T) {
+
+	dir := t.TempDir()
+
+	ioutil.WriteFile(filepath.Join(dir, "a"), []byte("first"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b"), []byte("second"), 0600)
+
+	first := &bytes.Buffer{}
+	digests, err := WriteZip(first, []File{{Name: "bin/b", Path: filepath.Join(dir, "b"), Executable: true}, {Name: "a", Path: filepath.Join(dir, "a")}})
+
+	utils.AssertNoError(t, "Write zip", err)
+	utils.AssertEquals(t, "Sorted digests", "a", digests[0].Name)
+
+	os.Chtimes(filepath.Join(dir, "a"), time.Now(), time.Now().Add(time.Hour))
+
+	second := &bytes.Buffer{}
+	_, err = WriteZip(second, []File{{Name: "a", Path: filepath.Join(dir, "a")}, {Name: "bin/b", Path: filepath.Join(dir, "b"), Executable: true}})
+
+	utils.AssertNoError(t, "Rewrite zip", err)
+	utils.AssertTrue(t, "Same zip regardless of order and times", bytes.Equal(first.Bytes(), second.Bytes()))
+}
+
+func TestBuild(t *testing.T) {
+
+	runner := &fakeGo{}
+	builder := Builder{Runner: runner, Main: "./api", Executable: Bootstrap, Tags: []string{NoRPCTag}, Dir: t.TempDir()}
+
+	built, err := builder.Build(context.Background(), testSource)
+
+	utils.AssertNoError(t, "Build", err)
+	utils.AssertEquals(t, "Build command", "env GOOS=linux GOARCH=amd64 CGO_ENABLED=0 go build -trimpath -buildvcs=false -tags lambda.norpc "+
+		"-ldflags -buildid=", runner.calls[1][:strings.Index(runner.calls[1], " -o ")])
+	utils.AssertEquals(t, "Zip name", built.Manifest.SHA256+".zip", filepath.Base(built.Path))
+	utils.AssertEquals(t, "Manifest Go version", "go1.21.0", built.Manifest.GoVersion)
+	utils.AssertEquals(t, "Manifest commit", "a00eaaf456941631", built.Manifest.Commit)
+	utils.AssertEquals(t, "Manifest file", "bootstrap", built.Manifest.Files[0].Name)
+	utils.AssertTrue(t, "Manifest executable", built.Manifest.Files[0].Executable)
+
+	manifest, err := ReadManifest(built.ManifestPath)
+
+	utils.AssertNoError(t, "Read manifest", err)
+	utils.AssertEquals(t, "Manifest file digest", built.Manifest.Files[0].SHA256, manifest.Files[0].SHA256)
+
+	rebuilt, err := builder.Build(context.Background(), testSource)
+
+	utils.AssertNoError(t, "Rebuild", err)
+	utils.AssertEquals(t, "Reproducible", built.Manifest.SHA256, rebuilt.Manifest.SHA256)
+
+	// A later commit which only changes files outside the lambda, such as the README
+	later := Source{Release: "v1.0.2", Branch: "master", Commit: "b11fbbf567052742", CommitTime: testSource.CommitTime.Add(time.Hour)}
+
+	rebuilt, err = builder.Build(context.Background(), later)
+
+	utils.AssertNoError(t, "Build later commit", err)
+	utils.AssertEquals(t, "Same zip for a later commit", built.Manifest.SHA256, rebuilt.Manifest.SHA256)
+	utils.AssertEquals(t, "Manifest of the later commit", "b11fbbf567052742", rebuilt.Manifest.Commit)
+
+	builder.GOARCH = "arm64"
+
+	arm, err := builder.Build(context.Background(), testSource)
+
+	utils.AssertNoError(t, "Build arm64", err)
+	utils.AssertEquals(t, "Manifest architecture", "arm64", arm.Manifest.GOARCH)
+	utils.AssertTrue(t, "Architecture changes digest", arm.Manifest.SHA256 != built.Manifest.SHA256)
+
+	builder.GOARCH = "386"
+
+	_, err = builder.Build(context.Background(), testSource)
+
+	utils.AssertErrorEquals(t, "Invalid architecture", "Architecture must be amd64 or arm64, not 386", err)
+}
+
+func TestVerify(t *testing.T) {
+
+	built, err := Builder{Runner: &fakeGo{}, Main: "./api", Executable: Bootstrap, Dir: t.TempDir()}.Build(context.Background(), testSource)
+
+	utils.AssertNoError(t, "Build", err)
+
+	zipped, _ := ioutil.ReadFile(built.Path)
+
+	utils.AssertNoError(t, "Verify", Verify(zipped, built.Manifest))
+
+	manifest := built.Manifest
+	manifest.Files = append([]FileDigest{{Name: "bootstrap", SHA256: "0000", Size: 4, Executable: true}}, FileDigest{Name: "config.json"})
+
+	utils.AssertErrorEquals(t, "Verify changed file", "Artifact does not match its manifest: bootstrap does not match the manifest; config.json is missing",
+		Verify(zipped, manifest))
+
+	manifest = built.Manifest
+	manifest.SHA256 = "0000"
+	manifest.Size = 1
+
+	utils.AssertErrorEquals(t, "Verify changed zip", "Artifact does not match its manifest: "+
+		"zip SHA-256 is "+built.Manifest.SHA256+", not 0000; zip size is "+fmt.Sprint(built.Manifest.Size)+", not 1",
+		Verify(zipped, manifest))
+
+	err = Verify([]byte("not a zip"), built.Manifest)
+
+	utils.AssertTrue(t, "Verify invalid zip", err != nil && strings.HasPrefix(err.Error(), "Invalid artifact"))
+}
@@ -0,0 +1,154 @@
+// The buildinfo package describes the build of the running executable. Release, branch, commit and build time are
+// set at compile time with -ldflags (see LDFlags) or else read from the environment, which is how the lambda, built
+// reproducibly without them, gets them. The VCS revision, dirty flag, Go version and module versions are read from the
+// build information which the go command embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The import path of this package, for -ldflags -X
+const importPath = "github.com/merlincox/aws-api-gateway-deploy/pkg/buildinfo"
+
+// Set at compile time with -ldflags "-X github.com/merlincox/aws-api-gateway-deploy/pkg/buildinfo.Release=v1.0.0" etc
+var (
+	Release   string
+	Branch    string
+	Commit    string
+	BuildTime string
+)
+
+// The length to which commit hashes are shortened, as for git rev-parse --short=16
+const commitLength = 16
+
+// Info describes a build
+type Info struct {
+	Release   string
+	Branch    string
+	Commit    string
+	Dirty     bool
+	BuildTime string
+	GoVersion string
+	// The main module path
+	Path string
+	// The modules built into the executable, sorted by path
+	Dependencies []Module
+}
+
+// Module is a module version
+type Module struct {
+	Path    string
+	Version string
+}
+
+// Read returns the build information of the running executable. Values set with -ldflags take precedence, then the
+// RELEASE, BRANCH, COMMIT and BUILD_TIME values of getenv, and then the embedded VCS information
+func Read(getenv func(string) string) Info {
+
+	bi, ok := debug.ReadBuildInfo()
+
+	if !ok {
+		bi = nil
+	}
+
+	return merge(bi, getenv)
+}
+
+func merge(bi *debug.BuildInfo, getenv func(string) string) Info {
+
+	info := Info{
+		Release:   first(Release, getenv("RELEASE")),
+		Branch:    first(Branch, getenv("BRANCH")),
+		Commit:    first(Commit, getenv("COMMIT")),
+		BuildTime: first(BuildTime, getenv("BUILD_TIME")),
+		GoVersion: runtime.Version(),
+	}
+
+	if bi == nil {
+		return info
+	}
+
+	if bi.GoVersion != "" {
+		info.GoVersion = bi.GoVersion
+	}
+
+	info.Path = bi.Main.Path
+
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = shorten(setting.Value)
+			}
+		case "vcs.modified":
+			info.Dirty = setting.Value == "true"
+		}
+	}
+
+	for _, dep := range bi.Deps {
+
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		info.Dependencies = append(info.Dependencies, Module{Path: dep.Path, Version: dep.Version})
+	}
+
+	sort.Slice(info.Dependencies, func(i, j int) bool {
+		return info.Dependencies[i].Path < info.Dependencies[j].Path
+	})
+
+	return info
+}
+
+// LDFlags returns the -ldflags value which sets the release, branch, commit and build time
+func LDFlags(release, branch, commit string, buildTime time.Time) string {
+
+	values := map[string]string{
+		"Release":   release,
+		"Branch":    branch,
+		"Commit":    commit,
+		"BuildTime": buildTime.UTC().Format(time.RFC3339),
+	}
+
+	names := make([]string, 0, len(values))
+
+	for name := range values {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	flags := make([]string, len(names))
+
+	for i, name := range names {
+		flags[i] = "-X " + importPath + "." + name + "=" + values[name]
+	}
+
+	return strings.Join(flags, " ")
+}
+
+func first(values ...string) string {
+
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func shorten(commit string) string {
+
+	if len(commit) > commitLength {
+		return commit[:commitLength]
+	}
+
+	return commit
+}
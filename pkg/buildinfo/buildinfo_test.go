@@ -0,0 +1,75 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func testEnv(values map[string]string) func(string) string {
+	return func(key string) string {
+		return values[key]
+	}
+}
+
+func testBuildInfo() *debug.BuildInfo {
+	return &debug.BuildInfo{
+		GoVersion: "go1.18.2",
+		Main:      debug.Module{Path: "github.com/merlincox/aws-api-gateway-deploy"},
+		Deps: []*debug.Module{
+			{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+			{Path: "golang.org/x/text", Version: "v0.3.5", Replace: &debug.Module{Path: "golang.org/x/text", Version: "v0.3.6"}},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "a00eaaf4569416318d3c7b2e1f0a9b8c7d6e5f40"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+}
+
+func TestMergeVCS(t *testing.T) {
+
+	info := merge(testBuildInfo(), testEnv(map[string]string{"RELEASE": "v1.0.1", "BRANCH": "master", "BUILD_TIME": "2019-01-02T03:04:05Z"}))
+
+	utils.AssertEquals(t, "Release from env", "v1.0.1", info.Release)
+	utils.AssertEquals(t, "Branch from env", "master", info.Branch)
+	utils.AssertEquals(t, "Build time from env", "2019-01-02T03:04:05Z", info.BuildTime)
+	utils.AssertEquals(t, "Commit from VCS", "a00eaaf456941631", info.Commit)
+	utils.AssertTrue(t, "Dirty", info.Dirty)
+	utils.AssertEquals(t, "Go version", "go1.18.2", info.GoVersion)
+	utils.AssertEquals(t, "Main path", "github.com/merlincox/aws-api-gateway-deploy", info.Path)
+	utils.AssertEquals(t, "Number of dependencies", 2, len(info.Dependencies))
+	utils.AssertEquals(t, "Replaced dependency", Module{Path: "golang.org/x/text", Version: "v0.3.6"}, info.Dependencies[0])
+	utils.AssertEquals(t, "Dependency", Module{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"}, info.Dependencies[1])
+}
+
+func TestMergePrecedence(t *testing.T) {
+
+	Release, Commit, BuildTime = "v2.0.0", "b11fbbf567052742", "2019-01-02T03:04:05Z"
+	defer func() { Release, Commit, BuildTime = "", "", "" }()
+
+	info := merge(testBuildInfo(), testEnv(map[string]string{"RELEASE": "v1.0.1", "COMMIT": "c22"}))
+
+	utils.AssertEquals(t, "Release from ldflags", "v2.0.0", info.Release)
+	utils.AssertEquals(t, "Commit from ldflags", "b11fbbf567052742", info.Commit)
+	utils.AssertEquals(t, "Build time from ldflags", "2019-01-02T03:04:05Z", info.BuildTime)
+
+	info = merge(nil, testEnv(nil))
+
+	utils.AssertEquals(t, "Commit without build info", "b11fbbf567052742", info.Commit)
+	utils.AssertFalse(t, "Not dirty without build info", info.Dirty)
+	utils.AssertEquals(t, "No dependencies", 0, len(info.Dependencies))
+}
+
+func TestLDFlags(t *testing.T) {
+
+	flags := LDFlags("v1.0.1", "master", "a00eaaf456941631", time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	utils.AssertEquals(t, "LDFlags",
+		"-X "+importPath+".Branch=master "+
+			"-X "+importPath+".BuildTime=2019-01-02T03:04:05Z "+
+			"-X "+importPath+".Commit=a00eaaf456941631 "+
+			"-X "+importPath+".Release=v1.0.1", flags)
+}
@@ -0,0 +1,203 @@
+// The client package calls the API over HTTP, returning its models and decoding its error responses into an Error.
+// Its operations are generated from the Swagger definition of the API in api.yaml
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// The methods of Client in operations.go are generated from the API definition
+//go:generate go run ../../cmd/client --template ../../api.yaml --out operations.go
+
+const (
+	// The default number of times a request is retried
+	DefaultRetries = 2
+	// The default time waited before the first retry, which doubles for each retry after it
+	DefaultBackoff = 100 * time.Millisecond
+)
+
+// Client calls the API deployed at a base URL, such as https://abc123.execute-api.eu-west-1.amazonaws.com/test
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	retries        int
+	backoff        time.Duration
+	acceptLanguage string
+}
+
+// An Option configures optional behaviour of a Client
+type Option func(client *Client)
+
+// WithHTTPClient sets the HTTP client requests are made with, instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) {
+		client.httpClient = httpClient
+	}
+}
+
+// WithRetries sets how many times a request is retried after a network error or a 429, 502, 503 or 504 response, and
+// the time waited before the first retry, which doubles for each retry after it. Only requests of idempotent methods
+// are retried
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(client *Client) {
+		client.retries = retries
+		client.backoff = backoff
+	}
+}
+
+// WithAcceptLanguage sets the Accept-Language header of requests whose parameters do not set it
+func WithAcceptLanguage(language string) Option {
+	return func(client *Client) {
+		client.acceptLanguage = language
+	}
+}
+
+// NewClient creates a Client of the API at a base URL
+func NewClient(baseURL string, opts ...Option) *Client {
+
+	client := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retries:    DefaultRetries,
+		backoff:    DefaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client
+}
+
+// Error is an error response of the API. It is a models.ApiError, whose body is decoded from the response, or made
+// from the status if the response is not an ApiErrorBody
+type Error struct {
+	statusCode int
+	body       models.ApiErrorBody
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%v: %v", err.statusCode, err.body.Message)
+}
+
+// StatusCode returns the HTTP status of the response
+func (err *Error) StatusCode() int {
+	return err.statusCode
+}
+
+// ErrorBody returns the body of the response
+func (err *Error) ErrorBody() models.ApiErrorBody {
+	return err.body
+}
+
+// Returns the Error of a response with a status other than 2xx
+func responseError(statusCode int, body []byte) *Error {
+
+	apiErr := &Error{statusCode: statusCode}
+
+	if json.Unmarshal(body, &apiErr.body) != nil || apiErr.body.Message == "" {
+		apiErr.body = models.ApiErrorBody{
+			Message: http.StatusText(statusCode),
+			Code:    statusCode,
+		}
+	}
+
+	return apiErr
+}
+
+// Makes a request of the API, retrying it if it fails transiently, and decodes a successful response into result
+// unless it is nil. Any other response is returned as an *Error
+func (client *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, result interface{}) error {
+
+	target := client.baseURL + path
+
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		retry, err := client.attempt(ctx, method, target, header, result)
+
+		if !retry || attempt >= client.retries || !idempotent(method) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(client.backoff << attempt):
+		}
+	}
+}
+
+// Makes a request once, returning its error and whether it failed transiently
+func (client *Client) attempt(ctx context.Context, method, target string, header http.Header, result interface{}) (bool, error) {
+
+	request, err := http.NewRequestWithContext(ctx, method, target, nil)
+
+	if err != nil {
+		return false, err
+	}
+
+	if header != nil {
+		request.Header = header.Clone()
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	if client.acceptLanguage != "" && request.Header.Get("Accept-Language") == "" {
+		request.Header.Set("Accept-Language", client.acceptLanguage)
+	}
+
+	response, err := client.httpClient.Do(request)
+
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return transient(response.StatusCode), responseError(response.StatusCode, body)
+	}
+
+	if result == nil || len(body) == 0 {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return false, fmt.Errorf("Invalid response to %v %v: %v", method, target, err)
+	}
+
+	return false, nil
+}
+
+func transient(statusCode int) bool {
+
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+func idempotent(method string) bool {
+	return method != http.MethodPost && method != http.MethodPatch
+}
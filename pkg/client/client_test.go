@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/api/front"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func readDefinition(t *testing.T) swagger.Document {
+
+	template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+
+	utils.AssertNoError(t, "Read template", err)
+
+	doc, err := swagger.ParseTemplate(template, nil)
+
+	utils.AssertNoError(t, "Parse template", err)
+
+	return doc
+}
+
+// Serves the API from a Front, passing requests to its handler as API Gateway does after matching their paths to the
+// paths of the API definition
+func frontServer(t *testing.T, api front.Front) *httptest.Server {
+
+	doc := readDefinition(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		request := events.APIGatewayProxyRequest{
+			HTTPMethod:            r.Method,
+			Path:                  r.URL.Path,
+			Headers:               map[string]string{},
+			QueryStringParameters: map[string]string{},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				HTTPMethod:   r.Method,
+				ResourcePath: r.URL.Path,
+			},
+		}
+
+		for path := range doc.Paths {
+			if parameters, ok := matchPath(path, r.URL.Path); ok {
+				request.Resource = path
+				request.RequestContext.ResourcePath = path
+				request.PathParameters = parameters
+			}
+		}
+
+		for name := range r.Header {
+			request.Headers[name] = r.Header.Get(name)
+		}
+
+		for name := range r.URL.Query() {
+			request.QueryStringParameters[name] = r.URL.Query().Get(name)
+		}
+
+		response, err := api.Handler(r.Context(), request)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		for name, value := range response.Headers {
+			w.Header().Set(name, value)
+		}
+
+		w.WriteHeader(response.StatusCode)
+		w.Write([]byte(response.Body))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// Returns the parameters of a path of the API definition such as /calc/{op} in a request path, if it matches
+func matchPath(definition, path string) (map[string]string, bool) {
+
+	expected := strings.Split(definition, "/")
+	actual := strings.Split(path, "/")
+
+	if len(expected) != len(actual) {
+		return nil, false
+	}
+
+	parameters := map[string]string{}
+
+	for i, segment := range expected {
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			parameters[segment[1:len(segment)-1]] = actual[i]
+			continue
+		}
+
+		if segment != actual[i] {
+			return nil, false
+		}
+	}
+
+	return parameters, true
+}
+
+func TestGetStatus(t *testing.T) {
+
+	server := frontServer(t, front.NewFront(models.Status{Platform: "test", Release: "1.2.3", Branch: "main"}, 0))
+
+	status, err := NewClient(server.URL).GetStatus(context.Background())
+
+	utils.AssertNoError(t, "Get status", err)
+	utils.AssertEquals(t, "Platform", "test", status.Platform)
+	utils.AssertEquals(t, "Release", "1.2.3", status.Release)
+	utils.AssertEquals(t, "Branch", "main", status.Branch)
+}
+
+func TestGetCalcOp(t *testing.T) {
+
+	server := frontServer(t, front.NewFront(models.Status{}, 0))
+	french := "fr-FR"
+
+	result, err := NewClient(server.URL+"/").GetCalcOp(context.Background(), GetCalcOpParams{
+		Op:             "divide",
+		Val1:           7,
+		Val2:           2,
+		AcceptLanguage: &french,
+	})
+
+	utils.AssertNoError(t, "Calc", err)
+	utils.AssertEquals(t, "Op", "divide", result.Op)
+	utils.AssertEquals(t, "Val1", 7.0, result.Val1)
+	utils.AssertEquals(t, "Val2", 2.0, result.Val2)
+	utils.AssertEquals(t, "Locale", "fr-FR", result.Locale)
+	utils.AssertEquals(t, "Result", "3,5", result.Result)
+
+	result, err = NewClient(server.URL, WithAcceptLanguage("en-GB")).GetCalcOp(context.Background(), GetCalcOpParams{
+		Op:   "add",
+		Val1: 1000,
+		Val2: 0.5,
+	})
+
+	utils.AssertNoError(t, "Calc with default language", err)
+	utils.AssertEquals(t, "Default locale", "en-GB", result.Locale)
+	utils.AssertEquals(t, "Default locale result", "1,000.5", result.Result)
+}
+
+func TestErrorResponse(t *testing.T) {
+
+	server := frontServer(t, front.NewFront(models.Status{}, 0))
+
+	_, err := NewClient(server.URL).GetCalcOp(context.Background(), GetCalcOpParams{Op: "modulo", Val1: 1, Val2: 2})
+
+	var apiErr models.ApiError
+
+	utils.AssertTrue(t, "API error", errors.As(err, &apiErr))
+	utils.AssertEquals(t, "Status", http.StatusBadRequest, apiErr.StatusCode())
+	utils.AssertEquals(t, "Body", models.ApiErrorBody{
+		Message: "Invalid parameter op: modulo is not one of add, subtract, sub, multiply, mul, divide, div, power, pow, root, roo",
+		Code:    400,
+	}, apiErr.ErrorBody())
+	utils.AssertErrorEquals(t, "Error", "400: Invalid parameter op: modulo is not one of add, subtract, sub, multiply, mul, divide, div, power, pow, root, roo", err)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Not JSON", http.StatusForbidden)
+	}))
+
+	defer server.Close()
+
+	_, err = NewClient(server.URL).GetStatus(context.Background())
+
+	utils.AssertTrue(t, "Typed error", errors.As(err, &apiErr))
+	utils.AssertEquals(t, "Body from status", models.ApiErrorBody{Message: "Forbidden", Code: 403}, apiErr.ErrorBody())
+}
+
+func TestRetries(t *testing.T) {
+
+	api := frontServer(t, front.NewFront(models.Status{Platform: "test"}, 0))
+	failures := 2
+	attempts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		attempts++
+
+		if attempts <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		response, err := http.Get(api.URL + r.URL.Path)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		defer response.Body.Close()
+
+		body, _ := ioutil.ReadAll(response.Body)
+
+		w.WriteHeader(response.StatusCode)
+		w.Write(body)
+	}))
+
+	defer server.Close()
+
+	status, err := NewClient(server.URL, WithRetries(2, time.Millisecond)).GetStatus(context.Background())
+
+	utils.AssertNoError(t, "Retried", err)
+	utils.AssertEquals(t, "Platform", "test", status.Platform)
+	utils.AssertEquals(t, "Attempts", 3, attempts)
+
+	attempts = 0
+
+	_, err = NewClient(server.URL, WithRetries(1, time.Millisecond)).GetStatus(context.Background())
+
+	utils.AssertErrorEquals(t, "Retries exhausted", "503: Service Unavailable", err)
+	utils.AssertEquals(t, "Attempts when exhausted", 2, attempts)
+
+	attempts = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = NewClient(server.URL, WithRetries(5, time.Millisecond)).GetStatus(ctx)
+
+	utils.AssertTrue(t, "Cancelled", errors.Is(err, context.Canceled))
+	utils.AssertEquals(t, "Attempts when cancelled", 0, attempts)
+}
+
+func TestOperationsMatchTemplate(t *testing.T) {
+
+	generated, err := swagger.GenerateClient("client", "github.com/merlincox/aws-api-gateway-deploy/pkg/models", readDefinition(t))
+
+	utils.AssertNoError(t, "Generate client", err)
+
+	current, err := ioutil.ReadFile("operations.go")
+
+	utils.AssertNoError(t, "Read operations", err)
+	utils.AssertTrue(t, "operations.go is up to date with api.yaml: run go generate ./pkg/client", string(generated) == string(current))
+}
@@ -0,0 +1,73 @@
+// Code generated from the Swagger definition of the API. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// GetCalcOpParams are the parameters of GET /calc/{op}
+type GetCalcOpParams struct {
+	// op in the path
+	Op string
+	// val1 in the query
+	Val1 float64
+	// val2 in the query
+	Val2 float64
+	// Accept-Language in the header, or nil if it is not given
+	AcceptLanguage *string
+}
+
+// GetCalcOp calls GET /calc/{op}
+func (client *Client) GetCalcOp(ctx context.Context, params GetCalcOpParams) (result models.CalculationResult, err error) {
+
+	query := url.Values{}
+
+	query.Set("val1", strconv.FormatFloat(params.Val1, 'f', -1, 64))
+	query.Set("val2", strconv.FormatFloat(params.Val2, 'f', -1, 64))
+
+	header := http.Header{}
+
+	if params.AcceptLanguage != nil {
+		header.Set("Accept-Language", *params.AcceptLanguage)
+	}
+
+	err = client.do(ctx, "GET", "/calc/"+url.PathEscape(params.Op), query, header, &result)
+
+	return result, err
+}
+
+// GetDocs calls GET /docs
+func (client *Client) GetDocs(ctx context.Context) error {
+
+	return client.do(ctx, "GET", "/docs", nil, nil, nil)
+}
+
+// GetHealth calls GET /health
+func (client *Client) GetHealth(ctx context.Context) (result models.Health, err error) {
+
+	err = client.do(ctx, "GET", "/health", nil, nil, &result)
+
+	return result, err
+}
+
+// GetOpenAPI calls GET /openapi.json
+func (client *Client) GetOpenAPI(ctx context.Context) (result map[string]interface{}, err error) {
+
+	err = client.do(ctx, "GET", "/openapi.json", nil, nil, &result)
+
+	return result, err
+}
+
+// GetStatus calls GET /status
+func (client *Client) GetStatus(ctx context.Context) (result models.Status, err error) {
+
+	err = client.do(ctx, "GET", "/status", nil, nil, &result)
+
+	return result, err
+}
@@ -0,0 +1,54 @@
+// The command package runs external commands such as git, go and aws. The packages which run them take a Runner, so
+// that tests can substitute a fake for ExecRunner.
+package command
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// A Runner runs an external command such as git, go or aws and returns its standard output
+type Runner interface {
+	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+}
+
+// ExecRunner runs commands as local processes
+type ExecRunner struct {
+	// The working directory for commands. Defaults to the current directory
+	Dir string
+	// Extra environment variables for commands, in the form KEY=value
+	Env []string
+	// If set, receives a copy of the standard output and standard error of commands as they run
+	Log io.Writer
+}
+
+func (runner ExecRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = runner.Dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if len(runner.Env) > 0 {
+		cmd.Env = append(os.Environ(), runner.Env...)
+	}
+
+	if runner.Log != nil {
+		cmd.Stdout = io.MultiWriter(stdout, runner.Log)
+		cmd.Stderr = io.MultiWriter(stderr, runner.Log)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return stdout.Bytes(), fmt.Errorf("%v %v failed: %v: %v", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,213 @@
+// The config package loads typed configuration structs from environment variables.
+//
+// Each exported field with an env tag is set from the named variable, converted to the field's type: strings, bools,
+// ints, floats, time.Durations and slices of these, which are comma-separated. A default tag gives the value used
+// when the variable is unset or empty, and a required:"true" tag makes an empty value an error. A value of the form
+// scheme:name, such as ssm:/sample-api/live/api-key, is resolved by the Resolver registered for the scheme, so
+// secrets can be kept in SSM Parameter Store or Secrets Manager. Loading reports every problem at once, including
+// those returned by a Validate method on the struct.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Resolver resolves a named value, such as a parameter or secret
+type Resolver interface {
+	Resolve(ctx context.Context, name string) (string, error)
+}
+
+// A Validator validates a loaded configuration
+type Validator interface {
+	Validate() error
+}
+
+// Loader loads configuration structs
+type Loader struct {
+	// Looks up an environment variable. Defaults to os.LookupEnv
+	Lookup func(key string) (string, bool)
+	// Resolvers for values of the form scheme:name, by scheme
+	Resolvers map[string]Resolver
+}
+
+// Load loads dst, which must be a pointer to a struct, from the environment with no resolvers
+func Load(ctx context.Context, dst interface{}) error {
+	return Loader{}.Load(ctx, dst)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load loads dst, which must be a pointer to a struct, returning an error listing every problem
+func (loader Loader) Load(ctx context.Context, dst interface{}) error {
+
+	v := reflect.ValueOf(dst)
+
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Cannot load configuration into %T: not a pointer to a struct", dst)
+	}
+
+	lookup := loader.Lookup
+
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+
+	var problems []string
+
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+
+		field := v.Type().Field(i)
+		key, ok := field.Tag.Lookup("env")
+
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		raw, _ := lookup(key)
+
+		if raw == "" {
+			raw = field.Tag.Get("default")
+		}
+
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				problems = append(problems, key+" is required")
+			}
+			continue
+		}
+
+		raw, err := loader.resolve(ctx, raw)
+
+		if err == nil {
+			err = set(v.Field(i), raw)
+		}
+
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", key, err))
+		}
+	}
+
+	if validator, ok := dst.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Invalid configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// Resolves a value of the form scheme:name with the resolver for the scheme. Other values are returned unchanged
+func (loader Loader) resolve(ctx context.Context, raw string) (string, error) {
+
+	i := strings.Index(raw, ":")
+
+	if i < 0 {
+		return raw, nil
+	}
+
+	resolver, ok := loader.Resolvers[raw[:i]]
+
+	if !ok {
+		return raw, nil
+	}
+
+	value, err := resolver.Resolve(ctx, raw[i+1:])
+
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %v: %v", raw, err)
+	}
+
+	return value, nil
+}
+
+func set(field reflect.Value, raw string) error {
+
+	if field.Kind() != reflect.Slice {
+		return setScalar(field, raw)
+	}
+
+	parts := strings.Split(raw, ",")
+	slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+
+	for i, part := range parts {
+		if err := setScalar(slice.Index(i), strings.TrimSpace(part)); err != nil {
+			return err
+		}
+	}
+
+	field.Set(slice)
+
+	return nil
+}
+
+func setScalar(field reflect.Value, raw string) error {
+
+	if field.Type() == durationType {
+
+		d, err := time.ParseDuration(raw)
+
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+
+		field.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch field.Kind() {
+
+	case reflect.String:
+
+		field.SetString(raw)
+
+	case reflect.Bool:
+
+		b, err := strconv.ParseBool(raw)
+
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+
+		n, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+
+		field.SetInt(n)
+
+	case reflect.Float32, reflect.Float64:
+
+		f, err := strconv.ParseFloat(raw, field.Type().Bits())
+
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+
+		field.SetFloat(f)
+
+	default:
+
+		return fmt.Errorf("unsupported type %v", field.Type())
+	}
+
+	return nil
+}
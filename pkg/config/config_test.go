@@ -0,0 +1,134 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+type testConfig struct {
+	Platform string        `env:"PLATFORM" required:"true"`
+	CacheTTL time.Duration `env:"CACHE_TTL" default:"60s"`
+	Debug    bool          `env:"DEBUG"`
+	Batch    int           `env:"BATCH" default:"25"`
+	Rate     float64       `env:"RATE" default:"0.5"`
+	Headers  []string      `env:"HEADERS" default:"Authorization,X-Api-Key"`
+	Ports    []int         `env:"PORTS"`
+	APIKey   string        `env:"API_KEY"`
+	Ignored  string
+}
+
+func (config testConfig) Validate() error {
+
+	if config.Rate > 1 {
+		return errors.New("RATE must be at most 1")
+	}
+
+	return nil
+}
+
+func testLoader(env map[string]string) Loader {
+
+	stub := Stub{
+		"/sample-api/test/api-key": "parameter-key",
+		"sample-api/test":          `{"apiKey":"secret-key","port":8080}`,
+	}
+
+	return Loader{
+		Lookup: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+		Resolvers: map[string]Resolver{
+			SchemeSSM:     ParameterStore{Client: stub},
+			SchemeSecrets: SecretsManager{Client: stub},
+		},
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+
+	var config testConfig
+
+	err := testLoader(map[string]string{"PLATFORM": "test"}).Load(context.Background(), &config)
+
+	utils.AssertNoError(t, "Load defaults", err)
+	utils.AssertEquals(t, "Platform", "test", config.Platform)
+	utils.AssertEquals(t, "Default duration", time.Minute, config.CacheTTL)
+	utils.AssertEquals(t, "Default int", 25, config.Batch)
+	utils.AssertEquals(t, "Default float", 0.5, config.Rate)
+	utils.AssertEquals(t, "Default list", "[Authorization X-Api-Key]", fmt.Sprint(config.Headers))
+	utils.AssertFalse(t, "Default bool", config.Debug)
+	utils.AssertEquals(t, "Unset list", 0, len(config.Ports))
+}
+
+func TestLoadValues(t *testing.T) {
+
+	var config testConfig
+
+	err := testLoader(map[string]string{
+		"PLATFORM":  "live",
+		"CACHE_TTL": "1m30s",
+		"DEBUG":     "true",
+		"BATCH":     "10",
+		"HEADERS":   "Cookie",
+		"PORTS":     "80, 443",
+		"API_KEY":   "ssm:/sample-api/test/api-key",
+	}).Load(context.Background(), &config)
+
+	utils.AssertNoError(t, "Load values", err)
+	utils.AssertEquals(t, "Duration", 90*time.Second, config.CacheTTL)
+	utils.AssertTrue(t, "Bool", config.Debug)
+	utils.AssertEquals(t, "Int", 10, config.Batch)
+	utils.AssertEquals(t, "List", "[Cookie]", fmt.Sprint(config.Headers))
+	utils.AssertEquals(t, "Int list", "[80 443]", fmt.Sprint(config.Ports))
+	utils.AssertEquals(t, "SSM parameter", "parameter-key", config.APIKey)
+
+	err = testLoader(map[string]string{
+		"PLATFORM": "live",
+		"API_KEY":  "secretsmanager:sample-api/test#apiKey",
+		"PORTS":    "secretsmanager:sample-api/test#port",
+	}).Load(context.Background(), &config)
+
+	utils.AssertNoError(t, "Load secrets", err)
+	utils.AssertEquals(t, "Secret key", "secret-key", config.APIKey)
+	utils.AssertEquals(t, "Secret number", "[8080]", fmt.Sprint(config.Ports))
+}
+
+func TestLoadProblems(t *testing.T) {
+
+	var config testConfig
+
+	err := testLoader(map[string]string{
+		"CACHE_TTL": "60",
+		"DEBUG":     "maybe",
+		"PORTS":     "80,http",
+		"API_KEY":   "ssm:/sample-api/live/api-key",
+	}).Load(context.Background(), &config)
+
+	utils.AssertErrorEquals(t, "Every problem", "Invalid configuration: PLATFORM is required; "+
+		`CACHE_TTL: invalid duration "60"; DEBUG: invalid boolean "maybe"; PORTS: invalid integer "http"; `+
+		"API_KEY: cannot resolve ssm:/sample-api/live/api-key: parameter /sample-api/live/api-key not found", err)
+
+	err = testLoader(map[string]string{"PLATFORM": "test", "RATE": "2"}).Load(context.Background(), &config)
+
+	utils.AssertErrorEquals(t, "Validation", "Invalid configuration: RATE must be at most 1", err)
+
+	err = testLoader(nil).Load(context.Background(), config)
+
+	utils.AssertErrorEquals(t, "Not a pointer", "Cannot load configuration into config.testConfig: not a pointer to a struct", err)
+}
+
+func TestLoadUnresolvedScheme(t *testing.T) {
+
+	var config testConfig
+
+	err := testLoader(map[string]string{"PLATFORM": "https://example.com"}).Load(context.Background(), &config)
+
+	utils.AssertNoError(t, "Unregistered scheme", err)
+	utils.AssertEquals(t, "Unregistered scheme value", "https://example.com", config.Platform)
+}
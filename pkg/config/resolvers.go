@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The schemes of values resolved from SSM Parameter Store and Secrets Manager
+const (
+	SchemeSSM     = "ssm"
+	SchemeSecrets = "secretsmanager"
+)
+
+// A ParameterGetter gets a parameter from SSM Parameter Store, such as a wrapper of the AWS SDK SSM client
+type ParameterGetter interface {
+	GetParameter(ctx context.Context, name string, decrypt bool) (string, error)
+}
+
+// ParameterStore resolves SSM Parameter Store parameter names, decrypting SecureString parameters
+type ParameterStore struct {
+	Client ParameterGetter
+}
+
+func (store ParameterStore) Resolve(ctx context.Context, name string) (string, error) {
+	return store.Client.GetParameter(ctx, name, true)
+}
+
+// A SecretGetter gets the string value of a secret from Secrets Manager, such as a wrapper of the AWS SDK Secrets
+// Manager client
+type SecretGetter interface {
+	GetSecretValue(ctx context.Context, id string) (string, error)
+}
+
+// SecretsManager resolves Secrets Manager secret IDs. An ID of the form id#key resolves the key of a secret whose
+// value is a JSON object
+type SecretsManager struct {
+	Client SecretGetter
+}
+
+func (manager SecretsManager) Resolve(ctx context.Context, name string) (string, error) {
+
+	id, key := name, ""
+
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		id, key = name[:i], name[i+1:]
+	}
+
+	value, err := manager.Client.GetSecretValue(ctx, id)
+
+	if err != nil || key == "" {
+		return value, err
+	}
+
+	var fields map[string]interface{}
+
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %v is not a JSON object", id)
+	}
+
+	field, ok := fields[key]
+
+	if !ok {
+		return "", fmt.Errorf("secret %v has no key %v", id, key)
+	}
+
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+
+	return fmt.Sprint(field), nil
+}
+
+// Stub is an in-memory ParameterGetter and SecretGetter for running and testing locally without AWS
+type Stub map[string]string
+
+func (stub Stub) GetParameter(ctx context.Context, name string, decrypt bool) (string, error) {
+	return stub.get("parameter", name)
+}
+
+func (stub Stub) GetSecretValue(ctx context.Context, id string) (string, error) {
+	return stub.get("secret", id)
+}
+
+func (stub Stub) get(kind, name string) (string, error) {
+
+	value, ok := stub[name]
+
+	if !ok {
+		return "", fmt.Errorf("%v %v not found", kind, name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,244 @@
+// The conformance package checks that a handler of API Gateway proxy requests implements the Swagger definition of
+// the API. It drives valid and invalid requests generated from the definition of every operation through the handler,
+// validates the responses against their schemas and checks that errors are ApiErrorBody, reporting undocumented
+// routes and status codes
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+// The methods requested of every path to check the handler serves only those the definition documents
+var probeMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH"}
+
+// Handler handles API Gateway proxy requests, such as Front.Handler
+type Handler func(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error)
+
+// Finding is a way the handler does not conform to the definition
+type Finding struct {
+	// The request, such as GET/calc/{op} without val1
+	Request string
+	Message string
+}
+
+func (finding Finding) String() string {
+	return finding.Request + ": " + finding.Message
+}
+
+// Harness checks a handler against the definition of an API
+type Harness struct {
+	Definition swagger.Document
+	Handler    Handler
+	// Routes of the form METHOD/path which are also requested, and must not be served unless they are documented
+	Probes []string
+	// The stage of the requests
+	Stage string
+}
+
+// Run drives the samples of every operation of the definition which the lambda handles through the handler, and
+// requests the undocumented methods of every path and the probes, returning the findings in request order
+func (harness Harness) Run(ctx context.Context) []Finding {
+
+	var findings []Finding
+
+	for _, route := range harness.Definition.Routes() {
+
+		if route.Mock() {
+			continue
+		}
+
+		for _, sample := range Samples(route) {
+			findings = append(findings, harness.check(ctx, sample)...)
+		}
+	}
+
+	for _, probe := range harness.probes() {
+		findings = append(findings, harness.checkUndocumented(ctx, probe)...)
+	}
+
+	return findings
+}
+
+// Returns the routes which the definition does not document, of the probe methods of its paths and the probes
+func (harness Harness) probes() []string {
+
+	documented := map[string]bool{}
+
+	for _, route := range harness.Definition.Routes() {
+		documented[route.Key()] = true
+	}
+
+	var probes []string
+
+	for path := range harness.Definition.Paths {
+		for _, method := range probeMethods {
+			probes = append(probes, method+path)
+		}
+	}
+
+	probes = append(probes, harness.Probes...)
+	sort.Strings(probes)
+
+	var undocumented []string
+
+	for i, probe := range probes {
+		if !documented[probe] && (i == 0 || probes[i-1] != probe) {
+			undocumented = append(undocumented, probe)
+		}
+	}
+
+	return undocumented
+}
+
+// Checks the response to a sample request
+func (harness Harness) check(ctx context.Context, sample Sample) []Finding {
+
+	sample.Request.RequestContext.Stage = harness.Stage
+
+	response, err := harness.Handler(ctx, sample.Request)
+
+	if err != nil {
+		return []Finding{{sample.Name, "The handler failed: " + err.Error()}}
+	}
+
+	var findings []Finding
+
+	finding := func(format string, a ...interface{}) {
+		findings = append(findings, Finding{sample.Name, fmt.Sprintf(format, a...)})
+	}
+
+	status := response.StatusCode
+	documented, ok := sample.Route.Responses[strconv.Itoa(status)]
+
+	if !ok {
+		documented, ok = sample.Route.Responses["default"]
+	}
+
+	switch {
+	case !ok:
+		finding("Status %v is not documented", status)
+	case sample.Valid && status >= 400 && status < 500:
+		finding("The valid request was rejected with %v", status)
+	}
+
+	switch {
+	case !sample.Valid && status < 400:
+		finding("The invalid request was accepted with %v", status)
+	case !sample.Valid && status >= 500:
+		finding("The invalid request failed with %v instead of being rejected", status)
+	}
+
+	if documented.Schema != nil {
+
+		var body interface{}
+
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+			finding("The %v response is not JSON: %v", status, err)
+			return findings
+		}
+
+		for _, problem := range harness.Definition.Validate(body, documented.Schema) {
+			finding("The %v response does not match its schema: %v", status, problem)
+		}
+
+		return findings
+	}
+
+	if status >= 400 {
+		for _, problem := range errorProblems(status, response.Body) {
+			finding("The %v response is not an ApiErrorBody: %v", status, problem)
+		}
+	}
+
+	return findings
+}
+
+// Checks that the handler does not serve an undocumented route
+func (harness Harness) checkUndocumented(ctx context.Context, route string) []Finding {
+
+	method, path := splitRoute(route)
+
+	response, err := harness.Handler(ctx, events.APIGatewayProxyRequest{
+		HTTPMethod: method,
+		Resource:   path,
+		Path:       path,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod:   method,
+			ResourcePath: path,
+			Stage:        harness.Stage,
+		},
+	})
+
+	if err != nil {
+		return []Finding{{route, "The handler failed: " + err.Error()}}
+	}
+
+	if response.StatusCode != http.StatusNotFound {
+		return []Finding{{route, fmt.Sprintf("The route is not documented but is served with %v", response.StatusCode)}}
+	}
+
+	var findings []Finding
+
+	for _, problem := range errorProblems(response.StatusCode, response.Body) {
+		findings = append(findings, Finding{route, "The 404 response is not an ApiErrorBody: " + problem})
+	}
+
+	return findings
+}
+
+// Returns the ways the body of an error response is not an ApiErrorBody with the code of its status
+func errorProblems(status int, body string) []string {
+
+	var fields map[string]interface{}
+
+	if err := json.Unmarshal([]byte(body), &fields); err != nil {
+		return []string{fmt.Sprintf("%q is not a JSON object", body)}
+	}
+
+	var problems []string
+
+	for _, name := range []string{"message", "code"} {
+		if _, ok := fields[name]; !ok {
+			problems = append(problems, name+" is missing")
+		}
+	}
+
+	var errorBody models.ApiErrorBody
+
+	decoder := json.NewDecoder(bytes.NewBufferString(body))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&errorBody); err != nil {
+		return append(problems, err.Error())
+	}
+
+	if _, ok := fields["code"]; ok && errorBody.Code != status {
+		problems = append(problems, fmt.Sprintf("its code is %v", errorBody.Code))
+	}
+
+	return problems
+}
+
+// Splits a route of the form METHOD/path
+func splitRoute(route string) (string, string) {
+
+	i := strings.Index(route, "/")
+
+	if i < 0 {
+		return route, "/"
+	}
+
+	return route[:i], route[i:]
+}
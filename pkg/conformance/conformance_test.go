@@ -0,0 +1,131 @@
+package conformance
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const testDefinition = `{
+	"swagger": "2.0",
+	"info": {"title": "Test", "version": "1"},
+	"paths": {
+		"/items/{kind}": {
+			"get": {
+				"parameters": [
+					{"name": "kind", "in": "path", "required": true, "type": "string", "enum": ["small", "large"]},
+					{"name": "count", "in": "query", "required": true, "type": "integer"},
+					{"name": "X-Verbose", "in": "header", "type": "boolean"}
+				],
+				"responses": {
+					"200": {"description": "Items", "schema": {"$ref": "#/definitions/Items"}},
+					"400": {"description": "Invalid parameters"}
+				},
+				"x-amazon-apigateway-integration": {"type": "aws_proxy"}
+			},
+			"options": {
+				"responses": {"200": {"description": "CORS"}},
+				"x-amazon-apigateway-integration": {"type": "mock"}
+			}
+		}
+	},
+	"definitions": {
+		"Items": {"type": "object", "required": ["kind"], "properties": {"kind": {"type": "string"}, "count": {"type": "integer"}}}
+	}
+}`
+
+func TestSamples(t *testing.T) {
+
+	doc, err := swagger.ParseJSON([]byte(testDefinition))
+
+	utils.AssertNoError(t, "Parse", err)
+
+	var names []string
+
+	for _, sample := range Samples(doc.Routes()[0]) {
+		names = append(names, sample.Name)
+	}
+
+	utils.AssertEquals(t, "Names", strings.Join([]string{
+		"GET/items/{kind} with all parameters",
+		"GET/items/{kind} with required parameters only",
+		"GET/items/{kind} with kind large",
+		"GET/items/{kind} without kind",
+		"GET/items/{kind} without count",
+		"GET/items/{kind} with kind not-small",
+		"GET/items/{kind} with count 2.5",
+		"GET/items/{kind} with X-Verbose yes",
+	}, "\n"), strings.Join(names, "\n"))
+
+	sample := Samples(doc.Routes()[0])[0]
+
+	utils.AssertTrue(t, "Valid", sample.Valid)
+	utils.AssertEquals(t, "Path", "/items/small", sample.Request.Path)
+	utils.AssertEquals(t, "Path parameter", "small", sample.Request.PathParameters["kind"])
+	utils.AssertEquals(t, "Query", "2", sample.Request.QueryStringParameters["count"])
+	utils.AssertEquals(t, "Header", "true", sample.Request.Headers["X-Verbose"])
+	utils.AssertEquals(t, "Route", "GET/items/{kind}", sample.Request.RequestContext.HTTPMethod+sample.Request.RequestContext.ResourcePath)
+}
+
+// Handles the test definition with a bug for each check of the harness
+func nonConformingHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+
+	route := request.RequestContext.HTTPMethod + request.RequestContext.ResourcePath
+
+	switch {
+	case route == "POST/items/{kind}":
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: `{}`}, nil
+	case route != "GET/items/{kind}":
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: `{"message":"Not found","code":404}`}, nil
+	case request.PathParameters["kind"] == "large":
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: `{"kind":"large","count":"2","extra":true}`}, nil
+	case request.PathParameters["kind"] != "small":
+		return events.APIGatewayProxyResponse{StatusCode: 200, Body: `{"kind":"other"}`}, nil
+	case request.QueryStringParameters["count"] == "":
+		return events.APIGatewayProxyResponse{StatusCode: 500, Body: `{"message":"Panic","code":500}`}, nil
+	case request.QueryStringParameters["count"] != "2":
+		return events.APIGatewayProxyResponse{StatusCode: 400, Body: `{"error":"Bad count","code":422}`}, nil
+	case request.Headers["X-Verbose"] == "":
+		return events.APIGatewayProxyResponse{StatusCode: 404, Body: `{"message":"Missing header","code":404}`}, nil
+	}
+
+	return events.APIGatewayProxyResponse{StatusCode: 200, Body: `{"kind":"small"}`}, nil
+}
+
+func TestRun(t *testing.T) {
+
+	doc, err := swagger.ParseJSON([]byte(testDefinition))
+
+	utils.AssertNoError(t, "Parse", err)
+
+	harness := Harness{
+		Definition: doc,
+		Handler:    nonConformingHandler,
+		Probes:     []string{"GET/items", "GET/items/{kind}"},
+	}
+
+	var findings []string
+
+	for _, finding := range harness.Run(context.Background()) {
+		findings = append(findings, finding.String())
+	}
+
+	utils.AssertEquals(t, "Findings", strings.Join([]string{
+		"GET/items/{kind} with required parameters only: Status 404 is not documented",
+		"GET/items/{kind} with kind large: The 200 response does not match its schema: $.count is \"2\", not an integer",
+		"GET/items/{kind} with kind large: The 200 response does not match its schema: $.extra is not documented",
+		"GET/items/{kind} without kind: The invalid request was accepted with 200",
+		"GET/items/{kind} without count: Status 500 is not documented",
+		"GET/items/{kind} without count: The invalid request failed with 500 instead of being rejected",
+		"GET/items/{kind} with kind not-small: The invalid request was accepted with 200",
+		"GET/items/{kind} with count 2.5: The 400 response is not an ApiErrorBody: message is missing",
+		"GET/items/{kind} with count 2.5: The 400 response is not an ApiErrorBody: json: unknown field \"error\"",
+		"GET/items/{kind} with X-Verbose yes: The invalid request was accepted with 200",
+		"POST/items/{kind}: The route is not documented but is served with 200",
+	}, "\n"), strings.Join(findings, "\n"))
+}
@@ -0,0 +1,190 @@
+package conformance
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+// Values of valid parameters of each type, which are used when a parameter has no enum
+var validValues = map[string]string{
+	"string":  "sample",
+	"number":  "2",
+	"integer": "2",
+	"boolean": "true",
+}
+
+// Values of invalid parameters of each type. Any string is valid unless the parameter has an enum
+var invalidValues = map[string]string{
+	"number":  "two",
+	"integer": "2.5",
+	"boolean": "yes",
+}
+
+// Sample is a request of an operation generated from the definition of its parameters
+type Sample struct {
+	// Describes the request, such as GET/calc/{op} without val1
+	Name  string
+	Route swagger.Route
+	// The request, without a stage
+	Request events.APIGatewayProxyRequest
+	// Whether the parameters are all valid and the required parameters all given
+	Valid bool
+}
+
+// Samples returns valid requests of an operation with all its parameters, with only the required parameters and with
+// each value of each enum, and invalid requests without each required parameter and with each parameter which has an
+// enum or is not a string set to an invalid value
+func Samples(route swagger.Route) []Sample {
+
+	values := map[string]string{}
+	optional := false
+
+	for _, parameter := range route.Parameters {
+
+		values[parameter.Name] = validValue(parameter)
+
+		if !parameter.Required {
+			optional = true
+		}
+	}
+
+	samples := []Sample{newSample(route, "with all parameters", values, true)}
+
+	if optional {
+
+		required := map[string]string{}
+
+		for _, parameter := range route.Parameters {
+			if parameter.Required {
+				required[parameter.Name] = values[parameter.Name]
+			}
+		}
+
+		samples = append(samples, newSample(route, "with required parameters only", required, true))
+	}
+
+	// The first value of each enum is in the sample with all parameters
+	for _, parameter := range route.Parameters {
+		for i := 1; i < len(parameter.Enum); i++ {
+			value := parameter.Enum[i]
+			samples = append(samples, newSample(route, fmt.Sprintf("with %v %v", parameter.Name, value), with(values, parameter.Name, fmt.Sprint(value)), true))
+		}
+	}
+
+	for _, parameter := range route.Parameters {
+		if parameter.Required {
+			samples = append(samples, newSample(route, "without "+parameter.Name, without(values, parameter.Name), false))
+		}
+	}
+
+	for _, parameter := range route.Parameters {
+		if value, ok := invalidValue(parameter); ok {
+			samples = append(samples, newSample(route, fmt.Sprintf("with %v %v", parameter.Name, value), with(values, parameter.Name, value), false))
+		}
+	}
+
+	return samples
+}
+
+// Returns a valid value of a parameter, which is the first value of its enum if it has one
+func validValue(parameter swagger.Parameter) string {
+
+	if len(parameter.Enum) > 0 {
+		return fmt.Sprint(parameter.Enum[0])
+	}
+
+	return validValues[parameter.Type]
+}
+
+// Returns an invalid value of a parameter, if it can have one
+func invalidValue(parameter swagger.Parameter) (string, bool) {
+
+	if len(parameter.Enum) > 0 {
+		return "not-" + fmt.Sprint(parameter.Enum[0]), true
+	}
+
+	value, ok := invalidValues[parameter.Type]
+
+	return value, ok
+}
+
+// Returns a request of a route with parameter values, putting each in the path, query string or headers
+func newSample(route swagger.Route, description string, values map[string]string, valid bool) Sample {
+
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: route.Method,
+		Resource:   route.Path,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			HTTPMethod:   route.Method,
+			ResourcePath: route.Path,
+		},
+	}
+
+	path := route.Path
+
+	for _, parameter := range route.Parameters {
+
+		value, ok := values[parameter.Name]
+
+		if !ok {
+			continue
+		}
+
+		switch parameter.In {
+		case "path":
+			request.PathParameters = put(request.PathParameters, parameter.Name, value)
+			path = strings.Replace(path, "{"+parameter.Name+"}", value, 1)
+		case "query":
+			request.QueryStringParameters = put(request.QueryStringParameters, parameter.Name, value)
+		case "header":
+			request.Headers = put(request.Headers, parameter.Name, value)
+		}
+	}
+
+	request.Path = path
+
+	return Sample{
+		Name:    route.Key() + " " + description,
+		Route:   route,
+		Request: request,
+		Valid:   valid,
+	}
+}
+
+func put(values map[string]string, name, value string) map[string]string {
+
+	if values == nil {
+		values = map[string]string{}
+	}
+
+	values[name] = value
+
+	return values
+}
+
+// Returns a copy of parameter values with one replaced
+func with(values map[string]string, name, value string) map[string]string {
+
+	copied := without(values, name)
+	copied[name] = value
+
+	return copied
+}
+
+// Returns a copy of parameter values without one
+func without(values map[string]string, name string) map[string]string {
+
+	copied := make(map[string]string, len(values))
+
+	for k, v := range values {
+		if k != name {
+			copied[k] = v
+		}
+	}
+
+	return copied
+}
@@ -0,0 +1,262 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+// ArtifactObject is an object in an artifact bucket
+type ArtifactObject struct {
+	Key          string
+	LastModified time.Time
+}
+
+// An ArtifactStore keeps lambda artifacts in a long-lived bucket in each region. An empty region means the AWS CLI
+// default region
+type ArtifactStore interface {
+	// BucketName returns the name of the artifact bucket in a region
+	BucketName(ctx context.Context, region string) (string, error)
+	// EnsureBucket creates and configures the artifact bucket in a region if need be, and returns its name. It is safe
+	// to call repeatedly, and concurrently with other deployments
+	EnsureBucket(ctx context.Context, region string) (string, error)
+	Exists(ctx context.Context, bucket, key, region string) (bool, error)
+	Upload(ctx context.Context, path, bucket, key, region string) error
+	// List returns the objects with a key prefix
+	List(ctx context.Context, bucket, prefix, region string) ([]ArtifactObject, error)
+	Delete(ctx context.Context, bucket, region string, keys []string) error
+}
+
+// The most keys S3 deletes in one request
+const deleteBatchSize = 1000
+
+// CLIArtifactStore is an ArtifactStore using the aws command line. Buckets are named by the project configuration,
+// block public access and have lifecycle rules aborting incomplete uploads and, if configured, expiring artifacts
+type CLIArtifactStore struct {
+	Runner  command.Runner
+	Project project.Config
+}
+
+func (store CLIArtifactStore) BucketName(ctx context.Context, region string) (string, error) {
+
+	region, err := store.region(ctx, region)
+
+	if err != nil {
+		return "", err
+	}
+
+	account, err := store.account(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	return store.Project.ArtifactBucket(account, region), nil
+}
+
+func (store CLIArtifactStore) EnsureBucket(ctx context.Context, region string) (string, error) {
+
+	region, err := store.region(ctx, region)
+
+	if err != nil {
+		return "", err
+	}
+
+	bucket, err := store.BucketName(ctx, region)
+
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := store.Runner.Run(ctx, "aws", "s3api", "head-bucket", "--region", region, "--bucket", bucket); err != nil {
+
+		args := []string{"s3api", "create-bucket", "--region", region, "--bucket", bucket}
+
+		// us-east-1 is the default location and may not be given as a constraint
+		if region != "us-east-1" {
+			args = append(args, "--create-bucket-configuration", "LocationConstraint="+region)
+		}
+
+		// Another deployment may have created it since
+		if _, err := store.Runner.Run(ctx, "aws", args...); err != nil && !strings.Contains(err.Error(), "BucketAlreadyOwnedByYou") {
+			return "", err
+		}
+	}
+
+	if _, err := store.Runner.Run(ctx, "aws", "s3api", "put-public-access-block", "--region", region, "--bucket", bucket,
+		"--public-access-block-configuration", "BlockPublicAcls=true,IgnorePublicAcls=true,BlockPublicPolicy=true,RestrictPublicBuckets=true"); err != nil {
+		return "", err
+	}
+
+	lifecycle, err := store.lifecycle()
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = store.Runner.Run(ctx, "aws", "s3api", "put-bucket-lifecycle-configuration", "--region", region, "--bucket", bucket,
+		"--lifecycle-configuration", lifecycle)
+
+	return bucket, err
+}
+
+// Returns the lifecycle configuration of artifact buckets as JSON
+func (store CLIArtifactStore) lifecycle() (string, error) {
+
+	type rule map[string]interface{}
+
+	rules := []rule{{
+		"ID":                             "abort-incomplete-uploads",
+		"Status":                         "Enabled",
+		"Filter":                         rule{"Prefix": ""},
+		"AbortIncompleteMultipartUpload": rule{"DaysAfterInitiation": 1},
+	}}
+
+	if store.Project.Artifacts.ExpireDays > 0 {
+		rules = append(rules, rule{
+			"ID":         "expire-" + store.Project.Project + "-artifacts",
+			"Status":     "Enabled",
+			"Filter":     rule{"Prefix": store.Project.Project + "/"},
+			"Expiration": rule{"Days": store.Project.Artifacts.ExpireDays},
+		})
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{"Rules": rules})
+
+	return string(raw), err
+}
+
+func (store CLIArtifactStore) Exists(ctx context.Context, bucket, key, region string) (bool, error) {
+
+	args := append([]string{"s3api", "head-object"}, regionArgs(region)...)
+
+	_, err := store.Runner.Run(ctx, "aws", append(args, "--bucket", bucket, "--key", key)...)
+
+	if err == nil {
+		return true, nil
+	}
+
+	if strings.Contains(err.Error(), "Not Found") || strings.Contains(err.Error(), "404") {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func (store CLIArtifactStore) Upload(ctx context.Context, path, bucket, key, region string) error {
+
+	_, err := store.Runner.Run(ctx, "aws", append([]string{"s3", "cp", path, "s3://" + bucket + "/" + key}, regionArgs(region)...)...)
+
+	return err
+}
+
+func (store CLIArtifactStore) List(ctx context.Context, bucket, prefix, region string) ([]ArtifactObject, error) {
+
+	args := append([]string{"s3api", "list-objects-v2"}, regionArgs(region)...)
+
+	out, err := store.Runner.Run(ctx, "aws", append(args, "--bucket", bucket, "--prefix", prefix, "--output", "json")...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// There is no output when no objects match
+	if strings.TrimSpace(string(out)) == "" {
+		return nil, nil
+	}
+
+	var objects struct {
+		Contents []struct {
+			Key          string
+			LastModified time.Time
+		}
+	}
+
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, fmt.Errorf("Cannot parse objects: %v", err)
+	}
+
+	list := make([]ArtifactObject, len(objects.Contents))
+
+	for i, object := range objects.Contents {
+		list[i] = ArtifactObject{Key: object.Key, LastModified: object.LastModified}
+	}
+
+	return list, nil
+}
+
+func (store CLIArtifactStore) Delete(ctx context.Context, bucket, region string, keys []string) error {
+
+	for start := 0; start < len(keys); start += deleteBatchSize {
+
+		end := start + deleteBatchSize
+
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		type object struct {
+			Key string
+		}
+
+		request := struct {
+			Objects []object
+			Quiet   bool
+		}{Quiet: true}
+
+		for _, key := range keys[start:end] {
+			request.Objects = append(request.Objects, object{Key: key})
+		}
+
+		raw, err := json.Marshal(request)
+
+		if err != nil {
+			return err
+		}
+
+		args := append([]string{"s3api", "delete-objects"}, regionArgs(region)...)
+
+		if _, err := store.Runner.Run(ctx, "aws", append(args, "--bucket", bucket, "--delete", string(raw))...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the region, or the AWS CLI default region if it is empty
+func (store CLIArtifactStore) region(ctx context.Context, region string) (string, error) {
+
+	if region != "" {
+		return region, nil
+	}
+
+	out, err := store.Runner.Run(ctx, "aws", "configure", "get", "region")
+
+	if err != nil {
+		return "", err
+	}
+
+	if region = trimmed(out); region == "" {
+		return "", errors.New("No AWS region is configured")
+	}
+
+	return region, nil
+}
+
+func (store CLIArtifactStore) account(ctx context.Context) (string, error) {
+
+	out, err := store.Runner.Run(ctx, "aws", "sts", "get-caller-identity", "--query", "Account", "--output", "text")
+
+	if err != nil {
+		return "", err
+	}
+
+	return trimmed(out), nil
+}
@@ -0,0 +1,192 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// fakeArtifacts is an ArtifactStore keeping objects in memory, in a bucket per region named artifacts-<region>
+type fakeArtifacts struct {
+	mutex   sync.Mutex
+	objects map[string]map[string]time.Time
+	uploads []string
+	deleted []string
+}
+
+func newFakeArtifacts() *fakeArtifacts {
+	return &fakeArtifacts{objects: map[string]map[string]time.Time{}}
+}
+
+func (store *fakeArtifacts) put(bucket, key string, modified time.Time) {
+
+	if store.objects[bucket] == nil {
+		store.objects[bucket] = map[string]time.Time{}
+	}
+
+	store.objects[bucket][key] = modified
+}
+
+func (store *fakeArtifacts) BucketName(ctx context.Context, region string) (string, error) {
+	return "artifacts-" + region, nil
+}
+
+func (store *fakeArtifacts) EnsureBucket(ctx context.Context, region string) (string, error) {
+	return store.BucketName(ctx, region)
+}
+
+func (store *fakeArtifacts) Exists(ctx context.Context, bucket, key, region string) (bool, error) {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	_, ok := store.objects[bucket][key]
+
+	return ok, nil
+}
+
+func (store *fakeArtifacts) Upload(ctx context.Context, path, bucket, key, region string) error {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.put(bucket, key, time.Now())
+	store.uploads = append(store.uploads, path+" "+bucket+"/"+key)
+
+	return nil
+}
+
+func (store *fakeArtifacts) List(ctx context.Context, bucket, prefix, region string) ([]ArtifactObject, error) {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	var list []ArtifactObject
+
+	for key, modified := range store.objects[bucket] {
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, ArtifactObject{Key: key, LastModified: modified})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+
+	return list, nil
+}
+
+func (store *fakeArtifacts) Delete(ctx context.Context, bucket, region string, keys []string) error {
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	for _, key := range keys {
+		delete(store.objects[bucket], key)
+		store.deleted = append(store.deleted, bucket+"/"+key)
+	}
+
+	return nil
+}
+
+func TestEnsureArtifactBucket(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws sts get-caller-identity --query Account --output text"] = "123456789012\n"
+	runner.errors["aws s3api head-bucket --region eu-west-1 --bucket api-artifacts-123456789012-eu-west-1"] = errors.New("Not Found")
+
+	config := project.Default()
+	config.Artifacts.ExpireDays = 90
+
+	store := CLIArtifactStore{Runner: runner, Project: config}
+
+	bucket, err := store.EnsureBucket(context.Background(), "eu-west-1")
+
+	utils.AssertNoError(t, "Ensure bucket", err)
+	utils.AssertEquals(t, "Bucket", "api-artifacts-123456789012-eu-west-1", bucket)
+	utils.AssertEquals(t, "Created", "aws s3api create-bucket --region eu-west-1 --bucket api-artifacts-123456789012-eu-west-1 --create-bucket-configuration LocationConstraint=eu-west-1", runner.calls[2])
+	utils.AssertTrue(t, "Public access blocked", strings.HasPrefix(runner.calls[3], "aws s3api put-public-access-block --region eu-west-1 --bucket api-artifacts-123456789012-eu-west-1 "))
+	utils.AssertTrue(t, "Expiry rule", strings.Contains(runner.calls[4], `"Expiration":{"Days":90},"Filter":{"Prefix":"api/"}`))
+
+	runner = newFakeRunner()
+	runner.outputs["aws configure get region"] = "us-east-1\n"
+	runner.outputs["aws sts get-caller-identity --query Account --output text"] = "123456789012\n"
+	runner.errors["aws s3api head-bucket --region us-east-1 --bucket api-artifacts-123456789012-us-east-1"] = errors.New("Not Found")
+	runner.errors["aws s3api create-bucket --region us-east-1 --bucket api-artifacts-123456789012-us-east-1"] = errors.New("BucketAlreadyOwnedByYou")
+	store.Runner = runner
+	store.Project = project.Default()
+
+	bucket, err = store.EnsureBucket(context.Background(), "")
+
+	utils.AssertNoError(t, "Ensure bucket created concurrently", err)
+	utils.AssertEquals(t, "Default region bucket", "api-artifacts-123456789012-us-east-1", bucket)
+	utils.AssertFalse(t, "No expiry rule", strings.Contains(runner.calls[len(runner.calls)-1], "Expiration"))
+
+	runner = newFakeRunner()
+	store.Runner = runner
+
+	_, err = store.EnsureBucket(context.Background(), "")
+
+	utils.AssertErrorEquals(t, "No region", "No AWS region is configured", err)
+}
+
+func TestArtifactObjects(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws s3api list-objects-v2 --region eu-west-1 --bucket artifacts --prefix api/live/ --output json"] = `{"Contents":[
+		{"Key":"api/live/5e7d.json","LastModified":"2026-10-01T12:00:00+00:00"},
+		{"Key":"api/live/5e7d.zip","LastModified":"2026-10-01T12:00:01+00:00"}]}`
+	runner.errors["aws s3api head-object --region eu-west-1 --bucket artifacts --key api/live/6f8e.zip"] =
+		errors.New("An error occurred (404) when calling the HeadObject operation: Not Found")
+	runner.errors["aws s3api head-object --region eu-west-1 --bucket artifacts --key api/live/7a9f.zip"] =
+		errors.New("An error occurred (403) when calling the HeadObject operation: Forbidden")
+
+	store := CLIArtifactStore{Runner: runner}
+
+	objects, err := store.List(context.Background(), "artifacts", "api/live/", "eu-west-1")
+
+	utils.AssertNoError(t, "List", err)
+	utils.AssertEquals(t, "Object count", 2, len(objects))
+	utils.AssertEquals(t, "Object key", "api/live/5e7d.zip", objects[1].Key)
+	utils.AssertTrue(t, "Object time", objects[1].LastModified.Equal(time.Date(2026, 10, 1, 12, 0, 1, 0, time.UTC)))
+
+	objects, err = store.List(context.Background(), "artifacts", "api/test/", "eu-west-1")
+
+	utils.AssertNoError(t, "List none", err)
+	utils.AssertEquals(t, "No objects", 0, len(objects))
+
+	exists, err := store.Exists(context.Background(), "artifacts", "api/live/5e7d.zip", "eu-west-1")
+
+	utils.AssertNoError(t, "Exists", err)
+	utils.AssertTrue(t, "Existing", exists)
+
+	exists, err = store.Exists(context.Background(), "artifacts", "api/live/6f8e.zip", "eu-west-1")
+
+	utils.AssertNoError(t, "Not exists", err)
+	utils.AssertFalse(t, "Missing", exists)
+
+	_, err = store.Exists(context.Background(), "artifacts", "api/live/7a9f.zip", "eu-west-1")
+
+	utils.AssertErrorEquals(t, "Exists failure", "An error occurred (403) when calling the HeadObject operation: Forbidden", err)
+
+	keys := make([]string, deleteBatchSize+1)
+
+	for i := range keys {
+		keys[i] = "api/live/old.zip"
+	}
+
+	runner.calls = nil
+
+	err = store.Delete(context.Background(), "artifacts", "eu-west-1", keys)
+
+	utils.AssertNoError(t, "Delete", err)
+	utils.AssertEquals(t, "Delete batches", 2, len(runner.calls))
+	utils.AssertEquals(t, "Last batch", `aws s3api delete-objects --region eu-west-1 --bucket artifacts --delete {"Objects":[{"Key":"api/live/old.zip"}],"Quiet":true}`, runner.calls[1])
+}
@@ -0,0 +1,305 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+)
+
+// Certificates for edge-optimized endpoints, which are served by CloudFront, must be in this region
+const EdgeCertificateRegion = "us-east-1"
+
+// A DomainResolver finds the Route 53 hosted zone for a domain
+type DomainResolver interface {
+	HostedZoneID(ctx context.Context, domain string) (string, error)
+}
+
+// A CertificateResolver finds the ARN of an ACM certificate covering a custom domain in a region, which is empty for
+// the AWS CLI default region
+type CertificateResolver interface {
+	CertificateArn(ctx context.Context, customDomain, domain, region string) (string, error)
+}
+
+// CLIDomainResolver is a DomainResolver using the aws command line
+type CLIDomainResolver struct {
+	Runner command.Runner
+}
+
+func (resolver CLIDomainResolver) HostedZoneID(ctx context.Context, domain string) (string, error) {
+
+	out, err := resolver.Runner.Run(ctx, "aws", "route53", "list-hosted-zones", "--output", "json")
+
+	if err != nil {
+		return "", err
+	}
+
+	var zones struct {
+		HostedZones []struct {
+			Id   string
+			Name string
+		}
+	}
+
+	if err := json.Unmarshal(out, &zones); err != nil {
+		return "", fmt.Errorf("Cannot parse hosted zones: %v", err)
+	}
+
+	for _, zone := range zones.HostedZones {
+		if zone.Name == domain+"." {
+			// Ids are of the form /hostedzone/Z1234567890
+			parts := strings.Split(zone.Id, "/")
+			return parts[len(parts)-1], nil
+		}
+	}
+
+	return "", fmt.Errorf("No hosted-zone record was found for %v domain", domain)
+}
+
+// CLICertificateResolver is a CertificateResolver using the aws command line. It prefers a certificate for the exact
+// custom domain to a wildcard certificate for the domain
+type CLICertificateResolver struct {
+	Runner command.Runner
+}
+
+func (resolver CLICertificateResolver) CertificateArn(ctx context.Context, customDomain, domain, region string) (string, error) {
+
+	args := append(append([]string{"acm", "list-certificates"}, regionArgs(region)...), "--output", "json")
+
+	out, err := resolver.Runner.Run(ctx, "aws", args...)
+
+	if err != nil {
+		return "", err
+	}
+
+	var certificates struct {
+		CertificateSummaryList []struct {
+			CertificateArn string
+			DomainName     string
+		}
+	}
+
+	if err := json.Unmarshal(out, &certificates); err != nil {
+		return "", fmt.Errorf("Cannot parse certificates: %v", err)
+	}
+
+	for _, name := range []string{customDomain, "*." + domain} {
+		for _, certificate := range certificates.CertificateSummaryList {
+			if certificate.DomainName == name {
+				return certificate.CertificateArn, nil
+			}
+		}
+	}
+
+	if region == "" {
+		region = "the default region"
+	}
+
+	return "", fmt.Errorf("No SSL certificate was found for %v or *.%v patterns in %v", customDomain, domain, region)
+}
+
+// A StackDeployer creates or updates a CloudFormation stack from a packaged template in a region, which is empty for
+// the AWS CLI default region
+type StackDeployer interface {
+	Deploy(ctx context.Context, template, stackName, region string, parameters map[string]string) error
+	// Outputs returns the outputs of a stack by key
+	Outputs(ctx context.Context, stackName, region string) (map[string]string, error)
+}
+
+// CLIStackDeployer is a StackDeployer using the aws command line
+type CLIStackDeployer struct {
+	Runner command.Runner
+}
+
+func (deployer CLIStackDeployer) Deploy(ctx context.Context, template, stackName, region string, parameters map[string]string) error {
+
+	args := append([]string{"cloudformation", "deploy"}, regionArgs(region)...)
+
+	args = append(args,
+		"--template-file", template,
+		"--stack-name", stackName,
+		"--capabilities", "CAPABILITY_IAM",
+		"--parameter-overrides",
+	)
+
+	args = append(args, parameterOverrides(parameters)...)
+
+	_, err := deployer.Runner.Run(ctx, "aws", args...)
+
+	return err
+}
+
+func (deployer CLIStackDeployer) Outputs(ctx context.Context, stackName, region string) (map[string]string, error) {
+
+	args := append([]string{"cloudformation", "describe-stacks"}, regionArgs(region)...)
+
+	out, err := deployer.Runner.Run(ctx, "aws", append(args, "--stack-name", stackName, "--output", "json")...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stacks struct {
+		Stacks []struct {
+			Outputs []struct {
+				OutputKey   string
+				OutputValue string
+			}
+		}
+	}
+
+	if err := json.Unmarshal(out, &stacks); err != nil {
+		return nil, fmt.Errorf("Cannot parse stack description: %v", err)
+	}
+
+	if len(stacks.Stacks) == 0 {
+		return nil, fmt.Errorf("Stack %v was not found", stackName)
+	}
+
+	outputs := map[string]string{}
+
+	for _, output := range stacks.Stacks[0].Outputs {
+		outputs[output.OutputKey] = output.OutputValue
+	}
+
+	return outputs, nil
+}
+
+// A StackInspector reads what a deployed stack runs
+type StackInspector interface {
+	// CodeUris returns the CodeUri of every serverless function of a stack, or none if the stack does not exist
+	CodeUris(ctx context.Context, stackName, region string) ([]string, error)
+}
+
+// CLIStackInspector is a StackInspector using the aws command line
+type CLIStackInspector struct {
+	Runner command.Runner
+}
+
+func (inspector CLIStackInspector) CodeUris(ctx context.Context, stackName, region string) ([]string, error) {
+
+	args := append([]string{"cloudformation", "get-template"}, regionArgs(region)...)
+
+	out, err := inspector.Runner.Run(ctx, "aws", append(args, "--stack-name", stackName, "--template-stage", "Original", "--output", "json")...)
+
+	if err != nil {
+
+		if strings.Contains(err.Error(), "does not exist") {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var template struct {
+		// A YAML template is returned as a string, and a JSON template as an object
+		TemplateBody json.RawMessage
+	}
+
+	if err := json.Unmarshal(out, &template); err != nil {
+		return nil, fmt.Errorf("Cannot parse stack template: %v", err)
+	}
+
+	body := []byte(template.TemplateBody)
+
+	var text string
+
+	if json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	}
+
+	return codeUris(body)
+}
+
+// CLIObjectClient is a history.ObjectClient using the aws command line
+type CLIObjectClient struct {
+	Runner command.Runner
+}
+
+func (client CLIObjectClient) PutObject(ctx context.Context, bucket, key string, body []byte) error {
+
+	file, err := ioutil.TempFile("", "object")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(body)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Runner.Run(ctx, "aws", "s3api", "put-object", "--bucket", bucket, "--key", key, "--body", file.Name())
+
+	return err
+}
+
+func (client CLIObjectClient) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	return client.Runner.Run(ctx, "aws", "s3", "cp", "s3://"+bucket+"/"+key, "-")
+}
+
+func (client CLIObjectClient) ListKeys(ctx context.Context, bucket, prefix string) ([]string, error) {
+
+	out, err := client.Runner.Run(ctx, "aws", "s3api", "list-objects-v2", "--bucket", bucket, "--prefix", prefix, "--output", "json")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// There is no output when no objects match
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var objects struct {
+		Contents []struct {
+			Key string
+		}
+	}
+
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, fmt.Errorf("Cannot parse objects: %v", err)
+	}
+
+	keys := make([]string, len(objects.Contents))
+
+	for i, object := range objects.Contents {
+		keys[i] = object.Key
+	}
+
+	return keys, nil
+}
+
+// Returns parameters as Key=Value arguments in key order
+func parameterOverrides(parameters map[string]string) []string {
+
+	var overrides []string
+
+	for _, key := range sortedKeys(parameters) {
+		overrides = append(overrides, key+"="+parameters[key])
+	}
+
+	return overrides
+}
+
+// Returns the --region argument for a region, or nothing for the AWS CLI default region
+func regionArgs(region string) []string {
+
+	if region == "" {
+		return nil
+	}
+
+	return []string{"--region", region}
+}
@@ -0,0 +1,155 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestHostedZoneID(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws route53 list-hosted-zones --output json"] = `{"HostedZones":[
+		{"Id":"/hostedzone/Z999","Name":"example.org."},
+		{"Id":"/hostedzone/Z123","Name":"example.com."}]}`
+
+	resolver := CLIDomainResolver{Runner: runner}
+
+	id, err := resolver.HostedZoneID(context.Background(), "example.com")
+
+	utils.AssertNoError(t, "Hosted zone lookup", err)
+	utils.AssertEquals(t, "Hosted zone ID", "Z123", id)
+
+	_, err = resolver.HostedZoneID(context.Background(), "example.net")
+
+	utils.AssertErrorEquals(t, "Missing hosted zone", "No hosted-zone record was found for example.net domain", err)
+}
+
+func TestCertificateArn(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws acm list-certificates --region us-east-1 --output json"] = `{"CertificateSummaryList":[
+		{"CertificateArn":"arn:wildcard","DomainName":"*.example.com"},
+		{"CertificateArn":"arn:exact","DomainName":"api.example.com"}]}`
+
+	resolver := CLICertificateResolver{Runner: runner}
+
+	arn, err := resolver.CertificateArn(context.Background(), "api.example.com", "example.com", "us-east-1")
+
+	utils.AssertNoError(t, "Exact certificate lookup", err)
+	utils.AssertEquals(t, "Exact certificate preferred", "arn:exact", arn)
+
+	arn, err = resolver.CertificateArn(context.Background(), "api-test.example.com", "example.com", "us-east-1")
+
+	utils.AssertNoError(t, "Wildcard certificate lookup", err)
+	utils.AssertEquals(t, "Wildcard certificate", "arn:wildcard", arn)
+
+	_, err = resolver.CertificateArn(context.Background(), "api.example.org", "example.org", "us-east-1")
+
+	utils.AssertErrorEquals(t, "Missing certificate",
+		"No SSL certificate was found for api.example.org or *.example.org patterns in us-east-1", err)
+
+	runner.outputs["aws acm list-certificates --output json"] = `{"CertificateSummaryList":[]}`
+
+	_, err = resolver.CertificateArn(context.Background(), "api.example.com", "example.com", "")
+
+	utils.AssertErrorEquals(t, "Missing certificate in default region",
+		"No SSL certificate was found for api.example.com or *.example.com patterns in the default region", err)
+
+	runner.errors["aws acm list-certificates --region us-east-1 --output json"] = errors.New("no credentials")
+
+	_, err = resolver.CertificateArn(context.Background(), "api.example.com", "example.com", "us-east-1")
+
+	utils.AssertErrorEquals(t, "AWS failure", "no credentials", err)
+}
+
+func TestStackDeploy(t *testing.T) {
+
+	runner := newFakeRunner()
+	deployer := CLIStackDeployer{Runner: runner}
+
+	err := deployer.Deploy(context.Background(), "packaged.yaml", "api-stack-test", "eu-west-1", map[string]string{
+		"Platform": "test",
+		"Commit":   "abc",
+	})
+
+	utils.AssertNoError(t, "Stack deploy", err)
+	utils.AssertEquals(t, "Stack deploy command",
+		"aws cloudformation deploy --region eu-west-1 --template-file packaged.yaml --stack-name api-stack-test --capabilities CAPABILITY_IAM "+
+			"--parameter-overrides Commit=abc Platform=test", runner.calls[0])
+}
+
+func TestStackOutputs(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws cloudformation describe-stacks --region eu-west-1 --stack-name api-stack-test --output json"] = `{"Stacks":[
+		{"StackName":"api-stack-test","Outputs":[{"OutputKey":"ApiUrl","OutputValue":"https://api-test.example.com/"}]}]}`
+
+	deployer := CLIStackDeployer{Runner: runner}
+
+	outputs, err := deployer.Outputs(context.Background(), "api-stack-test", "eu-west-1")
+
+	utils.AssertNoError(t, "Stack outputs", err)
+	utils.AssertEquals(t, "Stack output", "https://api-test.example.com/", outputs["ApiUrl"])
+
+	runner.outputs["aws cloudformation describe-stacks --stack-name api-stack-test --output json"] = `{"Stacks":[]}`
+
+	_, err = deployer.Outputs(context.Background(), "api-stack-test", "")
+
+	utils.AssertErrorEquals(t, "Missing stack", "Stack api-stack-test was not found", err)
+}
+
+func TestStackCodeUris(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws cloudformation get-template --region eu-west-1 --stack-name api-stack-live --template-stage Original --output json"] = `{
+		"TemplateBody": "Resources:\n  Function:\n    Type: AWS::Serverless::Function\n    Properties:\n      CodeUri: s3://bucket/api/live/5e7d.zip\n"}`
+	runner.outputs["aws cloudformation get-template --region us-east-1 --stack-name api-stack-live --template-stage Original --output json"] = `{
+		"TemplateBody": {"Resources": {"Function": {"Type": "AWS::Serverless::Function", "Properties": {"CodeUri": "s3://bucket/api/live/7a9f.zip"}}}}}`
+	runner.errors["aws cloudformation get-template --region eu-west-2 --stack-name api-stack-live --template-stage Original --output json"] =
+		errors.New("An error occurred (ValidationError) when calling the GetTemplate operation: Stack with id api-stack-live does not exist")
+
+	inspector := CLIStackInspector{Runner: runner}
+
+	uris, err := inspector.CodeUris(context.Background(), "api-stack-live", "eu-west-1")
+
+	utils.AssertNoError(t, "YAML template", err)
+	utils.AssertEquals(t, "YAML CodeUri", "s3://bucket/api/live/5e7d.zip", strings.Join(uris, " "))
+
+	uris, err = inspector.CodeUris(context.Background(), "api-stack-live", "us-east-1")
+
+	utils.AssertNoError(t, "JSON template", err)
+	utils.AssertEquals(t, "JSON CodeUri", "s3://bucket/api/live/7a9f.zip", strings.Join(uris, " "))
+
+	uris, err = inspector.CodeUris(context.Background(), "api-stack-live", "eu-west-2")
+
+	utils.AssertNoError(t, "Missing stack", err)
+	utils.AssertEquals(t, "No CodeUris", 0, len(uris))
+}
+
+func TestObjectClient(t *testing.T) {
+
+	runner := newFakeRunner()
+	runner.outputs["aws s3api list-objects-v2 --bucket history --prefix live/ --output json"] = `{"Contents":[
+		{"Key":"live/20261001T120000.000000000Z.json"},{"Key":"live/20261002T120000.000000000Z.json"}]}`
+
+	client := CLIObjectClient{Runner: runner}
+
+	keys, err := client.ListKeys(context.Background(), "history", "live/")
+
+	utils.AssertNoError(t, "List keys", err)
+	utils.AssertEquals(t, "Keys", "live/20261001T120000.000000000Z.json live/20261002T120000.000000000Z.json", strings.Join(keys, " "))
+
+	keys, err = client.ListKeys(context.Background(), "history", "test/")
+
+	utils.AssertNoError(t, "List no keys", err)
+	utils.AssertEquals(t, "No keys", 0, len(keys))
+
+	err = client.PutObject(context.Background(), "history", "live/record.json", []byte("{}"))
+
+	utils.AssertNoError(t, "Put object", err)
+	utils.AssertTrue(t, "Put object command", strings.HasPrefix(runner.calls[2], "aws s3api put-object --bucket history --key live/record.json --body "))
+}
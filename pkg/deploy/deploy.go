@@ -0,0 +1,326 @@
+// The deploy package deploys the API stack to an environment defined in the project configuration: it checks the
+// repository with a GitGuard, resolves the hosted zone and certificate for the custom domain, builds the lambda, and
+// packages it and deploys the CloudFormation stack in each region of the environment. Each deployment is recorded in
+// a history store, from which a Rollback can redeploy an earlier one without rebuilding.
+//
+// Each step is behind an interface so that it can be replaced by a fake in tests.
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+var subdomainPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// Request holds the arguments of a deployment
+type Request struct {
+	SubdomainBase string
+	Domain        string
+	// The name of an environment in the project configuration, or empty for the default environment
+	Platform string
+}
+
+// Deployer deploys the API
+type Deployer struct {
+	Git          GitGuard
+	Domains      DomainResolver
+	Certificates CertificateResolver
+	Packager     Packager
+	Stacks       StackDeployer
+	// Optional. If set, plans include the changes a deployment would make to the stack
+	ChangeSets ChangeSetClient
+	// The environments and template to deploy
+	Project project.Config
+	// Asks the user to confirm a deployment, returning true to proceed. Deployments to environments requiring
+	// confirmation fail if this is nil
+	Confirm func(prompt string) bool
+	// If true, deployments do not run the tests before building
+	SkipTests bool
+	// Optional. If set, deployments are recorded here
+	History history.Store
+	// The user recorded as making deployments
+	User string
+	// Progress messages are written here
+	Out io.Writer
+}
+
+// ResolveRequest checks the subdomain base and domain of a request and returns its environment from the project
+// configuration
+func ResolveRequest(config project.Config, request Request) (*project.Environment, error) {
+
+	if !subdomainPattern.MatchString(request.SubdomainBase) {
+		return nil, fmt.Errorf("%v does not match pattern %v", request.SubdomainBase, subdomainPattern)
+	}
+
+	if request.Domain == "" {
+		return nil, errors.New("A domain is required")
+	}
+
+	return config.Environment(request.Platform)
+}
+
+// CustomDomain returns the domain at which the API is served in an environment
+func CustomDomain(env *project.Environment, request Request) string {
+	return env.SubdomainFor(request.SubdomainBase) + "." + request.Domain
+}
+
+// Deploy checks and builds the API for a request, and packages and deploys it to each region of the environment, in
+// sequence or in parallel. A sequential deployment stops at the first region which fails
+func (deployer Deployer) Deploy(ctx context.Context, request Request) error {
+
+	plan, err := deployer.Plan(ctx, request)
+
+	if err != nil {
+		return err
+	}
+
+	if len(plan.Problems) > 0 {
+		return errors.New(strings.Join(plan.Problems, "\n"))
+	}
+
+	if plan.Confirm && (deployer.Confirm == nil || !deployer.Confirm(fmt.Sprintf("About to deploy %v to %v. Confirm?", plan.Git.Tag, plan.Platform))) {
+		return errors.New("Cancelling deployment")
+	}
+
+	if !deployer.SkipTests {
+
+		deployer.printf("Testing %v (%v)\n", plan.Git.Tag, plan.Git.Commit)
+
+		if err := deployer.Packager.Test(ctx); err != nil {
+			return err
+		}
+	}
+
+	deployer.printf("Building %v (%v) for %v\n", plan.Git.Tag, plan.Git.Commit, plan.CustomDomain)
+
+	code, err := deployer.Packager.Build(ctx, plan.Git)
+
+	if err != nil {
+		return err
+	}
+
+	deployer.printf("Built %v\n", code.Manifest.Name)
+
+	var results []RegionResult
+
+	switch {
+	case len(plan.Regions) == 1:
+		results = []RegionResult{deployer.timedDeployRegion(ctx, plan, plan.Regions[0], code)}
+	case plan.Parallel:
+		results = deployer.deployParallel(ctx, plan, code)
+	default:
+		results = deployer.deploySequence(ctx, plan, code)
+	}
+
+	recordErr := deployer.record(ctx, history.Record{
+		Platform:  plan.Platform,
+		Release:   plan.Git.Tag,
+		Commit:    plan.Git.Commit,
+		Branch:    plan.Git.Branch,
+		StackName: plan.StackName,
+		Artifact:  code.Manifest.SHA256,
+	}, results)
+
+	if len(results) == 1 {
+
+		if results[0].Err != nil {
+			return results[0].Err
+		}
+
+		return recordErr
+	}
+
+	deployer.printf("\nResults:\n\n")
+
+	var failures []string
+
+	for _, result := range results {
+
+		deployer.printf("  %v\n", result)
+
+		if result.Err != nil {
+			failures = append(failures, regionName(result.Region)+": "+result.Err.Error())
+		}
+	}
+
+	if recordErr != nil {
+		failures = append(failures, recordErr.Error())
+	}
+
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+// RegionResult is the outcome of deploying to one region
+type RegionResult struct {
+	Region   string
+	Err      error
+	Skipped  bool
+	Duration time.Duration
+	// What was deployed, if successful
+	Deployed history.Region
+}
+
+func (result RegionResult) String() string {
+
+	switch {
+	case result.Skipped:
+		return regionName(result.Region) + ": skipped"
+	case result.Err != nil:
+		return fmt.Sprintf("%v: failed after %v: %v", regionName(result.Region), result.Duration.Round(time.Second), result.Err)
+	}
+
+	return fmt.Sprintf("%v: deployed in %v", regionName(result.Region), result.Duration.Round(time.Second))
+}
+
+func (deployer Deployer) deploySequence(ctx context.Context, plan Plan, code artifact.Artifact) []RegionResult {
+
+	results := make([]RegionResult, len(plan.Regions))
+	failed := false
+
+	for i, region := range plan.Regions {
+
+		if failed {
+			results[i] = RegionResult{Region: region.Region, Skipped: true}
+			continue
+		}
+
+		results[i] = deployer.timedDeployRegion(ctx, plan, region, code)
+		failed = results[i].Err != nil
+	}
+
+	return results
+}
+
+func (deployer Deployer) deployParallel(ctx context.Context, plan Plan, code artifact.Artifact) []RegionResult {
+
+	results := make([]RegionResult, len(plan.Regions))
+
+	var wg sync.WaitGroup
+
+	for i, region := range plan.Regions {
+
+		wg.Add(1)
+
+		go func(i int, region RegionPlan) {
+			defer wg.Done()
+			results[i] = deployer.timedDeployRegion(ctx, plan, region, code)
+		}(i, region)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+func (deployer Deployer) timedDeployRegion(ctx context.Context, plan Plan, region RegionPlan, code artifact.Artifact) RegionResult {
+
+	start := time.Now()
+	deployed, err := deployer.deployRegion(ctx, plan, region, code)
+
+	return RegionResult{Region: region.Region, Err: err, Duration: time.Since(start), Deployed: deployed}
+}
+
+// Packages the API for a region and deploys the stack there, keeping the packaged template so that the deployment can
+// be rolled back to. Progress is not printed for parallel deployments
+func (deployer Deployer) deployRegion(ctx context.Context, plan Plan, region RegionPlan, code artifact.Artifact) (deployed history.Region, err error) {
+
+	verbose := !plan.Parallel || len(plan.Regions) == 1
+
+	if verbose {
+		deployer.printf("Packaging for region %v\n", regionName(region.Region))
+	}
+
+	packaged, cleanup, err := deployer.Packager.Package(ctx, plan.Template, region.Region, code)
+	defer cleanup()
+
+	if err != nil {
+		return
+	}
+
+	raw, err := ioutil.ReadFile(packaged)
+
+	if err != nil {
+		return
+	}
+
+	deployed = history.Region{Region: region.Region, Parameters: region.Parameters, Template: string(raw)}
+
+	if verbose {
+		deployer.printf("Deploying %v stack in region %v\n", plan.StackName, regionName(region.Region))
+	}
+
+	if err = deployer.Stacks.Deploy(ctx, packaged, plan.StackName, region.Region, region.Parameters); err != nil {
+		return
+	}
+
+	deployed.Outputs, err = deployer.Stacks.Outputs(ctx, plan.StackName, region.Region)
+
+	return
+}
+
+// Records a deployment of the regions which were deployed successfully, if any
+func (deployer Deployer) record(ctx context.Context, record history.Record, results []RegionResult) error {
+
+	if deployer.History == nil {
+		return nil
+	}
+
+	for _, result := range results {
+		if result.Err == nil && !result.Skipped {
+			record.Regions = append(record.Regions, result.Deployed)
+		}
+	}
+
+	if len(record.Regions) == 0 {
+		return nil
+	}
+
+	record.Time = time.Now().UTC()
+	record.ID = history.NewID(record.Time)
+	record.User = deployer.User
+
+	if err := deployer.History.Save(ctx, record); err != nil {
+		return fmt.Errorf("Cannot record deployment: %v", err)
+	}
+
+	deployer.printf("Recorded deployment %v\n", record.ID)
+
+	return nil
+}
+
+func (deployer Deployer) printf(format string, a ...interface{}) {
+
+	if deployer.Out != nil {
+		fmt.Fprintf(deployer.Out, format, a...)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,391 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// fakeRunner returns canned output for commands, keyed by the command line, and records every command run
+type fakeRunner struct {
+	outputs map[string]string
+	errors  map[string]error
+	calls   []string
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{
+		outputs: map[string]string{},
+		errors:  map[string]error{},
+	}
+}
+
+func (runner *fakeRunner) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	command := strings.Join(append([]string{name}, args...), " ")
+	runner.calls = append(runner.calls, command)
+
+	return []byte(runner.outputs[command]), runner.errors[command]
+}
+
+type fakeGit struct {
+	info     GitInfo
+	checkErr error
+}
+
+func (git fakeGit) Info(ctx context.Context) (GitInfo, error) {
+	return git.info, nil
+}
+
+func (git fakeGit) Check(ctx context.Context, env *project.Environment, info GitInfo) error {
+	return git.checkErr
+}
+
+type fakeDomains map[string]string
+
+func (domains fakeDomains) HostedZoneID(ctx context.Context, domain string) (string, error) {
+
+	if id, ok := domains[domain]; ok {
+		return id, nil
+	}
+
+	return "", fmt.Errorf("No hosted-zone record was found for %v domain", domain)
+}
+
+type fakeCertificates map[string]string
+
+func (certificates fakeCertificates) CertificateArn(ctx context.Context, customDomain, domain, region string) (string, error) {
+
+	if arn, ok := certificates[customDomain+"@"+region]; ok {
+		return arn, nil
+	}
+
+	if arn, ok := certificates[customDomain]; ok && region == EdgeCertificateRegion {
+		return arn, nil
+	}
+
+	return "", errors.New("No SSL certificate")
+}
+
+type fakePackager struct {
+	mutex    sync.Mutex
+	tested   int
+	packaged bool
+	cleaned  bool
+	region   string
+	regions  []string
+	info     GitInfo
+	err      map[string]error
+	// The directory packaged templates are written to
+	dir string
+}
+
+func (packager *fakePackager) Test(ctx context.Context) error {
+
+	packager.tested++
+
+	return nil
+}
+
+func (packager *fakePackager) Build(ctx context.Context, info GitInfo) (artifact.Artifact, error) {
+
+	packager.info = info
+
+	return artifact.Artifact{Path: "bin/artifact.zip", Manifest: artifact.Manifest{Name: "artifact.zip", SHA256: "5e7d"}}, nil
+}
+
+func (packager *fakePackager) Package(ctx context.Context, template, region string, code artifact.Artifact) (string, func(), error) {
+
+	packager.mutex.Lock()
+	defer packager.mutex.Unlock()
+
+	packager.packaged = true
+	packager.region = region
+	packager.regions = append(packager.regions, region)
+
+	packaged := filepath.Join(packager.dir, region, "packaged-"+filepath.Base(template))
+
+	cleanup := func() {
+		packager.mutex.Lock()
+		packager.cleaned = true
+		os.Remove(packaged)
+		packager.mutex.Unlock()
+	}
+
+	os.MkdirAll(filepath.Dir(packaged), 0755)
+	ioutil.WriteFile(packaged, []byte("CodeUri: s3://artifacts-"+region+"/"+packager.info.Commit), 0644)
+
+	return packaged, cleanup, packager.err[region]
+}
+
+type fakeStacks struct {
+	mutex      sync.Mutex
+	template   string
+	stackName  string
+	parameters map[string]string
+	byRegion   map[string]map[string]string
+	err        map[string]error
+}
+
+func (stacks *fakeStacks) Deploy(ctx context.Context, template, stackName, region string, parameters map[string]string) error {
+
+	stacks.mutex.Lock()
+	defer stacks.mutex.Unlock()
+
+	stacks.template = template
+	stacks.stackName = stackName
+	stacks.parameters = parameters
+
+	if stacks.byRegion == nil {
+		stacks.byRegion = map[string]map[string]string{}
+	}
+
+	stacks.byRegion[region] = parameters
+
+	return stacks.err[region]
+}
+
+func (stacks *fakeStacks) Outputs(ctx context.Context, stackName, region string) (map[string]string, error) {
+	return map[string]string{"ApiUrl": "https://" + region + ".example.com/"}, nil
+}
+
+func testDeployer(t *testing.T, confirmed bool) (Deployer, *fakePackager, *fakeStacks) {
+
+	packager := &fakePackager{dir: t.TempDir()}
+	stacks := &fakeStacks{}
+
+	return Deployer{
+		Git: fakeGit{info: GitInfo{Tag: "v1.0.1", Branch: "master", Commit: "a00eaaf456941631", Time: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}},
+		Domains: fakeDomains{
+			"example.com": "Z123",
+		},
+		Certificates: fakeCertificates{
+			"api-test.example.com": "arn:test",
+			"api.example.com":      "arn:live",
+		},
+		Packager: packager,
+		Stacks:   stacks,
+		Project:  project.Default(),
+		Confirm:  func(prompt string) bool { return confirmed },
+	}, packager, stacks
+}
+
+func TestDeploy(t *testing.T) {
+
+	deployer, packager, stacks := testDeployer(t, false)
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com"})
+
+	utils.AssertNoError(t, "Deploy", err)
+	utils.AssertEquals(t, "Tested once", 1, packager.tested)
+	utils.AssertTrue(t, "Packaged", packager.packaged)
+	utils.AssertTrue(t, "Cleaned up", packager.cleaned)
+	utils.AssertEquals(t, "Packaged release", "v1.0.1", packager.info.Tag)
+	utils.AssertEquals(t, "Deployed template", "packaged-api.yaml", filepath.Base(stacks.template))
+	utils.AssertEquals(t, "Stack name", "api-stack-test", stacks.stackName)
+	utils.AssertEquals(t, "Platform parameter", "test", stacks.parameters["Platform"])
+	utils.AssertEquals(t, "CustomDomain parameter", "api-test.example.com", stacks.parameters["CustomDomain"])
+	utils.AssertEquals(t, "HostedZone parameter", "Z123", stacks.parameters["HostedZone"])
+	utils.AssertEquals(t, "CertificateArn parameter", "arn:test", stacks.parameters["CertificateArn"])
+	utils.AssertEquals(t, "Release parameter", "v1.0.1", stacks.parameters["Release"])
+	utils.AssertEquals(t, "Commit parameter", "a00eaaf456941631", stacks.parameters["Commit"])
+	utils.AssertEquals(t, "Branch parameter", "master", stacks.parameters["Branch"])
+	utils.AssertEquals(t, "BuildTime parameter", "2026-10-01T12:00:00Z", stacks.parameters["BuildTime"])
+	utils.AssertEquals(t, "Runtime parameter", "provided.al2023", stacks.parameters["Runtime"])
+	utils.AssertEquals(t, "Architecture parameter", "x86_64", stacks.parameters["Architecture"])
+}
+
+func TestDeploySkippingTests(t *testing.T) {
+
+	deployer, packager, _ := testDeployer(t, false)
+	deployer.SkipTests = true
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com"})
+
+	utils.AssertNoError(t, "Deploy", err)
+	utils.AssertEquals(t, "Not tested", 0, packager.tested)
+	utils.AssertTrue(t, "Packaged", packager.packaged)
+}
+
+func TestDeployLive(t *testing.T) {
+
+	deployer, packager, stacks := testDeployer(t, true)
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+	utils.AssertNoError(t, "Confirmed live deploy", err)
+	utils.AssertTrue(t, "Confirmed live deploy packaged", packager.packaged)
+	utils.AssertEquals(t, "Live CustomDomain parameter", "api.example.com", stacks.parameters["CustomDomain"])
+
+	deployer, packager, _ = testDeployer(t, false)
+
+	err = deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+	utils.AssertErrorEquals(t, "Unconfirmed live deploy", "Cancelling deployment", err)
+	utils.AssertFalse(t, "Unconfirmed live deploy packaged", packager.packaged)
+}
+
+func TestDeployFailures(t *testing.T) {
+
+	deployer, packager, _ := testDeployer(t, true)
+
+	deployer.Git = fakeGit{checkErr: errors.New("There are uncommitted changes")}
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com"})
+
+	utils.AssertErrorEquals(t, "Git guard failure", "There are uncommitted changes", err)
+
+	deployer, packager, _ = testDeployer(t, true)
+
+	err = deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.org"})
+
+	utils.AssertErrorEquals(t, "Unknown domain", "No hosted-zone record was found for example.org domain\nNo SSL certificate", err)
+	utils.AssertFalse(t, "Unknown domain packaged", packager.packaged)
+}
+
+func TestResolveRequest(t *testing.T) {
+
+	config := project.Default()
+
+	env, err := ResolveRequest(config, Request{SubdomainBase: "my-api", Domain: "example.com"})
+
+	utils.AssertNoError(t, "Valid request", err)
+	utils.AssertEquals(t, "Default platform", "test", env.Name)
+
+	_, err = ResolveRequest(config, Request{SubdomainBase: "my-api", Domain: "example.com", Platform: "prod"})
+
+	utils.AssertErrorEquals(t, "Invalid platform", "Platform must be one of live, stage, test", err)
+
+	_, err = ResolveRequest(config, Request{SubdomainBase: "My_API", Domain: "example.com"})
+
+	utils.AssertErrorEquals(t, "Invalid subdomain", "My_API does not match pattern ^[a-z0-9-]+$", err)
+}
+
+func TestDeployConfiguredEnvironment(t *testing.T) {
+
+	deployer, packager, stacks := testDeployer(t, false)
+
+	config, err := project.Parse([]byte(`
+environments:
+  dev:
+    subdomain: "dev-{base}"
+    stack: "sample-{platform}"
+    region: eu-west-2
+    parameters:
+      ApiLambdaNameBase: DevLambda
+`))
+
+	utils.AssertNoError(t, "Project configuration", err)
+
+	deployer.Project = config
+	deployer.Certificates = fakeCertificates{"dev-api.example.com": "arn:dev"}
+
+	err = deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "dev"})
+
+	utils.AssertNoError(t, "Configured deploy", err)
+	utils.AssertEquals(t, "Configured region", "eu-west-2", packager.region)
+	utils.AssertEquals(t, "Configured stack name", "sample-dev", stacks.stackName)
+	utils.AssertEquals(t, "Configured custom domain", "dev-api.example.com", stacks.parameters["CustomDomain"])
+	utils.AssertEquals(t, "Configured extra parameter", "DevLambda", stacks.parameters["ApiLambdaNameBase"])
+	utils.AssertEquals(t, "Configured platform parameter", "dev", stacks.parameters["Platform"])
+}
+
+const multiRegionConfig = `
+environments:
+  live:
+    subdomain: "{base}"
+    endpoint: regional
+    routing: failover
+    parallel: %v
+    regions:
+    - name: eu-west-1
+      failover: primary
+    - name: us-east-1
+      failover: secondary
+      parameters:
+        ApiLambdaNameBase: UsLambda
+    - name: ap-south-1
+      failover: secondary
+`
+
+func multiRegionDeployer(t *testing.T, parallel bool) (Deployer, *fakePackager, *fakeStacks) {
+
+	deployer, packager, stacks := testDeployer(t, true)
+
+	config, err := project.Parse([]byte(fmt.Sprintf(multiRegionConfig, parallel)))
+
+	utils.AssertNoError(t, "Multi-region configuration", err)
+
+	deployer.Project = config
+	deployer.Certificates = fakeCertificates{
+		"api.example.com@eu-west-1":  "arn:eu",
+		"api.example.com@us-east-1":  "arn:us",
+		"api.example.com@ap-south-1": "arn:ap",
+	}
+	deployer.Out = &bytes.Buffer{}
+
+	return deployer, packager, stacks
+}
+
+func TestDeployRegions(t *testing.T) {
+
+	for _, parallel := range []bool{false, true} {
+
+		deployer, packager, stacks := multiRegionDeployer(t, parallel)
+
+		err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+		utils.AssertNoError(t, "Multi-region deploy", err)
+
+		sort.Strings(packager.regions)
+
+		utils.AssertEquals(t, "Packaged regions", "ap-south-1 eu-west-1 us-east-1", strings.Join(packager.regions, " "))
+		utils.AssertEquals(t, "Tested once for every region", 1, packager.tested)
+		utils.AssertEquals(t, "Deployed regions", 3, len(stacks.byRegion))
+
+		eu, us := stacks.byRegion["eu-west-1"], stacks.byRegion["us-east-1"]
+
+		utils.AssertEquals(t, "Regional certificate", "arn:eu", eu["CertificateArn"])
+		utils.AssertEquals(t, "Endpoint type", "REGIONAL", eu["EndpointType"])
+		utils.AssertEquals(t, "Routing policy", "FAILOVER", eu["RoutingPolicy"])
+		utils.AssertEquals(t, "Primary role", "PRIMARY", eu["FailoverRole"])
+		utils.AssertEquals(t, "Secondary role", "SECONDARY", us["FailoverRole"])
+		utils.AssertEquals(t, "Region parameter", "UsLambda", us["ApiLambdaNameBase"])
+		utils.AssertEquals(t, "Region parameter elsewhere", "", eu["ApiLambdaNameBase"])
+		utils.AssertTrue(t, "Results", strings.Contains(deployer.Out.(*bytes.Buffer).String(), "  us-east-1: deployed in 0s\n"))
+	}
+}
+
+func TestDeployRegionFailures(t *testing.T) {
+
+	deployer, packager, stacks := multiRegionDeployer(t, false)
+	stacks.err = map[string]error{"us-east-1": errors.New("Stack rolled back")}
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+	utils.AssertErrorEquals(t, "Sequential failure", "us-east-1: Stack rolled back", err)
+	utils.AssertEquals(t, "Sequential stops", "eu-west-1 us-east-1", strings.Join(packager.regions, " "))
+	utils.AssertTrue(t, "Skipped result", strings.Contains(deployer.Out.(*bytes.Buffer).String(), "  ap-south-1: skipped\n"))
+
+	deployer, packager, stacks = multiRegionDeployer(t, true)
+	stacks.err = map[string]error{"us-east-1": errors.New("Stack rolled back")}
+	packager.err = map[string]error{"eu-west-1": errors.New("Bucket exists")}
+
+	err = deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+	utils.AssertErrorEquals(t, "Parallel failures", "eu-west-1: Bucket exists\nus-east-1: Stack rolled back", err)
+	utils.AssertEquals(t, "Parallel continues", 3, len(packager.regions))
+}
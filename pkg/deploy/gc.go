@@ -0,0 +1,222 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+// The default minimum age of an artifact which the collector removes, which leaves alone those of deployments in
+// progress
+const DefaultMinAge = 24 * time.Hour
+
+// Collector removes the artifacts of a platform which neither a recorded deployment nor a deployed stack refers to
+type Collector struct {
+	Artifacts ArtifactStore
+	History   history.Store
+	Stacks    StackInspector
+	Project   project.Config
+	// Artifacts uploaded more recently than this are kept
+	MinAge time.Duration
+	// Clock for the age of artifacts. Defaults to time.Now
+	Now func() time.Time
+	Out io.Writer
+}
+
+func (collector Collector) now() time.Time {
+
+	if collector.Now != nil {
+		return collector.Now()
+	}
+
+	return time.Now()
+}
+
+func (collector Collector) printf(format string, a ...interface{}) {
+
+	if collector.Out != nil {
+		fmt.Fprintf(collector.Out, format, a...)
+	}
+}
+
+// Collect removes the artifacts of a platform from the artifact bucket of every region it is or has been deployed to,
+// except those referred to by a recorded deployment or by the stack in the region, or uploaded within the minimum age.
+// It refuses to collect a platform with no recorded deployments, as the history may simply be missing, as it is from a
+// fresh clone when kept in a directory. With dryRun, it only prints what it would remove
+func (collector Collector) Collect(ctx context.Context, platform string, dryRun bool) error {
+	return collector.collect(ctx, platform, dryRun, false)
+}
+
+// CollectAll collects the artifacts of every platform of a project, each with the collector for its environment.
+// Platforms with no recorded deployments are skipped with a message rather than refused, so that one which has never
+// been deployed does not stop the others being collected
+func CollectAll(ctx context.Context, config project.Config, collectorFor func(env *project.Environment) Collector, dryRun bool) error {
+
+	var platforms []string
+
+	for name := range config.Environments {
+		platforms = append(platforms, name)
+	}
+
+	sort.Strings(platforms)
+
+	for _, platform := range platforms {
+
+		env, err := config.Environment(platform)
+
+		if err != nil {
+			return err
+		}
+
+		if err := collectorFor(env).collect(ctx, env.Name, dryRun, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector Collector) collect(ctx context.Context, platform string, dryRun, skipUnrecorded bool) error {
+
+	if collector.History == nil {
+		return errors.New("No deployment history is configured")
+	}
+
+	if collector.Stacks == nil {
+		return errors.New("No stack inspector is configured")
+	}
+
+	env, err := collector.Project.Environment(platform)
+
+	if err != nil {
+		return err
+	}
+
+	records, err := collector.History.List(ctx, env.Name)
+
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 && skipUnrecorded {
+		collector.printf("Skipping %v: no deployments of it are recorded in the history\n", env.Name)
+		return nil
+	}
+
+	if len(records) == 0 {
+		return fmt.Errorf("No deployments of %v are recorded in the history, so its artifacts cannot be collected safely", env.Name)
+	}
+
+	referenced := map[string]bool{}
+	var regions []string
+
+	for _, target := range env.Targets() {
+		regions = appendMissing(regions, target.Name)
+	}
+
+	for _, record := range records {
+
+		referenced[record.Artifact] = true
+
+		for _, region := range record.Regions {
+			regions = appendMissing(regions, region.Region)
+		}
+	}
+
+	prefix := collector.Project.ArtifactPrefix(env.Name)
+	cutoff := collector.now().Add(-collector.MinAge)
+
+	for _, region := range regions {
+
+		uris, err := collector.Stacks.CodeUris(ctx, env.StackName(), region)
+
+		if err != nil {
+			return err
+		}
+
+		for _, uri := range uris {
+			referenced[artifactName(uri)] = true
+		}
+	}
+
+	for _, region := range regions {
+		if err := collector.collectRegion(ctx, region, prefix, referenced, cutoff, dryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (collector Collector) collectRegion(ctx context.Context, region, prefix string, referenced map[string]bool, cutoff time.Time, dryRun bool) error {
+
+	bucket, err := collector.Artifacts.BucketName(ctx, region)
+
+	if err != nil {
+		return err
+	}
+
+	objects, err := collector.Artifacts.List(ctx, bucket, prefix, region)
+
+	if err != nil {
+		return err
+	}
+
+	var keys []string
+
+	for _, object := range objects {
+
+		if !referenced[artifactName(object.Key)] && object.LastModified.Before(cutoff) {
+			keys = append(keys, object.Key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	action := "Removing"
+
+	if dryRun {
+		action = "Would remove"
+	}
+
+	for _, key := range keys {
+		collector.printf("%v s3://%v/%v\n", action, bucket, key)
+	}
+
+	collector.printf("%v: %v of %v objects under %v unreferenced\n", bucket, len(keys), len(objects), prefix)
+
+	if dryRun || len(keys) == 0 {
+		return nil
+	}
+
+	return collector.Artifacts.Delete(ctx, bucket, region, keys)
+}
+
+// Returns the name of an artifact from its key or URI, as artifacts and their manifests are named by the SHA-256 digest
+// of the artifact
+func artifactName(key string) string {
+
+	name := path.Base(key)
+
+	return strings.TrimSuffix(name, path.Ext(name))
+}
+
+// Appends a string to a list unless it is already in it
+func appendMissing(list []string, s string) []string {
+
+	for _, item := range list {
+		if item == s {
+			return list
+		}
+	}
+
+	return append(list, s)
+}
@@ -0,0 +1,149 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// fakeInspector returns the CodeUris of stacks, keyed by stack name and region
+type fakeInspector map[string][]string
+
+func (inspector fakeInspector) CodeUris(ctx context.Context, stackName, region string) ([]string, error) {
+	return inspector[stackName+" "+region], nil
+}
+
+func TestCollect(t *testing.T) {
+
+	config, err := project.Parse([]byte(historyConfig))
+
+	utils.AssertNoError(t, "History configuration", err)
+
+	now := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-72 * time.Hour)
+
+	store := newFakeArtifacts()
+
+	for _, key := range []string{"5e7d.zip", "5e7d.json", "6f8e.zip", "6f8e.json", "7a9f.zip", "9c1b.zip"} {
+		store.put("artifacts-eu-west-1", "api/live/"+key, old)
+	}
+
+	store.put("artifacts-eu-west-1", "api/live/8b0a.zip", now.Add(-time.Hour))
+	store.put("artifacts-eu-west-1", "api/test/6f8e.zip", old)
+	store.put("artifacts-us-east-1", "api/live/6f8e.zip", old)
+
+	records := history.FileStore{Dir: t.TempDir()}
+	records.Save(context.Background(), history.Record{
+		ID:       "20261001T120000.000000000Z",
+		Platform: "live",
+		Artifact: "5e7d",
+		Regions:  []history.Region{{Region: "eu-west-1"}},
+	})
+	records.Save(context.Background(), history.Record{
+		ID:       "20260901T120000.000000000Z",
+		Platform: "live",
+		Artifact: "7a9f",
+		Regions:  []history.Region{{Region: "us-east-1"}},
+	})
+
+	env, _ := config.Environment("live")
+
+	// The stack runs an artifact no recorded deployment refers to, as when it was deployed from another machine
+	stacks := fakeInspector{env.StackName() + " eu-west-1": {"s3://artifacts-eu-west-1/api/live/9c1b.zip"}}
+
+	out := &bytes.Buffer{}
+	collector := Collector{
+		Artifacts: store,
+		History:   records,
+		Stacks:    stacks,
+		Project:   config,
+		MinAge:    DefaultMinAge,
+		Now:       func() time.Time { return now },
+		Out:       out,
+	}
+
+	err = collector.Collect(context.Background(), "live", true)
+
+	utils.AssertNoError(t, "Dry run", err)
+	utils.AssertEquals(t, "Nothing removed", 0, len(store.deleted))
+	utils.AssertTrue(t, "Dry run output", strings.Contains(out.String(), "Would remove s3://artifacts-eu-west-1/api/live/6f8e.json\n"))
+
+	err = collector.Collect(context.Background(), "live", false)
+
+	utils.AssertNoError(t, "Collect", err)
+	utils.AssertEquals(t, "Removed", strings.Join([]string{
+		"artifacts-eu-west-1/api/live/6f8e.json",
+		"artifacts-eu-west-1/api/live/6f8e.zip",
+		"artifacts-us-east-1/api/live/6f8e.zip",
+	}, "\n"), strings.Join(store.deleted, "\n"))
+
+	collector.History = history.FileStore{Dir: t.TempDir()}
+	store.deleted = nil
+
+	err = collector.Collect(context.Background(), "live", false)
+
+	utils.AssertErrorEquals(t, "Empty history",
+		"No deployments of live are recorded in the history, so its artifacts cannot be collected safely", err)
+	utils.AssertEquals(t, "Nothing removed without history", 0, len(store.deleted))
+
+	collector.History = nil
+
+	err = collector.Collect(context.Background(), "live", false)
+
+	utils.AssertErrorEquals(t, "No history", "No deployment history is configured", err)
+}
+
+func TestCollectAll(t *testing.T) {
+
+	config, err := project.Parse([]byte(historyConfig))
+
+	utils.AssertNoError(t, "History configuration", err)
+
+	now := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-72 * time.Hour)
+
+	store := newFakeArtifacts()
+
+	for _, key := range []string{"api/live/5e7d.zip", "api/test/5e7d.zip", "api/test/6f8e.zip"} {
+		store.put("artifacts-eu-west-1", key, old)
+	}
+
+	// Only test has recorded deployments: live, which sorts first, has none, as with a history missing its records
+	records := history.FileStore{Dir: t.TempDir()}
+	records.Save(context.Background(), history.Record{
+		ID:       "20261001T120000.000000000Z",
+		Platform: "test",
+		Artifact: "5e7d",
+		Regions:  []history.Region{{Region: "eu-west-1"}},
+	})
+
+	out := &bytes.Buffer{}
+	collectorFor := func(env *project.Environment) Collector {
+		return Collector{
+			Artifacts: store,
+			History:   records,
+			Stacks:    fakeInspector{},
+			Project:   config,
+			MinAge:    DefaultMinAge,
+			Now:       func() time.Time { return now },
+			Out:       out,
+		}
+	}
+
+	err = CollectAll(context.Background(), config, collectorFor, false)
+
+	utils.AssertNoError(t, "Collect all", err)
+	utils.AssertEquals(t, "Removed", "artifacts-eu-west-1/api/test/6f8e.zip", strings.Join(store.deleted, "\n"))
+	utils.AssertTrue(t, "Live skipped", strings.Contains(out.String(), "Skipping live: no deployments of it are recorded in the history\n"))
+
+	err = collectorFor(nil).Collect(context.Background(), "live", false)
+
+	utils.AssertErrorEquals(t, "Named platform without history",
+		"No deployments of live are recorded in the history, so its artifacts cannot be collected safely", err)
+}
@@ -0,0 +1,128 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/guard"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+// GitInfo describes the commit being deployed
+type GitInfo struct {
+	// The output of git describe --tags, or "untagged"
+	Tag    string
+	Branch string
+	// The commit hash shortened to 16 characters
+	Commit string
+	// The commit time, which is the build time of the lambda so that builds are reproducible
+	Time time.Time
+}
+
+// A GitGuard reports on the repository being deployed and checks whether it may be deployed to an environment
+type GitGuard interface {
+	Info(ctx context.Context) (GitInfo, error)
+	Check(ctx context.Context, env *project.Environment, info GitInfo) error
+}
+
+// CLIGitGuard is a GitGuard using the git command line
+type CLIGitGuard struct {
+	Runner command.Runner
+	// If true, the remote is not fetched before checking that HEAD is in sync with it, so that the check has no side
+	// effects, as for a plan
+	NoFetch bool
+}
+
+func (g CLIGitGuard) Info(ctx context.Context) (info GitInfo, err error) {
+
+	info.Tag = "untagged"
+
+	if out, err := g.git(ctx, "describe", "--tags"); err == nil {
+		info.Tag = trimmed(out)
+	}
+
+	out, err := g.git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+
+	if err != nil {
+		return
+	}
+
+	info.Branch = trimmed(out)
+
+	out, err = g.git(ctx, "rev-parse", "--short=16", "HEAD")
+
+	if err != nil {
+		return
+	}
+
+	info.Commit = trimmed(out)
+
+	out, err = g.git(ctx, "show", "--no-patch", "--format=%cI", "HEAD")
+
+	if err != nil {
+		return
+	}
+
+	if info.Time, err = time.Parse(time.RFC3339, trimmed(out)); err != nil {
+		err = fmt.Errorf("Cannot parse commit time: %v", err)
+	}
+
+	return
+}
+
+// Check evaluates the release policy of the environment and fails listing every rule the repository does not satisfy
+func (g CLIGitGuard) Check(ctx context.Context, env *project.Environment, info GitInfo) error {
+
+	policy, err := ReleasePolicy(env, !g.NoFetch)
+
+	if err != nil {
+		return err
+	}
+
+	return policy.Evaluate(ctx, guard.Repo{Runner: g.Runner}).Err()
+}
+
+// ReleasePolicy returns the release policy for an environment: a clean working tree always, and the branch, upstream
+// sync, release tag, signed tag and changelog rules which the environment configures. The upstream sync rule fetches
+// the remote first if fetch is true
+func ReleasePolicy(env *project.Environment, fetch bool) (guard.Policy, error) {
+
+	policy := guard.Policy{guard.CleanTree()}
+
+	if len(env.Branches) > 0 {
+		policy = append(policy, guard.BranchAllowlist(env.Branches...))
+	}
+
+	if env.RequireSync {
+		policy = append(policy, guard.UpstreamSync("origin", fetch))
+	}
+
+	if env.TagPattern == "" {
+		return policy, nil
+	}
+
+	pattern, err := regexp.Compile(env.TagPattern)
+
+	if err != nil {
+		return nil, err
+	}
+
+	policy = append(policy, guard.TagOnHead(pattern))
+
+	if env.SignedTag {
+		policy = append(policy, guard.SignedTag(pattern))
+	}
+
+	if env.Changelog != "" {
+		policy = append(policy, guard.ChangelogEntry(env.Changelog, pattern))
+	}
+
+	return policy, nil
+}
+
+func (g CLIGitGuard) git(ctx context.Context, args ...string) ([]byte, error) {
+	return g.Runner.Run(ctx, "git", args...)
+}
@@ -0,0 +1,117 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func testGitRunner() *fakeRunner {
+
+	runner := newFakeRunner()
+	runner.outputs["git describe --tags"] = "v1.0.1\n"
+	runner.outputs["git rev-parse --abbrev-ref HEAD"] = "master\n"
+	runner.outputs["git rev-parse --short=16 HEAD"] = "a00eaaf456941631\n"
+	runner.outputs["git show --no-patch --format=%cI HEAD"] = "2026-10-01T13:00:00+01:00\n"
+	runner.outputs["git tag --points-at HEAD"] = "v1.0.1\n"
+	runner.outputs["git rev-list --left-right --count HEAD...origin/master"] = "0\t0\n"
+
+	return runner
+}
+
+func TestGitInfo(t *testing.T) {
+
+	runner := testGitRunner()
+	guard := CLIGitGuard{Runner: runner}
+
+	info, err := guard.Info(context.Background())
+
+	utils.AssertNoError(t, "Git info", err)
+	utils.AssertEquals(t, "Git info", "v1.0.1 master a00eaaf456941631", info.Tag+" "+info.Branch+" "+info.Commit)
+	utils.AssertEquals(t, "Git commit time", "2026-10-01T12:00:00Z", info.Time.UTC().Format(time.RFC3339))
+
+	runner.errors["git describe --tags"] = errors.New("No names found")
+
+	info, err = guard.Info(context.Background())
+
+	utils.AssertNoError(t, "Untagged git info", err)
+	utils.AssertEquals(t, "Untagged git tag", "untagged", info.Tag)
+}
+
+func TestGitCheck(t *testing.T) {
+
+	config := project.Default()
+	live := config.Environments["live"]
+	stage := config.Environments["stage"]
+
+	info := GitInfo{Tag: "v1.0.1", Branch: "master", Commit: "a00eaaf456941631"}
+
+	runner := testGitRunner()
+	guard := CLIGitGuard{Runner: runner}
+
+	utils.AssertNoError(t, "Clean live check", guard.Check(context.Background(), live, info))
+
+	runner.outputs["git status --porcelain"] = " M api/main.go\n"
+
+	utils.AssertErrorEquals(t, "Uncommitted changes", "clean-tree: There are uncommitted changes", guard.Check(context.Background(), stage, info))
+
+	runner = testGitRunner()
+	runner.outputs["git rev-parse --abbrev-ref HEAD"] = "feature\n"
+	runner.outputs["git tag --points-at HEAD"] = ""
+	guard = CLIGitGuard{Runner: runner}
+
+	utils.AssertNoError(t, "Non-live branch", guard.Check(context.Background(), stage, info))
+	utils.AssertErrorEquals(t, "Live branch and tag",
+		"branch: Branch feature is not one of master\n"+
+			"upstream-sync: Cannot compare with origin/feature: unexpected output \"\"\n"+
+			`release-tag: HEAD is not tagged to match ^v[0-9]+\.[0-9]+\.[0-9]+$`,
+		guard.Check(context.Background(), live, info))
+
+	runner = testGitRunner()
+	runner.outputs["git rev-list --left-right --count HEAD...origin/master"] = "2\t0\n"
+	guard = CLIGitGuard{Runner: runner}
+
+	utils.AssertErrorEquals(t, "Live sync", "upstream-sync: Not in sync with origin/master: 2 ahead, 0 behind",
+		guard.Check(context.Background(), live, info))
+	utils.AssertTrue(t, "Fetched origin", strings.Contains(strings.Join(runner.calls, "\n"), "git fetch origin\n"))
+
+	runner = testGitRunner()
+	guard = CLIGitGuard{Runner: runner, NoFetch: true}
+
+	utils.AssertNoError(t, "Live check without fetching", guard.Check(context.Background(), live, info))
+	utils.AssertFalse(t, "Did not fetch origin", strings.Contains(strings.Join(runner.calls, "\n"), "git fetch"))
+}
+
+func TestReleasePolicy(t *testing.T) {
+
+	env := &project.Environment{
+		Name:        "live",
+		Branches:    []string{"master", "release/*"},
+		TagPattern:  `^v[0-9]+\.[0-9]+\.[0-9]+$`,
+		SignedTag:   true,
+		Changelog:   "CHANGELOG.md",
+		RequireSync: true,
+	}
+
+	policy, err := ReleasePolicy(env, true)
+
+	utils.AssertNoError(t, "Release policy", err)
+
+	var names []string
+
+	for _, rule := range policy {
+		names = append(names, rule.Name())
+	}
+
+	utils.AssertEquals(t, "Rule names", "clean-tree branch upstream-sync release-tag signed-tag changelog", strings.Join(names, " "))
+
+	policy, err = ReleasePolicy(&project.Environment{Name: "test"}, true)
+
+	utils.AssertNoError(t, "Minimal release policy", err)
+	utils.AssertEquals(t, "Minimal rule count", 1, len(policy))
+}
@@ -0,0 +1,237 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+)
+
+// A Packager tests and builds the lambda artifact once per deployment, and packages the CloudFormation template with
+// the artifact for each region deployed to
+type Packager interface {
+	// Test runs the tests of the module
+	Test(ctx context.Context) error
+	// Build builds the lambda artifact. The git information is compiled into the lambda as its build information
+	Build(ctx context.Context, info GitInfo) (artifact.Artifact, error)
+	// Package uploads the artifact to the region, and returns the path of the template deploying it and a function to
+	// remove anything created for it. An empty region means the AWS CLI default region. Packages for different regions
+	// may be created concurrently
+	Package(ctx context.Context, template, region string, code artifact.Artifact) (packaged string, cleanup func(), err error)
+}
+
+// CLIPackager is a Packager using the go and aws command lines. It uploads the artifact to the artifact bucket of the
+// region. Artifacts are named by their SHA-256 digest, so one already in the bucket is not uploaded again
+type CLIPackager struct {
+	Runner command.Runner
+	// The path of the lambda executable in the artifact, which must match the template's Handler
+	Executable string
+	// The main package of the lambda
+	Main string
+	// The target architecture: amd64 (the default) or arm64
+	GOARCH string
+	// Build tags, such as artifact.NoRPCTag
+	Tags []string
+	// The directory artifacts are built in
+	Dir string
+	// The bucket artifacts are kept in
+	Artifacts ArtifactStore
+	// The key prefix of the artifacts of the platform, from project.Config.ArtifactPrefix
+	Prefix string
+}
+
+func (packager CLIPackager) Test(ctx context.Context) error {
+
+	if _, err := packager.Runner.Run(ctx, "go", "mod", "download"); err != nil {
+		return err
+	}
+
+	if _, err := packager.Runner.Run(ctx, "go", "test", "./..."); err != nil {
+		return errors.New("Tests failed: " + err.Error())
+	}
+
+	return nil
+}
+
+func (packager CLIPackager) Build(ctx context.Context, info GitInfo) (artifact.Artifact, error) {
+
+	builder := artifact.Builder{
+		Runner:     packager.Runner,
+		Main:       packager.Main,
+		Executable: packager.Executable,
+		GOARCH:     packager.GOARCH,
+		Tags:       packager.Tags,
+		Dir:        packager.Dir,
+	}
+
+	return builder.Build(ctx, artifact.Source{Release: info.Tag, Branch: info.Branch, Commit: info.Commit, CommitTime: info.Time})
+}
+
+func (packager CLIPackager) Package(ctx context.Context, template, region string, code artifact.Artifact) (packaged string, cleanup func(), err error) {
+
+	cleanup = func() {}
+
+	bucket, err := packager.Artifacts.EnsureBucket(ctx, region)
+
+	if err != nil {
+		return
+	}
+
+	key := packager.Prefix + code.Manifest.Name
+
+	if err = packager.upload(ctx, bucket, key, region, code); err != nil {
+		return
+	}
+
+	raw, err := ioutil.ReadFile(template)
+
+	if err != nil {
+		return
+	}
+
+	if raw, err = setCodeUri(raw, "s3://"+bucket+"/"+key); err != nil {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "deploy")
+
+	if err != nil {
+		return
+	}
+
+	cleanup = func() {
+		os.RemoveAll(dir)
+	}
+
+	packaged = filepath.Join(dir, "packaged.yaml")
+	err = ioutil.WriteFile(packaged, raw, 0644)
+
+	return
+}
+
+// Uploads the artifact and its manifest unless the bucket already has them
+func (packager CLIPackager) upload(ctx context.Context, bucket, key, region string, code artifact.Artifact) error {
+
+	exists, err := packager.Artifacts.Exists(ctx, bucket, key, region)
+
+	if err != nil || exists {
+		return err
+	}
+
+	// The manifest goes first, so that an artifact is never without one
+	if err := packager.Artifacts.Upload(ctx, code.ManifestPath, bucket, packager.Prefix+code.Manifest.SHA256+".json", region); err != nil {
+		return err
+	}
+
+	return packager.Artifacts.Upload(ctx, code.Path, bucket, key, region)
+}
+
+// Sets the CodeUri of every serverless function in a template, keeping the CloudFormation intrinsic function tags
+func setCodeUri(template []byte, uri string) ([]byte, error) {
+
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(template, &doc); err != nil {
+		return nil, errors.New("Invalid template: " + err.Error())
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, errors.New("Invalid template: it is empty")
+	}
+
+	found := false
+	resources := mappingValue(doc.Content[0], "Resources")
+
+	for i := 1; resources != nil && i < len(resources.Content); i += 2 {
+
+		resource := resources.Content[i]
+
+		if kind := mappingValue(resource, "Type"); kind == nil || kind.Value != "AWS::Serverless::Function" {
+			continue
+		}
+
+		properties := mappingValue(resource, "Properties")
+
+		if properties == nil {
+			properties = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			resource.Content = append(resource.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "Properties"}, properties)
+		}
+
+		if codeUri := mappingValue(properties, "CodeUri"); codeUri != nil {
+			*codeUri = yaml.Node{Kind: yaml.ScalarNode, Value: uri}
+		} else {
+			properties.Content = append(properties.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "CodeUri"}, &yaml.Node{Kind: yaml.ScalarNode, Value: uri})
+		}
+
+		found = true
+	}
+
+	if !found {
+		return nil, errors.New("Invalid template: it has no AWS::Serverless::Function resource")
+	}
+
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), encoder.Close()
+}
+
+// Returns the CodeUri of every serverless function in a template which sets one to a string
+func codeUris(template []byte) ([]string, error) {
+
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(template, &doc); err != nil {
+		return nil, errors.New("Invalid template: " + err.Error())
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var uris []string
+	resources := mappingValue(doc.Content[0], "Resources")
+
+	for i := 1; resources != nil && i < len(resources.Content); i += 2 {
+
+		resource := resources.Content[i]
+
+		if kind := mappingValue(resource, "Type"); kind == nil || kind.Value != "AWS::Serverless::Function" {
+			continue
+		}
+
+		if codeUri := mappingValue(mappingValue(resource, "Properties"), "CodeUri"); codeUri != nil && codeUri.Kind == yaml.ScalarNode {
+			uris = append(uris, codeUri.Value)
+		}
+	}
+
+	return uris, nil
+}
+
+// Returns the value of a key in a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/artifact"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+var testArtifact = artifact.Artifact{
+	Path:         "bin/5e7d.zip",
+	ManifestPath: "bin/5e7d.json",
+	Manifest:     artifact.Manifest{Name: "5e7d.zip", SHA256: "5e7d"},
+}
+
+func TestPackage(t *testing.T) {
+
+	store := newFakeArtifacts()
+	packager := CLIPackager{Runner: newFakeRunner(), Artifacts: store, Prefix: "api/live/"}
+
+	packaged, cleanup, err := packager.Package(context.Background(), filepath.Join("..", "..", "api.yaml"), "eu-west-1", testArtifact)
+
+	utils.AssertNoError(t, "Package", err)
+	utils.AssertEquals(t, "Uploads", strings.Join([]string{
+		"bin/5e7d.json artifacts-eu-west-1/api/live/5e7d.json",
+		"bin/5e7d.zip artifacts-eu-west-1/api/live/5e7d.zip",
+	}, "\n"), strings.Join(store.uploads, "\n"))
+
+	raw, err := ioutil.ReadFile(packaged)
+
+	utils.AssertNoError(t, "Read packaged template", err)
+	utils.AssertTrue(t, "CodeUri", strings.Contains(string(raw), "      CodeUri: s3://artifacts-eu-west-1/api/live/5e7d.zip\n"))
+	utils.AssertTrue(t, "Intrinsic functions kept", strings.Contains(string(raw), "FunctionName: !Sub ${ApiLambdaNameBase}-${Platform}\n"))
+
+	cleanup()
+
+	_, err = os.Stat(packaged)
+
+	utils.AssertTrue(t, "Cleanup removes template", os.IsNotExist(err))
+
+	_, cleanup, err = packager.Package(context.Background(), filepath.Join("..", "..", "api.yaml"), "eu-west-1", testArtifact)
+	cleanup()
+
+	utils.AssertNoError(t, "Package unchanged", err)
+	utils.AssertEquals(t, "Unchanged artifact not uploaded", 2, len(store.uploads))
+}
+
+func TestSetCodeUri(t *testing.T) {
+
+	raw, err := setCodeUri([]byte(`
+Resources:
+  Function:
+    Type: AWS::Serverless::Function
+    Properties:
+      CodeUri: .
+      Handler: bootstrap
+  Bucket:
+    Type: AWS::S3::Bucket
+`), "s3://bucket/lambda/5e7d.zip")
+
+	utils.AssertNoError(t, "Set CodeUri", err)
+	utils.AssertTrue(t, "CodeUri replaced", strings.Contains(string(raw), "      CodeUri: s3://bucket/lambda/5e7d.zip\n      Handler: bootstrap\n"))
+
+	_, err = setCodeUri([]byte("Resources: {}"), "s3://bucket/lambda/5e7d.zip")
+
+	utils.AssertErrorEquals(t, "No function", "Invalid template: it has no AWS::Serverless::Function resource", err)
+}
@@ -0,0 +1,305 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/guard"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+)
+
+// Plan is everything resolved for a deployment before anything is built or changed
+type Plan struct {
+	SubdomainBase string  `json:"subdomainBase"`
+	Domain        string  `json:"domain"`
+	Platform      string  `json:"platform"`
+	CustomDomain  string  `json:"customDomain"`
+	HostedZoneID  string  `json:"hostedZoneId"`
+	Git           GitInfo `json:"git"`
+	StackName     string  `json:"stackName"`
+	Template      string  `json:"template"`
+	Profile       string  `json:"profile,omitempty"`
+	Confirm       bool    `json:"confirm"`
+	Endpoint      string  `json:"endpoint"`
+	Routing       string  `json:"routing"`
+	// If true, the regions are deployed in parallel rather than in sequence
+	Parallel bool `json:"parallel"`
+	// The key prefix of the lambda artifacts in the artifact bucket of each region
+	ArtifactPrefix string       `json:"artifactPrefix"`
+	Regions        []RegionPlan `json:"regions"`
+	// Every reason the deployment would fail, such as git checks or unresolved domains
+	Problems []string `json:"problems"`
+}
+
+// RegionPlan is everything resolved for deploying to one region
+type RegionPlan struct {
+	// The region, or empty for the AWS CLI default region
+	Region         string            `json:"region"`
+	CertificateArn string            `json:"certificateArn"`
+	Parameters     map[string]string `json:"parameters"`
+	// The stack changes the deployment would make, if a ChangeSetClient was supplied
+	Changes []ResourceChange `json:"changes,omitempty"`
+}
+
+// ResourceChange is a change to a stack resource reported by a CloudFormation change set
+type ResourceChange struct {
+	Action       string `json:"action"`
+	LogicalID    string `json:"logicalId"`
+	ResourceType string `json:"resourceType"`
+	Replacement  string `json:"replacement,omitempty"`
+}
+
+// A ChangeSetClient reports the changes that deploying a template with parameters would make to a stack in a region
+type ChangeSetClient interface {
+	Changes(ctx context.Context, template, stackName, region string, parameters map[string]string) ([]ResourceChange, error)
+}
+
+// Plan resolves every input of a deployment to an environment without building or changing anything. Failed checks and lookups are
+// reported as problems in the plan rather than as an error. If the deployer has a ChangeSetClient and there are no
+// problems, the plan includes the stack changes in each region
+func (deployer Deployer) Plan(ctx context.Context, request Request) (plan Plan, err error) {
+
+	env, err := ResolveRequest(deployer.Project, request)
+
+	if err != nil {
+		return
+	}
+
+	plan = Plan{
+		SubdomainBase:  request.SubdomainBase,
+		Domain:         request.Domain,
+		Platform:       env.Name,
+		CustomDomain:   CustomDomain(env, request),
+		StackName:      env.StackName(),
+		Template:       deployer.Project.Template,
+		Profile:        env.Profile,
+		Confirm:        env.Confirm,
+		Endpoint:       env.Endpoint,
+		Routing:        env.Routing,
+		Parallel:       env.Parallel,
+		ArtifactPrefix: deployer.Project.ArtifactPrefix(env.Name),
+		Problems:       []string{},
+	}
+
+	plan.Git, err = deployer.Git.Info(ctx)
+
+	if err != nil {
+		return
+	}
+
+	if err := deployer.Git.Check(ctx, env, plan.Git); err != nil {
+		plan.Problems = append(plan.Problems, problems(err)...)
+	}
+
+	if plan.HostedZoneID, err = deployer.Domains.HostedZoneID(ctx, request.Domain); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+
+	err = nil
+
+	for _, target := range env.Targets() {
+		plan.Regions = append(plan.Regions, deployer.planRegion(ctx, env, target, &plan))
+	}
+
+	if deployer.ChangeSets == nil || len(plan.Problems) > 0 {
+		return
+	}
+
+	for i, region := range plan.Regions {
+		if plan.Regions[i].Changes, err = deployer.ChangeSets.Changes(ctx, plan.Template, plan.StackName, region.Region, region.Parameters); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Resolves the certificate and parameters for a region, adding any problem to the plan
+func (deployer Deployer) planRegion(ctx context.Context, env *project.Environment, target project.Region, plan *Plan) RegionPlan {
+
+	region := RegionPlan{Region: target.Name, Parameters: map[string]string{}}
+
+	certificateRegion := EdgeCertificateRegion
+
+	if env.Endpoint == project.EndpointRegional {
+		certificateRegion = target.Name
+	}
+
+	var err error
+
+	if region.CertificateArn, err = deployer.Certificates.CertificateArn(ctx, plan.CustomDomain, plan.Domain, certificateRegion); err != nil {
+		plan.Problems = append(plan.Problems, err.Error())
+	}
+
+	for _, parameters := range []map[string]string{env.Parameters, target.Parameters} {
+		for k, v := range parameters {
+			region.Parameters[k] = v
+		}
+	}
+
+	for k, v := range map[string]string{
+		"Platform":       env.Name,
+		"Commit":         plan.Git.Commit,
+		"CustomDomain":   plan.CustomDomain,
+		"HostedZone":     plan.HostedZoneID,
+		"Release":        plan.Git.Tag,
+		"Branch":         plan.Git.Branch,
+		"BuildTime":      plan.Git.Time.UTC().Format(time.RFC3339),
+		"CertificateArn": region.CertificateArn,
+		"EndpointType":   strings.ToUpper(env.Endpoint),
+		"RoutingPolicy":  strings.ToUpper(env.Routing),
+		"Runtime":        env.Runtime,
+		"Architecture":   env.Architecture,
+	} {
+		region.Parameters[k] = v
+	}
+
+	if env.Routing == project.RoutingFailover {
+		region.Parameters["FailoverRole"] = strings.ToUpper(target.Failover)
+	}
+
+	return region
+}
+
+// problems splits a release policy error into one problem per failing rule
+func problems(err error) []string {
+
+	policyErr, ok := err.(guard.PolicyError)
+
+	if !ok {
+		return []string{err.Error()}
+	}
+
+	list := make([]string, len(policyErr))
+
+	for i, result := range policyErr {
+		list[i] = result.Rule + ": " + result.Err.Error()
+	}
+
+	return list
+}
+
+// WriteJSON writes the plan as indented JSON
+func (plan Plan) WriteJSON(w io.Writer) error {
+
+	raw, err := json.MarshalIndent(plan, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(raw))
+
+	return err
+}
+
+// WriteText writes the plan in a human-readable form
+func (plan Plan) WriteText(w io.Writer) error {
+
+	ew := &errWriter{w: w}
+
+	ew.printf("Deployment plan for %v\n\n", plan.CustomDomain)
+	ew.printf("  Platform:         %v\n", plan.Platform)
+	ew.printf("  Stack:            %v\n", plan.StackName)
+	ew.printf("  Template:         %v\n", plan.Template)
+	ew.printf("  Profile:          %v\n", dash(plan.Profile))
+	ew.printf("  Endpoint:         %v\n", plan.Endpoint)
+	ew.printf("  Routing:          %v\n", plan.Routing)
+	ew.printf("  Artifact prefix:  %v\n", plan.ArtifactPrefix)
+	ew.printf("  Hosted zone ID:   %v\n", dash(plan.HostedZoneID))
+	ew.printf("  Git tag:          %v\n", plan.Git.Tag)
+	ew.printf("  Git branch:       %v\n", plan.Git.Branch)
+	ew.printf("  Git commit:       %v\n", plan.Git.Commit)
+
+	if len(plan.Regions) > 1 {
+		if plan.Parallel {
+			ew.printf("\nRegions are deployed in parallel\n")
+		} else {
+			ew.printf("\nRegions are deployed in sequence\n")
+		}
+	}
+
+	for _, region := range plan.Regions {
+		region.writeText(ew)
+	}
+
+	if len(plan.Problems) > 0 {
+
+		ew.printf("\nProblems:\n\n")
+
+		for _, problem := range plan.Problems {
+			ew.printf("  %v\n", problem)
+		}
+	}
+
+	return ew.err
+}
+
+func (region RegionPlan) writeText(ew *errWriter) {
+
+	ew.printf("\nRegion %v:\n\n", regionName(region.Region))
+	ew.printf("  Certificate ARN:  %v\n", dash(region.CertificateArn))
+	ew.printf("\n  Parameter overrides:\n\n")
+
+	for _, key := range sortedKeys(region.Parameters) {
+		ew.printf("    %v=%v\n", key, region.Parameters[key])
+	}
+
+	if region.Changes == nil {
+		return
+	}
+
+	ew.printf("\n  Stack changes:\n\n")
+
+	if len(region.Changes) == 0 {
+		ew.printf("    No changes\n")
+	}
+
+	for _, change := range region.Changes {
+
+		symbol := map[string]string{"Add": "+", "Remove": "-", "Modify": "~"}[change.Action]
+
+		ew.printf("    %v %v (%v)", dash(symbol), change.LogicalID, change.ResourceType)
+
+		if change.Replacement == "True" || change.Replacement == "Conditional" {
+			ew.printf(" replacement: %v", change.Replacement)
+		}
+
+		ew.printf("\n")
+	}
+}
+
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, a ...interface{}) {
+
+	if ew.err == nil {
+		_, ew.err = fmt.Fprintf(ew.w, format, a...)
+	}
+}
+
+func dash(s string) string {
+
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// Returns the name of a region for messages, which is "default" for the AWS CLI default region
+func regionName(region string) string {
+
+	if region == "" {
+		return "default"
+	}
+
+	return region
+}
@@ -0,0 +1,95 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+type fakeChangeSets struct {
+	changes    []ResourceChange
+	parameters map[string]string
+}
+
+func (changeSets *fakeChangeSets) Changes(ctx context.Context, template, stackName, region string, parameters map[string]string) ([]ResourceChange, error) {
+
+	changeSets.parameters = parameters
+
+	return changeSets.changes, nil
+}
+
+func TestPlan(t *testing.T) {
+
+	deployer, packager, stacks := testDeployer(t, true)
+
+	changeSets := &fakeChangeSets{
+		changes: []ResourceChange{
+			{Action: "Modify", LogicalID: "ApiLambdaFunction", ResourceType: "AWS::Lambda::Function", Replacement: "False"},
+			{Action: "Add", LogicalID: "ApiRecordSet", ResourceType: "AWS::Route53::RecordSet"},
+		},
+	}
+
+	deployer.ChangeSets = changeSets
+
+	plan, err := deployer.Plan(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: "live"})
+
+	utils.AssertNoError(t, "Plan", err)
+	utils.AssertFalse(t, "Plan does not package", packager.packaged)
+	utils.AssertEquals(t, "Plan does not deploy", "", stacks.stackName)
+	utils.AssertEquals(t, "Plan custom domain", "api.example.com", plan.CustomDomain)
+	utils.AssertEquals(t, "Plan region count", 1, len(plan.Regions))
+	utils.AssertEquals(t, "Plan certificate", "arn:live", plan.Regions[0].CertificateArn)
+	utils.AssertEquals(t, "Plan stack name", "api-stack-live", plan.StackName)
+	utils.AssertEquals(t, "Plan artifact prefix", "api/live/", plan.ArtifactPrefix)
+	utils.AssertEquals(t, "Plan problems", 0, len(plan.Problems))
+	utils.AssertEquals(t, "Change set parameters", "Z123", changeSets.parameters["HostedZone"])
+
+	buf := &bytes.Buffer{}
+	err = plan.WriteText(buf)
+
+	utils.AssertNoError(t, "Plan text", err)
+	utils.AssertTrue(t, "Plan text parameters", strings.Contains(buf.String(), "    CertificateArn=arn:live\n"))
+	utils.AssertTrue(t, "Plan text modification", strings.Contains(buf.String(), "    ~ ApiLambdaFunction (AWS::Lambda::Function)\n"))
+	utils.AssertTrue(t, "Plan text addition", strings.Contains(buf.String(), "    + ApiRecordSet (AWS::Route53::RecordSet)\n"))
+
+	buf.Reset()
+	err = plan.WriteJSON(buf)
+
+	utils.AssertNoError(t, "Plan JSON", err)
+
+	parsed := Plan{}
+	err = json.Unmarshal(buf.Bytes(), &parsed)
+
+	utils.AssertNoError(t, "Plan JSON parses", err)
+	utils.AssertEquals(t, "Plan JSON git tag", "v1.0.1", parsed.Git.Tag)
+	utils.AssertEquals(t, "Plan JSON release parameter", "v1.0.1", parsed.Regions[0].Parameters["Release"])
+	utils.AssertEquals(t, "Plan JSON changes", 2, len(parsed.Regions[0].Changes))
+}
+
+func TestPlanProblems(t *testing.T) {
+
+	deployer, _, _ := testDeployer(t, true)
+
+	changeSets := &fakeChangeSets{}
+
+	deployer.Git = fakeGit{checkErr: errors.New("There are uncommitted changes")}
+	deployer.ChangeSets = changeSets
+
+	plan, err := deployer.Plan(context.Background(), Request{SubdomainBase: "api", Domain: "example.org"})
+
+	utils.AssertNoError(t, "Plan with problems", err)
+	utils.AssertEquals(t, "Plan problem count", 3, len(plan.Problems))
+	utils.AssertEquals(t, "Plan git problem", "There are uncommitted changes", plan.Problems[0])
+	utils.AssertTrue(t, "No change set with problems", changeSets.parameters == nil)
+
+	buf := &bytes.Buffer{}
+	plan.WriteText(buf)
+
+	utils.AssertTrue(t, "Plan text problems", strings.Contains(buf.String(), "Problems:\n\n  There are uncommitted changes\n"))
+	utils.AssertTrue(t, "Plan text unresolved zone", strings.Contains(buf.String(), "  Hosted zone ID:   -\n"))
+}
@@ -0,0 +1,112 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+)
+
+// Rollback redeploys an earlier recorded deployment to a platform, or to the default platform if it is empty, without
+// rebuilding. If id is empty, it is the most recent deployment of a commit other than the current one; otherwise it
+// is the deployment with that ID. Regions are redeployed in sequence, stopping at the first which fails. The rollback
+// is itself recorded, and so can be rolled back
+func (deployer Deployer) Rollback(ctx context.Context, platform, id string) error {
+
+	env, err := deployer.Project.Environment(platform)
+
+	if err != nil {
+		return err
+	}
+
+	if deployer.History == nil {
+		return errors.New("No deployment history is configured")
+	}
+
+	records, err := deployer.History.List(ctx, env.Name)
+
+	if err != nil {
+		return err
+	}
+
+	target, err := history.Previous(records, id)
+
+	if err != nil {
+		return err
+	}
+
+	if !target.CanRollback() {
+		return fmt.Errorf("The artifacts of deployment %v of %v were not retained, so it cannot be redeployed", target.ID, target.Release)
+	}
+
+	prompt := fmt.Sprintf("About to roll back %v from %v to %v. Confirm?", env.Name, records[0].Release, target.Release)
+
+	if env.Confirm && (deployer.Confirm == nil || !deployer.Confirm(prompt)) {
+		return errors.New("Cancelling rollback")
+	}
+
+	deployer.printf("Rolling back %v to %v (%v), deployed at %v\n", env.Name, target.Release, target.Commit, target.Time.Format("2006-01-02 15:04:05 MST"))
+
+	dir, err := ioutil.TempDir("", "rollback")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dir)
+
+	var results []RegionResult
+
+	for _, region := range target.Regions {
+
+		result := RegionResult{Region: region.Region}
+		result.Deployed, result.Err = deployer.redeployRegion(ctx, target.StackName, region, dir)
+		results = append(results, result)
+
+		if result.Err != nil {
+			break
+		}
+	}
+
+	recordErr := deployer.record(ctx, history.Record{
+		Platform:   target.Platform,
+		Release:    target.Release,
+		Commit:     target.Commit,
+		Branch:     target.Branch,
+		StackName:  target.StackName,
+		Artifact:   target.Artifact,
+		RollbackOf: target.ID,
+	}, results)
+
+	if err := results[len(results)-1].Err; err != nil {
+		return fmt.Errorf("%v: %v", regionName(results[len(results)-1].Region), err)
+	}
+
+	return recordErr
+}
+
+// Deploys the stack in a region from a recorded packaged template
+func (deployer Deployer) redeployRegion(ctx context.Context, stackName string, region history.Region, dir string) (history.Region, error) {
+
+	packaged := filepath.Join(dir, "packaged-"+regionName(region.Region)+".yaml")
+
+	if err := ioutil.WriteFile(packaged, []byte(region.Template), 0644); err != nil {
+		return region, err
+	}
+
+	deployer.printf("Deploying %v stack in region %v\n", stackName, regionName(region.Region))
+
+	if err := deployer.Stacks.Deploy(ctx, packaged, stackName, region.Region, region.Parameters); err != nil {
+		return region, err
+	}
+
+	var err error
+
+	region.Outputs, err = deployer.Stacks.Outputs(ctx, stackName, region.Region)
+
+	return region, err
+}
@@ -0,0 +1,168 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/history"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/project"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const historyConfig = `
+defaultEnvironment: test
+environments:
+  test:
+    region: eu-west-1
+  live:
+    subdomain: "{base}"
+    confirm: true
+    region: eu-west-1
+  stage:
+    region: eu-west-1
+`
+
+func historyDeployer(t *testing.T, confirmed bool) (Deployer, *fakePackager, *fakeStacks, history.Store) {
+
+	deployer, packager, stacks := testDeployer(t, confirmed)
+
+	config, err := project.Parse([]byte(historyConfig))
+
+	utils.AssertNoError(t, "History configuration", err)
+
+	store := history.FileStore{Dir: t.TempDir()}
+
+	deployer.Project = config
+	deployer.Certificates = fakeCertificates{"api-test.example.com": "arn:test", "api-stage.example.com": "arn:stage", "api.example.com": "arn:live"}
+	deployer.History = store
+	deployer.User = "deployer"
+
+	return deployer, packager, stacks, store
+}
+
+func deployCommit(t *testing.T, deployer *Deployer, platform, tag, commit string) {
+
+	deployer.Git = fakeGit{info: GitInfo{Tag: tag, Branch: "master", Commit: commit}}
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com", Platform: platform})
+
+	utils.AssertNoError(t, "Deploy "+tag, err)
+}
+
+func TestDeployRecordsHistory(t *testing.T) {
+
+	deployer, _, _, store := historyDeployer(t, true)
+
+	deployCommit(t, &deployer, "test", "v1.0.1", "a00eaaf456941631")
+
+	records, err := store.List(context.Background(), "test")
+
+	utils.AssertNoError(t, "List history", err)
+	utils.AssertEquals(t, "Record count", 1, len(records))
+
+	record := records[0]
+
+	utils.AssertEquals(t, "Recorded release", "v1.0.1", record.Release)
+	utils.AssertEquals(t, "Recorded commit", "a00eaaf456941631", record.Commit)
+	utils.AssertEquals(t, "Recorded user", "deployer", record.User)
+	utils.AssertEquals(t, "Recorded stack", "api-stack-test", record.StackName)
+	utils.AssertEquals(t, "Recorded region", "eu-west-1", record.Regions[0].Region)
+	utils.AssertEquals(t, "Recorded parameter", "arn:test", record.Regions[0].Parameters["CertificateArn"])
+	utils.AssertEquals(t, "Recorded output", "https://eu-west-1.example.com/", record.Regions[0].Outputs["ApiUrl"])
+	utils.AssertEquals(t, "Recorded template", "CodeUri: s3://artifacts-eu-west-1/a00eaaf456941631", record.Regions[0].Template)
+	utils.AssertEquals(t, "Recorded artifact", "5e7d", record.Artifact)
+	utils.AssertTrue(t, "Can roll back", record.CanRollback())
+}
+
+func TestDeployFailureNotRecorded(t *testing.T) {
+
+	deployer, _, stacks, store := historyDeployer(t, true)
+	stacks.err = map[string]error{"eu-west-1": errors.New("Stack rolled back")}
+
+	err := deployer.Deploy(context.Background(), Request{SubdomainBase: "api", Domain: "example.com"})
+
+	utils.AssertErrorEquals(t, "Failed deploy", "Stack rolled back", err)
+
+	records, _ := store.List(context.Background(), "test")
+
+	utils.AssertEquals(t, "Nothing recorded", 0, len(records))
+}
+
+func TestRollback(t *testing.T) {
+
+	deployer, _, stacks, store := historyDeployer(t, true)
+
+	err := deployer.Rollback(context.Background(), "", "")
+
+	utils.AssertErrorEquals(t, "Nothing recorded", "No deployments have been recorded", err)
+
+	deployCommit(t, &deployer, "test", "v1.0.1", "a00eaaf456941631")
+	deployCommit(t, &deployer, "test", "v1.0.2", "b11fbbf567a52742")
+
+	err = deployer.Rollback(context.Background(), "", "")
+
+	utils.AssertNoError(t, "Rollback", err)
+	utils.AssertEquals(t, "Rolled back release", "v1.0.1", stacks.parameters["Release"])
+	utils.AssertEquals(t, "Rolled back stack", "api-stack-test", stacks.stackName)
+
+	records, _ := store.List(context.Background(), "test")
+
+	utils.AssertEquals(t, "Rollback recorded", 3, len(records))
+	utils.AssertEquals(t, "Rollback record release", "v1.0.1", records[0].Release)
+	utils.AssertEquals(t, "Rollback record target", records[2].ID, records[0].RollbackOf)
+	utils.AssertTrue(t, "Rollback can be rolled back", records[0].CanRollback())
+
+	err = deployer.Rollback(context.Background(), "test", "")
+
+	utils.AssertNoError(t, "Roll back the rollback", err)
+	utils.AssertEquals(t, "Rolled forward release", "v1.0.2", stacks.parameters["Release"])
+
+	err = deployer.Rollback(context.Background(), "test", records[2].ID)
+
+	utils.AssertNoError(t, "Rollback by ID", err)
+	utils.AssertEquals(t, "Release by ID", "v1.0.1", stacks.parameters["Release"])
+
+	err = deployer.Rollback(context.Background(), "prod", "")
+
+	utils.AssertErrorEquals(t, "Unknown platform", "Platform must be one of live, stage, test", err)
+}
+
+func TestRollbackRefused(t *testing.T) {
+
+	deployer, _, stacks, store := historyDeployer(t, false)
+
+	// Deployments recorded while artifacts were kept in temporary buckets have no template
+	store.Save(context.Background(), history.Record{
+		ID:       "20260901T120000.000000000Z",
+		Platform: "stage",
+		Release:  "v1.0.1",
+		Commit:   "a00eaaf456941631",
+		Regions:  []history.Region{{Region: "eu-west-1"}},
+	})
+
+	deployCommit(t, &deployer, "stage", "v1.0.2", "b11fbbf567a52742")
+
+	err := deployer.Rollback(context.Background(), "stage", "")
+
+	utils.AssertTrue(t, "Temporary artifacts", err != nil && strings.HasPrefix(err.Error(), "The artifacts of deployment"))
+
+	deployer.Confirm = func(prompt string) bool { return true }
+
+	deployCommit(t, &deployer, "live", "v1.0.1", "a00eaaf456941631")
+	deployCommit(t, &deployer, "live", "v1.0.2", "b11fbbf567a52742")
+
+	deployer.Confirm = func(prompt string) bool { return false }
+
+	err = deployer.Rollback(context.Background(), "live", "")
+
+	utils.AssertErrorEquals(t, "Unconfirmed rollback", "Cancelling rollback", err)
+	utils.AssertEquals(t, "Not rolled back", "v1.0.2", stacks.parameters["Release"])
+
+	deployer.History = nil
+
+	err = deployer.Rollback(context.Background(), "live", "")
+
+	utils.AssertErrorEquals(t, "No history", "No deployment history is configured", err)
+}
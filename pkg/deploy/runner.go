@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+)
+
+// CheckPrerequisites returns an error naming every command which cannot be found on the path
+func CheckPrerequisites(commands ...string) error {
+
+	var missing []string
+
+	for _, command := range commands {
+		if _, err := exec.LookPath(command); err != nil {
+			missing = append(missing, command)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%v required to deploy but not found", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// CurrentUser returns the name of the user running the command, for the deployment history
+func CurrentUser() string {
+
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+
+	return os.Getenv("USER")
+}
+
+func trimmed(out []byte) string {
+	return strings.TrimSpace(string(out))
+}
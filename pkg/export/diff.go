@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The number of unchanged lines shown around changes
+const diffContext = 2
+
+// Diff returns the differences between two texts line by line, with unchanged lines near the changes for context, or
+// empty if they are the same. Removed lines start with - and added lines with +
+func Diff(oldName, newName, old, new string) string {
+
+	if old == new {
+		return ""
+	}
+
+	a := strings.Split(strings.TrimSuffix(old, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(new, "\n"), "\n")
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int, len(a)+1)
+
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+		// The line number in the old text
+		number int
+	}
+
+	var lines []line
+
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i], i + 1})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, line{'-', a[i], i + 1})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j], i + 1})
+			j++
+		}
+	}
+
+	// A line is shown if it is a change or is within the context of one
+	shown := make([]bool, len(lines))
+
+	for k, l := range lines {
+		if l.op != ' ' {
+			for c := k - diffContext; c <= k+diffContext; c++ {
+				if c >= 0 && c < len(lines) {
+					shown[c] = true
+				}
+			}
+		}
+	}
+
+	buf := &strings.Builder{}
+
+	fmt.Fprintf(buf, "--- %v\n+++ %v\n", oldName, newName)
+
+	for k, l := range lines {
+
+		if !shown[k] {
+			continue
+		}
+
+		if k == 0 || !shown[k-1] {
+			fmt.Fprintf(buf, "@@ line %v @@\n", l.number)
+		}
+
+		fmt.Fprintf(buf, "%c%v\n", l.op, l.text)
+	}
+
+	return buf.String()
+}
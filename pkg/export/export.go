@@ -0,0 +1,140 @@
+// The export package exports the Swagger definition of a deployed API, converts it to OpenAPI and generates Go
+// models from it, so that the models in the code can be checked against the API
+package export
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
+)
+
+// The name of the REST API of a platform, which is the title the template gives it
+func ApiName(platform string) string {
+	return "Sample-API-" + platform
+}
+
+// Exporter exports the Swagger definition of the REST API of a platform, and generates models from it to compare with
+// those in the code
+type Exporter struct {
+	Gateway Gateway
+	// The directory the definitions are written to
+	Dir string
+	// The version of the OpenAPI definition converted from the Swagger definition, by default swagger.OpenAPIVersion
+	OpenAPIVersion string
+	// The Go file of the models generated from the API
+	Models string
+	// The package of the models
+	Package string
+}
+
+// Result is an exported API definition and the models generated from it
+type Result struct {
+	// The paths of the JSON and YAML Swagger definitions
+	JSON string
+	YAML string
+	// The path of the OpenAPI definition
+	OpenAPI string
+	// The generated models
+	Models []byte
+	// The differences between the models file and the generated models, or empty if they are the same
+	Diff string
+}
+
+// Drifted returns whether the models file differs from the models of the API
+func (result Result) Drifted() bool {
+	return result.Diff != ""
+}
+
+// Export exports the Swagger definition of the platform's API in JSON and YAML, converts it to OpenAPI in JSON, and
+// generates models from it
+func (exporter Exporter) Export(ctx context.Context, platform string) (Result, error) {
+
+	var result Result
+
+	id, err := exporter.Gateway.RestApiID(ctx, ApiName(platform))
+
+	if err != nil {
+		return result, err
+	}
+
+	exported := map[string][]byte{}
+
+	for _, format := range []string{JSON, YAML} {
+		if exported[format], err = exporter.Gateway.Export(ctx, id, platform, format); err != nil {
+			return result, err
+		}
+	}
+
+	if err := os.MkdirAll(exporter.Dir, 0755); err != nil {
+		return result, err
+	}
+
+	result.JSON = filepath.Join(exporter.Dir, "swagger_"+platform+".json")
+	result.YAML = filepath.Join(exporter.Dir, "swagger_"+platform+".yaml")
+
+	if err := ioutil.WriteFile(result.JSON, exported[JSON], 0644); err != nil {
+		return result, err
+	}
+
+	if err := ioutil.WriteFile(result.YAML, exported[YAML], 0644); err != nil {
+		return result, err
+	}
+
+	version := exporter.OpenAPIVersion
+
+	if version == "" {
+		version = swagger.OpenAPIVersion
+	}
+
+	openAPI, err := swagger.ToOpenAPI(exported[JSON], version)
+
+	if err != nil {
+		return result, err
+	}
+
+	result.OpenAPI = filepath.Join(exporter.Dir, "openapi_"+platform+".json")
+
+	if err := ioutil.WriteFile(result.OpenAPI, openAPI, 0644); err != nil {
+		return result, err
+	}
+
+	doc, err := swagger.Parse(exported[JSON])
+
+	if err != nil {
+		return result, err
+	}
+
+	if result.Models, err = swagger.GenerateModels(exporter.Package, doc.Definitions); err != nil {
+		return result, err
+	}
+
+	current, err := ioutil.ReadFile(exporter.Models)
+
+	if err != nil && !os.IsNotExist(err) {
+		return result, err
+	}
+
+	result.Diff = Diff(exporter.Models, fmt.Sprintf("models of %v", ApiName(platform)), string(current), string(result.Models))
+
+	return result, nil
+}
+
+// Update replaces the models file with the generated models, keeping the previous file in the export directory
+func (exporter Exporter) Update(result Result) error {
+
+	current, err := ioutil.ReadFile(exporter.Models)
+
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(exporter.Dir, filepath.Base(exporter.Models)+"_old"), current, 0644)
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return ioutil.WriteFile(exporter.Models, result.Models, 0644)
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+type fakeGateway struct {
+	apis    map[string]string
+	exports map[string][]byte
+}
+
+func (gateway fakeGateway) RestApiID(ctx context.Context, name string) (string, error) {
+
+	if id, ok := gateway.apis[name]; ok {
+		return id, nil
+	}
+
+	return "", errors.New("Cannot find " + name + " API. Has it been deployed?")
+}
+
+func (gateway fakeGateway) Export(ctx context.Context, apiID, stage, format string) ([]byte, error) {
+	return gateway.exports[apiID+" "+stage+" "+format], nil
+}
+
+func testExporter(t *testing.T, models string) Exporter {
+
+	raw, err := ioutil.ReadFile(filepath.Join("testdata", "swagger_test.json"))
+
+	utils.AssertNoError(t, "Read export", err)
+
+	return Exporter{
+		Gateway: fakeGateway{
+			apis: map[string]string{"Sample-API-test": "a1b2c3"},
+			exports: map[string][]byte{
+				"a1b2c3 test " + JSON: raw,
+				"a1b2c3 test " + YAML: []byte("swagger: \"2.0\"\n"),
+			},
+		},
+		Dir:     t.TempDir(),
+		Models:  models,
+		Package: "models",
+	}
+}
+
+func TestExportMatchesModels(t *testing.T) {
+
+	exporter := testExporter(t, filepath.Join("..", "models", "api.go"))
+
+	result, err := exporter.Export(context.Background(), "test")
+
+	utils.AssertNoError(t, "Export", err)
+	utils.AssertFalse(t, "Models match the API", result.Drifted())
+	utils.AssertEquals(t, "Diff", "", result.Diff)
+
+	raw, err := ioutil.ReadFile(result.YAML)
+
+	utils.AssertNoError(t, "YAML written", err)
+	utils.AssertEquals(t, "YAML export", "swagger: \"2.0\"\n", string(raw))
+	utils.AssertEquals(t, "JSON path", filepath.Join(exporter.Dir, "swagger_test.json"), result.JSON)
+	utils.AssertEquals(t, "OpenAPI path", filepath.Join(exporter.Dir, "openapi_test.json"), result.OpenAPI)
+
+	raw, err = ioutil.ReadFile(result.OpenAPI)
+
+	utils.AssertNoError(t, "OpenAPI written", err)
+	utils.AssertTrue(t, "OpenAPI version", strings.Contains(string(raw), "\"openapi\": \"3.1.0\""))
+	utils.AssertTrue(t, "Integration kept", strings.Contains(string(raw), "\"x-amazon-apigateway-integration\""))
+
+	_, err = exporter.Export(context.Background(), "live")
+
+	utils.AssertErrorEquals(t, "Undeployed API", "Cannot find Sample-API-live API. Has it been deployed?", err)
+}
+
+func TestExportDrift(t *testing.T) {
+
+	models := filepath.Join(t.TempDir(), "api.go")
+	current, _ := ioutil.ReadFile(filepath.Join("..", "models", "api.go"))
+	drifted := strings.Replace(string(current), "\tDirty        bool         `json:\"dirty,omitempty\"`\n", "", 1)
+
+	ioutil.WriteFile(models, []byte(drifted), 0644)
+
+	exporter := testExporter(t, models)
+
+	result, err := exporter.Export(context.Background(), "test")
+
+	utils.AssertNoError(t, "Export", err)
+	utils.AssertTrue(t, "Drifted", result.Drifted())
+	utils.AssertTrue(t, "Diff adds field", strings.Contains(result.Diff, "\n+\tDirty        bool         `json:\"dirty,omitempty\"`\n"))
+
+	err = exporter.Update(result)
+
+	utils.AssertNoError(t, "Update", err)
+
+	updated, _ := ioutil.ReadFile(models)
+	old, _ := ioutil.ReadFile(filepath.Join(exporter.Dir, "api.go_old"))
+
+	utils.AssertEquals(t, "Updated models", string(current), string(updated))
+	utils.AssertEquals(t, "Previous models kept", drifted, string(old))
+}
+
+func TestDiff(t *testing.T) {
+
+	utils.AssertEquals(t, "Same", "", Diff("a", "b", "one\ntwo\n", "one\ntwo\n"))
+	utils.AssertEquals(t, "Changed", strings.Join([]string{
+		"--- a",
+		"+++ b",
+		"@@ line 2 @@",
+		" two",
+		" three",
+		"-four",
+		"+FOUR",
+		" five",
+		" six",
+		"",
+	}, "\n"), Diff("a", "b", "one\ntwo\nthree\nfour\nfive\nsix\nseven\n", "one\ntwo\nthree\nFOUR\nfive\nsix\nseven\n"))
+}
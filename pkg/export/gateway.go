@@ -0,0 +1,73 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+)
+
+// Export formats
+const (
+	JSON = "application/json"
+	YAML = "application/yaml"
+)
+
+// A Gateway finds deployed REST APIs and exports their Swagger definitions
+type Gateway interface {
+	// RestApiID returns the ID of the REST API with a name
+	RestApiID(ctx context.Context, name string) (string, error)
+	// Export returns the Swagger definition of a stage of a REST API with its API Gateway extensions, in JSON or YAML
+	Export(ctx context.Context, apiID, stage, format string) ([]byte, error)
+}
+
+// CLIGateway is a Gateway using the aws command line
+type CLIGateway struct {
+	Runner command.Runner
+}
+
+func (gateway CLIGateway) RestApiID(ctx context.Context, name string) (string, error) {
+
+	out, err := gateway.Runner.Run(ctx, "aws", "apigateway", "get-rest-apis",
+		"--query", fmt.Sprintf("items[?name=='%v'].id", name), "--output", "text")
+
+	if err != nil {
+		return "", err
+	}
+
+	ids := strings.Fields(string(out))
+
+	if len(ids) == 0 {
+		return "", fmt.Errorf("Cannot find %v API. Has it been deployed?", name)
+	}
+
+	if len(ids) > 1 {
+		return "", fmt.Errorf("There are %v APIs named %v", len(ids), name)
+	}
+
+	return ids[0], nil
+}
+
+func (gateway CLIGateway) Export(ctx context.Context, apiID, stage, format string) ([]byte, error) {
+
+	dir, err := ioutil.TempDir("", "export")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export")
+
+	if _, err := gateway.Runner.Run(ctx, "aws", "apigateway", "get-export", "--rest-api-id", apiID, "--stage-name", stage,
+		"--export-type", "swagger", "--parameters", "extensions=apigateway", "--accepts", format, path); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(path)
+}
@@ -0,0 +1,123 @@
+// The guard package evaluates release policies against a git repository. A Policy is a set of Rules, such as a clean
+// working tree, an allowed branch or a semantic version tag on HEAD, and evaluating it reports every failing rule at
+// once rather than stopping at the first.
+package guard
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/command"
+)
+
+// Repo is a git repository on which rules are checked
+type Repo struct {
+	// The repository directory. Defaults to the current directory
+	Dir string
+	// Runs git. Defaults to running it as a local process in Dir
+	Runner command.Runner
+}
+
+// Git runs a git command in the repository and returns its trimmed standard output
+func (repo Repo) Git(ctx context.Context, args ...string) (string, error) {
+
+	var (
+		out []byte
+		err error
+	)
+
+	if repo.Runner != nil {
+		out, err = repo.Runner.Run(ctx, "git", args...)
+	} else {
+		out, err = repo.exec(ctx, args...)
+	}
+
+	return strings.TrimSpace(string(out)), err
+}
+
+func (repo Repo) exec(ctx context.Context, args ...string) ([]byte, error) {
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repo.Dir
+
+	out, err := cmd.Output()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return out, fmt.Errorf("git %v failed: %v", strings.Join(args, " "), strings.TrimSpace(string(exitErr.Stderr)))
+	}
+
+	return out, err
+}
+
+// A Rule is a single release policy check
+type Rule interface {
+	Name() string
+	// Check returns an error describing why the repository fails the rule, or nil
+	Check(ctx context.Context, repo Repo) error
+}
+
+// A Policy is a set of rules which must all pass
+type Policy []Rule
+
+// Result is the outcome of checking one rule
+type Result struct {
+	Rule string
+	Err  error
+}
+
+// Report is the outcome of evaluating a policy
+type Report []Result
+
+// Evaluate checks every rule of the policy against the repository
+func (policy Policy) Evaluate(ctx context.Context, repo Repo) Report {
+
+	report := make(Report, 0, len(policy))
+
+	for _, rule := range policy {
+		report = append(report, Result{Rule: rule.Name(), Err: rule.Check(ctx, repo)})
+	}
+
+	return report
+}
+
+// Failures returns the results of failing rules
+func (report Report) Failures() []Result {
+
+	var failures []Result
+
+	for _, result := range report {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+
+	return failures
+}
+
+// Err returns an error listing every failing rule, or nil if all passed
+func (report Report) Err() error {
+
+	failures := report.Failures()
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return PolicyError(failures)
+}
+
+// PolicyError is the error for a policy with failing rules
+type PolicyError []Result
+
+func (err PolicyError) Error() string {
+
+	lines := make([]string, len(err))
+
+	for i, result := range err {
+		lines[i] = result.Rule + ": " + result.Err.Error()
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,187 @@
+package guard
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// testRepo is a temporary git repository with a bare origin remote
+type testRepo struct {
+	t    *testing.T
+	root string
+	Repo
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+
+	root, err := ioutil.TempDir("", "guard")
+
+	utils.AssertNoError(t, "Temp dir", err)
+
+	repo := &testRepo{t: t, root: root, Repo: Repo{Dir: filepath.Join(root, "work")}}
+
+	repo.run(root, "init", "--bare", "-b", "master", "origin.git")
+	repo.run(root, "init", "-b", "master", "work")
+	repo.git("config", "user.name", "Test")
+	repo.git("config", "user.email", "test@example.com")
+	repo.git("config", "tag.gpgSign", "false")
+	repo.git("remote", "add", "origin", filepath.Join(root, "origin.git"))
+	repo.commit("README.md", "# Test\n")
+	repo.git("push", "-q", "-u", "origin", "master")
+
+	return repo
+}
+
+func (repo *testRepo) close() {
+	os.RemoveAll(repo.root)
+}
+
+func (repo *testRepo) run(dir string, args ...string) {
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		repo.t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+}
+
+func (repo *testRepo) git(args ...string) {
+	repo.run(repo.Dir, args...)
+}
+
+func (repo *testRepo) write(file, content string) {
+
+	err := ioutil.WriteFile(filepath.Join(repo.Dir, file), []byte(content), 0644)
+
+	utils.AssertNoError(repo.t, "Write "+file, err)
+}
+
+func (repo *testRepo) commit(file, content string) {
+	repo.write(file, content)
+	repo.git("add", file)
+	repo.git("commit", "-q", "-m", "Update "+file)
+}
+
+func fullPolicy() Policy {
+	return Policy{
+		CleanTree(),
+		BranchAllowlist("master", "release/*"),
+		UpstreamSync("origin", true),
+		TagOnHead(SemverTagPattern),
+		ChangelogEntry("CHANGELOG.md", SemverTagPattern),
+	}
+}
+
+func TestPolicyPasses(t *testing.T) {
+
+	repo := newTestRepo(t)
+	defer repo.close()
+
+	repo.commit("CHANGELOG.md", "# Changelog\n\n## [1.2.0] - 2019-01-02\n\n* Added calc\n")
+	repo.git("tag", "-a", "-m", "Release", "v1.2.0")
+	repo.git("push", "-q", "origin", "master")
+
+	report := fullPolicy().Evaluate(context.Background(), repo.Repo)
+
+	utils.AssertNoError(t, "Passing policy", report.Err())
+	utils.AssertEquals(t, "Number of results", 5, len(report))
+}
+
+func TestPolicyReportsEveryFailure(t *testing.T) {
+
+	repo := newTestRepo(t)
+	defer repo.close()
+
+	repo.git("checkout", "-q", "-b", "feature")
+	repo.commit("CHANGELOG.md", "# Changelog\n\n## 1.1.0\n")
+	repo.git("tag", "v1.2.0")
+	repo.write("api.go", "package api\n")
+
+	err := fullPolicy().Evaluate(context.Background(), repo.Repo).Err()
+
+	utils.AssertTrue(t, "Policy error type", err != nil)
+
+	failures := err.(PolicyError)
+
+	utils.AssertEquals(t, "Number of failures", 4, len(failures))
+	utils.AssertEquals(t, "Clean tree failure", "clean-tree: There are uncommitted changes", failures[0].Rule+": "+failures[0].Err.Error())
+	utils.AssertErrorEquals(t, "Branch failure", "Branch feature is not one of master, release/*", failures[1].Err)
+	utils.AssertEquals(t, "Upstream failure rule", "upstream-sync", failures[2].Rule)
+	utils.AssertErrorEquals(t, "Changelog failure", "CHANGELOG.md has no entry for v1.2.0", failures[3].Err)
+}
+
+func TestUpstreamSync(t *testing.T) {
+
+	repo := newTestRepo(t)
+	defer repo.close()
+
+	rule := UpstreamSync("origin", false)
+
+	utils.AssertNoError(t, "In sync", rule.Check(context.Background(), repo.Repo))
+
+	repo.commit("api.go", "package api\n")
+
+	utils.AssertErrorEquals(t, "Ahead", "Not in sync with origin/master: 1 ahead, 0 behind", rule.Check(context.Background(), repo.Repo))
+
+	repo.git("push", "-q", "origin", "master")
+	repo.git("reset", "-q", "--hard", "HEAD~1")
+
+	utils.AssertErrorEquals(t, "Behind", "Not in sync with origin/master: 0 ahead, 1 behind", rule.Check(context.Background(), repo.Repo))
+}
+
+func TestReleaseTags(t *testing.T) {
+
+	repo := newTestRepo(t)
+	defer repo.close()
+
+	utils.AssertErrorEquals(t, "Untagged", `HEAD is not tagged to match ^v[0-9]+\.[0-9]+\.[0-9]+$`,
+		TagOnHead(SemverTagPattern).Check(context.Background(), repo.Repo))
+
+	repo.git("tag", "v1.0.0-rc1")
+	repo.git("tag", "-a", "-m", "Release", "v1.0.0")
+
+	utils.AssertNoError(t, "Semver tag", TagOnHead(SemverTagPattern).Check(context.Background(), repo.Repo))
+	utils.AssertNoError(t, "Custom tag pattern", TagOnHead(regexp.MustCompile(`-rc[0-9]+$`)).Check(context.Background(), repo.Repo))
+	utils.AssertErrorEquals(t, "Unsigned tag", "Tag v1.0.0 is not signed with a trusted key",
+		SignedTag(SemverTagPattern).Check(context.Background(), repo.Repo))
+
+	repo.commit("api.go", "package api\n")
+
+	utils.AssertErrorEquals(t, "Tag not on HEAD", `HEAD is not tagged to match ^v[0-9]+\.[0-9]+\.[0-9]+$`,
+		TagOnHead(SemverTagPattern).Check(context.Background(), repo.Repo))
+}
+
+func TestChangelogEntry(t *testing.T) {
+
+	repo := newTestRepo(t)
+	defer repo.close()
+
+	rule := ChangelogEntry("CHANGELOG.md", SemverTagPattern)
+
+	repo.git("tag", "v2.0.1")
+
+	utils.AssertErrorEquals(t, "Missing changelog", "No CHANGELOG.md committed", rule.Check(context.Background(), repo.Repo))
+
+	for _, heading := range []string{"## v2.0.1", "### 2.0.1 (2019-01-02)", "## [2.0.1]"} {
+
+		repo.git("tag", "-d", "v2.0.1")
+		repo.commit("CHANGELOG.md", "# Changelog\n\n"+heading+"\n\n## 2.0.10\n")
+		repo.git("tag", "v2.0.1")
+
+		utils.AssertNoError(t, "Changelog heading "+heading, rule.Check(context.Background(), repo.Repo))
+	}
+
+	repo.git("tag", "-d", "v2.0.1")
+	repo.commit("CHANGELOG.md", "# Changelog\n\n## 2.0.10\n")
+	repo.git("tag", "v2.0.1")
+
+	utils.AssertErrorEquals(t, "Similar version", "CHANGELOG.md has no entry for v2.0.1", rule.Check(context.Background(), repo.Repo))
+}
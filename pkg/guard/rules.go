@@ -0,0 +1,225 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The pattern for semantic version release tags of the form vN.N.N
+var SemverTagPattern = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// CleanTree requires that there are no uncommitted changes or untracked files
+func CleanTree() Rule {
+	return cleanTree{}
+}
+
+type cleanTree struct{}
+
+func (cleanTree) Name() string {
+	return "clean-tree"
+}
+
+func (cleanTree) Check(ctx context.Context, repo Repo) error {
+
+	out, err := repo.Git(ctx, "status", "--porcelain")
+
+	if err != nil {
+		return err
+	}
+
+	if out != "" {
+		return errors.New("There are uncommitted changes")
+	}
+
+	return nil
+}
+
+// BranchAllowlist requires that HEAD is on a branch matching one of the patterns, which may use path.Match wildcards
+func BranchAllowlist(patterns ...string) Rule {
+	return branchAllowlist(patterns)
+}
+
+type branchAllowlist []string
+
+func (branchAllowlist) Name() string {
+	return "branch"
+}
+
+func (patterns branchAllowlist) Check(ctx context.Context, repo Repo) error {
+
+	branch, err := CurrentBranch(ctx, repo)
+
+	if err != nil {
+		return err
+	}
+
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Branch %v is not one of %v", branch, strings.Join(patterns, ", "))
+}
+
+// UpstreamSync requires that HEAD is neither ahead of nor behind the same branch on the remote, fetching first if fetch
+// is true
+func UpstreamSync(remote string, fetch bool) Rule {
+	return upstreamSync{remote: remote, fetch: fetch}
+}
+
+type upstreamSync struct {
+	remote string
+	fetch  bool
+}
+
+func (upstreamSync) Name() string {
+	return "upstream-sync"
+}
+
+func (rule upstreamSync) Check(ctx context.Context, repo Repo) error {
+
+	branch, err := CurrentBranch(ctx, repo)
+
+	if err != nil {
+		return err
+	}
+
+	if rule.fetch {
+		if _, err := repo.Git(ctx, "fetch", rule.remote); err != nil {
+			return err
+		}
+	}
+
+	upstream := rule.remote + "/" + branch
+
+	out, err := repo.Git(ctx, "rev-list", "--left-right", "--count", "HEAD..."+upstream)
+
+	if err != nil {
+		return fmt.Errorf("Cannot compare with %v: %v", upstream, err)
+	}
+
+	counts := strings.Fields(out)
+
+	if len(counts) != 2 {
+		return fmt.Errorf("Cannot compare with %v: unexpected output %q", upstream, out)
+	}
+
+	if counts[0] != "0" || counts[1] != "0" {
+		return fmt.Errorf("Not in sync with %v: %v ahead, %v behind", upstream, counts[0], counts[1])
+	}
+
+	return nil
+}
+
+// TagOnHead requires that HEAD is exactly on a tag matching the pattern
+func TagOnHead(pattern *regexp.Regexp) Rule {
+	return tagOnHead{pattern: pattern}
+}
+
+type tagOnHead struct {
+	pattern *regexp.Regexp
+}
+
+func (tagOnHead) Name() string {
+	return "release-tag"
+}
+
+func (rule tagOnHead) Check(ctx context.Context, repo Repo) error {
+	_, err := ReleaseTag(ctx, repo, rule.pattern)
+	return err
+}
+
+// SignedTag requires that HEAD is exactly on a tag matching the pattern which git verify-tag accepts
+func SignedTag(pattern *regexp.Regexp) Rule {
+	return signedTag{pattern: pattern}
+}
+
+type signedTag struct {
+	pattern *regexp.Regexp
+}
+
+func (signedTag) Name() string {
+	return "signed-tag"
+}
+
+func (rule signedTag) Check(ctx context.Context, repo Repo) error {
+
+	tag, err := ReleaseTag(ctx, repo, rule.pattern)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.Git(ctx, "verify-tag", tag); err != nil {
+		return fmt.Errorf("Tag %v is not signed with a trusted key", tag)
+	}
+
+	return nil
+}
+
+// ChangelogEntry requires that the committed changelog file has a heading for the version of the release tag on HEAD,
+// such as "## v1.2.3", "## 1.2.3" or "## [1.2.3] - 2019-01-02"
+func ChangelogEntry(file string, pattern *regexp.Regexp) Rule {
+	return changelogEntry{file: file, pattern: pattern}
+}
+
+type changelogEntry struct {
+	file    string
+	pattern *regexp.Regexp
+}
+
+func (changelogEntry) Name() string {
+	return "changelog"
+}
+
+func (rule changelogEntry) Check(ctx context.Context, repo Repo) error {
+
+	tag, err := ReleaseTag(ctx, repo, rule.pattern)
+
+	if err != nil {
+		return err
+	}
+
+	content, err := repo.Git(ctx, "show", "HEAD:"+rule.file)
+
+	if err != nil {
+		return fmt.Errorf("No %v committed", rule.file)
+	}
+
+	version := regexp.QuoteMeta(strings.TrimPrefix(tag, "v"))
+	heading := regexp.MustCompile(`(?m)^#+\s*\[?v?` + version + `\]?(\s|$)`)
+
+	if !heading.MatchString(content) {
+		return fmt.Errorf("%v has no entry for %v", rule.file, tag)
+	}
+
+	return nil
+}
+
+// CurrentBranch returns the branch of HEAD
+func CurrentBranch(ctx context.Context, repo Repo) (string, error) {
+	return repo.Git(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// ReleaseTag returns the tag on HEAD matching the pattern, or an error if there is none
+func ReleaseTag(ctx context.Context, repo Repo, pattern *regexp.Regexp) (string, error) {
+
+	out, err := repo.Git(ctx, "tag", "--points-at", "HEAD")
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range strings.Fields(out) {
+		if pattern.MatchString(tag) {
+			return tag, nil
+		}
+	}
+
+	return "", fmt.Errorf("HEAD is not tagged to match %v", pattern)
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RemainingTime fails if less than minimum time remains before the deadline of the request being handled
+func RemainingTime(minimum time.Duration) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+
+		deadline, ok := Deadline(ctx)
+
+		if !ok {
+			return nil
+		}
+
+		if remaining := time.Until(deadline); remaining < minimum {
+			return fmt.Errorf("Only %v remaining, less than %v", remaining.Round(time.Millisecond), minimum)
+		}
+
+		return nil
+	})
+}
+
+// Required fails listing every configuration value which is empty, by name
+func Required(values map[string]string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+
+		var missing []string
+
+		for name, value := range values {
+			if value == "" {
+				missing = append(missing, name)
+			}
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+
+		sort.Strings(missing)
+
+		return fmt.Errorf("Missing configuration: %v", strings.Join(missing, ", "))
+	})
+}
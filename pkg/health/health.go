@@ -0,0 +1,158 @@
+// The health package runs health checks and aggregates their results in the health check response format for HTTP
+// APIs (application/health+json, draft-inadarei-api-health-check). Each check has a name, a timeout and a
+// criticality: a failing critical check fails the whole service, and a failing non-critical check only warns.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/models"
+)
+
+// The content type of health responses
+const ContentType = "application/health+json"
+
+// The statuses of checks and of the service
+const (
+	StatusPass = "pass"
+	StatusWarn = "warn"
+	StatusFail = "fail"
+)
+
+// The timeout of checks which do not set one
+const DefaultTimeout = time.Second
+
+// A Checker checks the health of one component, returning an error if it is unhealthy
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Check is a named health check
+type Check struct {
+	Name    string
+	Checker Checker
+	// The time after which the check fails. Defaults to DefaultTimeout
+	Timeout time.Duration
+	// If true, failure of the check fails the service; otherwise it only warns
+	Critical bool
+}
+
+// Warn returns an error which makes a check warn rather than fail, even if it is critical
+func Warn(err error) error {
+	return warning{err}
+}
+
+type warning struct {
+	error
+}
+
+type deadlineKey struct{}
+
+// Deadline returns the deadline of the context passed to Run, before any check timeout was applied
+func Deadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Value(deadlineKey{}).(time.Time)
+	return deadline, ok
+}
+
+// Run runs the checks concurrently and returns the aggregated result. Each check is keyed by its name and the
+// responseTime measurement, with its latency in milliseconds as its observed value
+func Run(ctx context.Context, checks []Check) models.Health {
+
+	if deadline, ok := ctx.Deadline(); ok {
+		ctx = context.WithValue(ctx, deadlineKey{}, deadline)
+	}
+
+	results := make([]models.HealthCheck, len(checks))
+
+	var wg sync.WaitGroup
+
+	for i, check := range checks {
+
+		wg.Add(1)
+
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = run(ctx, check)
+		}(i, check)
+	}
+
+	wg.Wait()
+
+	report := models.Health{
+		Status: StatusPass,
+		Checks: map[string][]models.HealthCheck{},
+	}
+
+	for i, result := range results {
+
+		key := checks[i].Name + ":responseTime"
+		report.Checks[key] = append(report.Checks[key], result)
+
+		if result.Status == StatusFail || (result.Status == StatusWarn && report.Status == StatusPass) {
+			report.Status = result.Status
+		}
+	}
+
+	return report
+}
+
+func run(ctx context.Context, check Check) models.HealthCheck {
+
+	timeout := check.Timeout
+
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		done <- check.Checker.Check(ctx)
+	}()
+
+	var err error
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("Timed out after %v", timeout)
+	}
+
+	result := models.HealthCheck{
+		Status:        StatusPass,
+		ObservedValue: float64(time.Since(start).Microseconds()) / 1000,
+		ObservedUnit:  "ms",
+		Time:          start.UTC().Format(time.RFC3339Nano),
+	}
+
+	if err == nil {
+		return result
+	}
+
+	result.Output = err.Error()
+
+	var warn warning
+
+	if check.Critical && !errors.As(err, &warn) {
+		result.Status = StatusFail
+	} else {
+		result.Status = StatusWarn
+	}
+
+	return result
+}
@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func passing(ctx context.Context) error {
+	return nil
+}
+
+func failing(ctx context.Context) error {
+	return errors.New("Connection refused")
+}
+
+func slow(ctx context.Context) error {
+	time.Sleep(time.Second)
+	return nil
+}
+
+func TestRunStatus(t *testing.T) {
+
+	scenarios := []struct {
+		name     string
+		checks   []Check
+		expected string
+	}{
+		{"No checks", nil, StatusPass},
+		{"Passing", []Check{{Name: "a", Checker: CheckerFunc(passing), Critical: true}}, StatusPass},
+		{"Non-critical failure", []Check{
+			{Name: "a", Checker: CheckerFunc(passing), Critical: true},
+			{Name: "b", Checker: CheckerFunc(failing)},
+		}, StatusWarn},
+		{"Critical failure", []Check{
+			{Name: "a", Checker: CheckerFunc(failing), Critical: true},
+			{Name: "b", Checker: CheckerFunc(failing)},
+		}, StatusFail},
+		{"Critical warning", []Check{
+			{Name: "a", Checker: CheckerFunc(func(ctx context.Context) error { return Warn(errors.New("Degraded")) }), Critical: true},
+		}, StatusWarn},
+	}
+
+	for _, scenario := range scenarios {
+		utils.AssertEquals(t, scenario.name, scenario.expected, Run(context.Background(), scenario.checks).Status)
+	}
+}
+
+func TestRunResults(t *testing.T) {
+
+	report := Run(context.Background(), []Check{
+		{Name: "database", Checker: CheckerFunc(failing), Critical: true},
+		{Name: "cache", Checker: CheckerFunc(slow), Timeout: 10 * time.Millisecond},
+	})
+
+	database := report.Checks["database:responseTime"][0]
+
+	utils.AssertEquals(t, "Database status", StatusFail, database.Status)
+	utils.AssertEquals(t, "Database output", "Connection refused", database.Output)
+	utils.AssertEquals(t, "Database unit", "ms", database.ObservedUnit)
+
+	cache := report.Checks["cache:responseTime"][0]
+
+	utils.AssertEquals(t, "Timed out status", StatusWarn, cache.Status)
+	utils.AssertEquals(t, "Timed out output", "Timed out after 10ms", cache.Output)
+	utils.AssertTrue(t, "Timed out latency", cache.ObservedValue >= 10 && cache.ObservedValue < 1000)
+}
+
+func TestRemainingTime(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	check := []Check{{Name: "lambda", Checker: RemainingTime(time.Second), Critical: true}}
+
+	utils.AssertEquals(t, "Enough time", StatusPass, Run(ctx, check).Status)
+	utils.AssertEquals(t, "No deadline", StatusPass, Run(context.Background(), check).Status)
+
+	check[0].Checker = RemainingTime(10 * time.Second)
+
+	report := Run(ctx, check)
+
+	utils.AssertEquals(t, "Too little time", StatusFail, report.Status)
+	utils.AssertTrue(t, "Too little time output", report.Checks["lambda:responseTime"][0].Output != "")
+}
+
+func TestRequired(t *testing.T) {
+
+	checker := Required(map[string]string{"PLATFORM": "test", "COMMIT": "", "BRANCH": ""})
+
+	utils.AssertErrorEquals(t, "Missing configuration", "Missing configuration: BRANCH, COMMIT", checker.Check(context.Background()))
+	utils.AssertNoError(t, "Complete configuration", Required(map[string]string{"PLATFORM": "test"}).Check(context.Background()))
+}
@@ -0,0 +1,251 @@
+// The history package records what was deployed to each platform and when, in a Store which may be a local directory
+// or an S3 bucket. A record keeps the packaged template and parameters for each region, so that a deployment whose
+// artifacts were retained can be redeployed without rebuilding.
+package history
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The format of record IDs, which sort in time order
+const idFormat = "20060102T150405.000000000Z"
+
+// Record is a deployment to a platform
+type Record struct {
+	// A unique ID derived from the time of the deployment
+	ID        string    `json:"id"`
+	Platform  string    `json:"platform"`
+	Release   string    `json:"release"`
+	Commit    string    `json:"commit"`
+	Branch    string    `json:"branch"`
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	StackName string    `json:"stackName"`
+	// The SHA-256 digest of the lambda artifact
+	Artifact string `json:"artifact,omitempty"`
+	// If this deployment was a rollback, the ID of the record it redeployed
+	RollbackOf string `json:"rollbackOf,omitempty"`
+	// The regions deployed successfully
+	Regions []Region `json:"regions"`
+}
+
+// Region is the part of a deployment in one region
+type Region struct {
+	// The region, or empty for the AWS CLI default region
+	Region     string            `json:"region"`
+	Parameters map[string]string `json:"parameters"`
+	// The outputs of the stack after the deployment
+	Outputs map[string]string `json:"outputs,omitempty"`
+	// The packaged template. Empty unless its artifacts were retained, in which case it can be redeployed
+	Template string `json:"template,omitempty"`
+}
+
+// NewID returns the ID of a record for a deployment at a time
+func NewID(t time.Time) string {
+	return t.UTC().Format(idFormat)
+}
+
+// CanRollback reports whether every region of the deployment can be redeployed
+func (record Record) CanRollback() bool {
+
+	for _, region := range record.Regions {
+		if region.Template == "" {
+			return false
+		}
+	}
+
+	return len(record.Regions) > 0
+}
+
+func (record Record) String() string {
+
+	s := fmt.Sprintf("%v  %v (%v) by %v at %v", record.ID, record.Release, record.Commit, record.User, record.Time.Format(time.RFC3339))
+
+	if record.RollbackOf != "" {
+		s += ", rolling back to " + record.RollbackOf
+	}
+
+	return s
+}
+
+// A Store saves and lists deployment records
+type Store interface {
+	Save(ctx context.Context, record Record) error
+	// List returns the records for a platform, most recent first
+	List(ctx context.Context, platform string) ([]Record, error)
+}
+
+// Previous returns the record to roll back to from the records of a platform, most recent first. If id is empty, it
+// is the most recent deployment of a different commit from the current one; otherwise it is the record with that ID
+func Previous(records []Record, id string) (Record, error) {
+
+	if len(records) == 0 {
+		return Record{}, errors.New("No deployments have been recorded")
+	}
+
+	for _, record := range records {
+
+		if id != "" && record.ID == id {
+			return record, nil
+		}
+
+		if id == "" && record.Commit != records[0].Commit {
+			return record, nil
+		}
+	}
+
+	if id != "" {
+		return Record{}, fmt.Errorf("No deployment %v has been recorded", id)
+	}
+
+	return Record{}, fmt.Errorf("No deployment before %v (%v) has been recorded", records[0].Release, records[0].Commit)
+}
+
+// FileStore is a Store keeping each record as a JSON file in a directory per platform
+type FileStore struct {
+	Dir string
+}
+
+func (store FileStore) Save(ctx context.Context, record Record) error {
+
+	dir := filepath.Join(store.Dir, record.Platform)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	raw, err := json.MarshalIndent(record, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, record.ID+".json"), raw, 0644)
+}
+
+func (store FileStore) List(ctx context.Context, platform string) ([]Record, error) {
+
+	files, err := ioutil.ReadDir(filepath.Join(store.Dir, platform))
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, file := range files {
+		if !file.IsDir() && strings.HasSuffix(file.Name(), ".json") {
+			names = append(names, filepath.Join(store.Dir, platform, file.Name()))
+		}
+	}
+
+	return readAll(names, ioutil.ReadFile)
+}
+
+// An ObjectClient reads and writes objects in an S3 bucket
+type ObjectClient interface {
+	PutObject(ctx context.Context, bucket, key string, body []byte) error
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+	// ListKeys returns the keys of the objects with a prefix
+	ListKeys(ctx context.Context, bucket, prefix string) ([]string, error)
+}
+
+// S3Store is a Store keeping each record as a JSON object under a prefix per platform in an S3 bucket
+type S3Store struct {
+	Client ObjectClient
+	Bucket string
+	// The prefix of the platform prefixes, such as deployments
+	Prefix string
+}
+
+func (store S3Store) Save(ctx context.Context, record Record) error {
+
+	raw, err := json.MarshalIndent(record, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return store.Client.PutObject(ctx, store.Bucket, path.Join(store.Prefix, record.Platform, record.ID+".json"), raw)
+}
+
+func (store S3Store) List(ctx context.Context, platform string) ([]Record, error) {
+
+	keys, err := store.Client.ListKeys(ctx, store.Bucket, path.Join(store.Prefix, platform)+"/")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, key := range keys {
+		if strings.HasSuffix(key, ".json") {
+			names = append(names, key)
+		}
+	}
+
+	return readAll(names, func(key string) ([]byte, error) {
+		return store.Client.GetObject(ctx, store.Bucket, key)
+	})
+}
+
+// Open returns the Store at a location: an S3 URL of the form s3://bucket/prefix, using client, or else a directory
+func Open(location string, client ObjectClient) Store {
+
+	if !strings.HasPrefix(location, "s3://") {
+		return FileStore{Dir: location}
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(location, "s3://"), "/", 2)
+	store := S3Store{Client: client, Bucket: parts[0]}
+
+	if len(parts) == 2 {
+		store.Prefix = strings.Trim(parts[1], "/")
+	}
+
+	return store
+}
+
+// Reads and parses the named records, returning them most recent first
+func readAll(names []string, read func(name string) ([]byte, error)) ([]Record, error) {
+
+	records := make([]Record, 0, len(names))
+
+	for _, name := range names {
+
+		raw, err := read(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var record Record
+
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return nil, fmt.Errorf("Cannot parse deployment record %v: %v", name, err)
+		}
+
+		records = append(records, record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID > records[j].ID
+	})
+
+	return records, nil
+}
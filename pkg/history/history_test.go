@@ -0,0 +1,161 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+// fakeObjects is an ObjectClient keeping objects in memory, keyed by bucket/key
+type fakeObjects map[string][]byte
+
+func (objects fakeObjects) PutObject(ctx context.Context, bucket, key string, body []byte) error {
+
+	objects[bucket+"/"+key] = body
+
+	return nil
+}
+
+func (objects fakeObjects) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+
+	if body, ok := objects[bucket+"/"+key]; ok {
+		return body, nil
+	}
+
+	return nil, errors.New("NoSuchKey")
+}
+
+func (objects fakeObjects) ListKeys(ctx context.Context, bucket, prefix string) ([]string, error) {
+
+	var keys []string
+
+	for name := range objects {
+		if strings.HasPrefix(name, bucket+"/"+prefix) {
+			keys = append(keys, strings.TrimPrefix(name, bucket+"/"))
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+func testRecords() []Record {
+
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	var records []Record
+
+	for i, release := range []string{"v1.0.0", "v1.1.0", "v1.1.0", "v1.2.0"} {
+
+		at := start.Add(time.Duration(i) * time.Hour)
+
+		records = append(records, Record{
+			ID:       NewID(at),
+			Platform: "live",
+			Release:  release,
+			Commit:   "commit-" + release,
+			Time:     at,
+			User:     "deployer",
+			Regions:  []Region{{Region: "eu-west-1", Template: "packaged " + release}},
+		})
+	}
+
+	return records
+}
+
+func testStore(t *testing.T, name string, store Store) {
+
+	ctx := context.Background()
+
+	records, err := store.List(ctx, "live")
+
+	utils.AssertNoError(t, name+" empty list", err)
+	utils.AssertEquals(t, name+" empty", 0, len(records))
+
+	for _, record := range testRecords() {
+		utils.AssertNoError(t, name+" save", store.Save(ctx, record))
+	}
+
+	utils.AssertNoError(t, name+" save other platform", store.Save(ctx, Record{ID: NewID(time.Now()), Platform: "test"}))
+
+	records, err = store.List(ctx, "live")
+
+	utils.AssertNoError(t, name+" list", err)
+	utils.AssertEquals(t, name+" count", 4, len(records))
+	utils.AssertEquals(t, name+" most recent first", "v1.2.0", records[0].Release)
+	utils.AssertEquals(t, name+" template", "packaged v1.0.0", records[3].Regions[0].Template)
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, "File store", FileStore{Dir: t.TempDir()})
+}
+
+func TestS3Store(t *testing.T) {
+
+	objects := fakeObjects{}
+
+	testStore(t, "S3 store", Open("s3://history-bucket/deployments/", objects))
+
+	_, ok := objects["history-bucket/deployments/live/20261001T120000.000000000Z.json"]
+
+	utils.AssertTrue(t, "S3 key", ok)
+}
+
+func TestOpen(t *testing.T) {
+
+	utils.AssertEquals(t, "Directory", FileStore{Dir: ".deploy/history"}, Open(".deploy/history", nil))
+	utils.AssertEquals(t, "Bucket only", "bucket", Open("s3://bucket", fakeObjects{}).(S3Store).Bucket)
+}
+
+func TestPrevious(t *testing.T) {
+
+	records := testRecords()
+
+	sort.Slice(records, func(i, j int) bool { return records[i].ID > records[j].ID })
+
+	previous, err := Previous(records, "")
+
+	utils.AssertNoError(t, "Previous", err)
+	utils.AssertEquals(t, "Previous release", "v1.1.0", previous.Release)
+	utils.AssertEquals(t, "Most recent of previous release", "20261001T140000.000000000Z", previous.ID)
+
+	previous, err = Previous(records[1:], "")
+
+	utils.AssertNoError(t, "Previous to repeated release", err)
+	utils.AssertEquals(t, "Skips repeated release", "v1.0.0", previous.Release)
+
+	previous, err = Previous(records, "20261001T120000.000000000Z")
+
+	utils.AssertNoError(t, "Previous by ID", err)
+	utils.AssertEquals(t, "Release by ID", "v1.0.0", previous.Release)
+
+	_, err = Previous(records, "20261001T120000Z")
+
+	utils.AssertErrorEquals(t, "Unknown ID", "No deployment 20261001T120000Z has been recorded", err)
+
+	_, err = Previous(records[3:], "")
+
+	utils.AssertErrorEquals(t, "Nothing earlier", "No deployment before v1.0.0 (commit-v1.0.0) has been recorded", err)
+
+	_, err = Previous(nil, "")
+
+	utils.AssertErrorEquals(t, "No records", "No deployments have been recorded", err)
+}
+
+func TestCanRollback(t *testing.T) {
+
+	record := testRecords()[0]
+
+	utils.AssertTrue(t, "Retained template", record.CanRollback())
+
+	record.Regions = append(record.Regions, Region{Region: "us-east-1"})
+
+	utils.AssertFalse(t, "Region without template", record.CanRollback())
+	utils.AssertFalse(t, "No regions", Record{}.CanRollback())
+}
@@ -0,0 +1,363 @@
+// The metrics package emits metrics as CloudWatch Embedded Metric Format (EMF) log lines.
+//
+// See https://docs.aws.amazon.com/AmazonCloudWatch/latest/monitoring/CloudWatch_Embedded_Metric_Format_Specification.html
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Unit string
+
+const (
+	Count        Unit = "Count"
+	Milliseconds Unit = "Milliseconds"
+	Seconds      Unit = "Seconds"
+	Bytes        Unit = "Bytes"
+	None         Unit = "None"
+)
+
+// EMF allows no more than 100 values for a metric in a single log line
+const maxValuesPerMetric = 100
+
+// Config configures a Logger
+type Config struct {
+	// The CloudWatch namespace for all metrics
+	Namespace string
+	// The dimension sets to publish. A set is only published for an entry which has a value for every dimension in it
+	DimensionSets [][]string
+	// Dimensions with fixed values which are added to every dimension set
+	DefaultDimensions map[string]string
+	// The number of recorded scopes after which a batch is flushed. Values of less than 1 mean every scope is flushed.
+	// Batching only spans the scopes recorded between flushes, so a caller which flushes at the end of every Lambda
+	// invocation, as front.WithMetrics does, batches within one invocation at most
+	BatchSize int
+	// The maximum age of a batch before it is flushed by FlushIfDue, if greater than zero
+	MaxBatchAge time.Duration
+	// Where EMF log lines are written. Defaults to os.Stdout
+	Writer io.Writer
+	// Clock for timestamps. Defaults to time.Now
+	Now func() time.Time
+}
+
+// Logger batches metrics recorded in Scopes and writes them as EMF log lines
+type Logger struct {
+	config   Config
+	mu       sync.Mutex
+	batches  map[string]*batch
+	order    []string
+	recorded int
+	started  time.Time
+}
+
+type batch struct {
+	timestamp  time.Time
+	dimensions map[string]string
+	units      map[string]Unit
+	values     map[string][]float64
+	names      []string
+}
+
+// NewLogger Create a new Logger object
+func NewLogger(config Config) *Logger {
+
+	if config.Writer == nil {
+		config.Writer = os.Stdout
+	}
+
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &Logger{
+		config:  config,
+		batches: map[string]*batch{},
+	}
+}
+
+// NewScope returns a Scope for collecting the metrics of a single unit of work such as a request
+func (logger *Logger) NewScope() *Scope {
+	return &Scope{
+		logger:     logger,
+		dimensions: map[string]string{},
+	}
+}
+
+// Record adds the metrics of a Scope to the current batch, flushing if the batch size is reached
+func (logger *Logger) Record(scope *Scope) {
+
+	if scope == nil || len(scope.metrics) == 0 {
+		return
+	}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	now := logger.config.Now()
+
+	if len(logger.batches) == 0 {
+		logger.started = now
+	}
+
+	dimensions := map[string]string{}
+
+	for k, v := range logger.config.DefaultDimensions {
+		dimensions[k] = v
+	}
+
+	for k, v := range scope.dimensions {
+		dimensions[k] = v
+	}
+
+	key := dimensionKey(dimensions)
+	b, ok := logger.batches[key]
+
+	if !ok {
+		b = &batch{
+			timestamp:  now,
+			dimensions: dimensions,
+			units:      map[string]Unit{},
+			values:     map[string][]float64{},
+		}
+		logger.batches[key] = b
+		logger.order = append(logger.order, key)
+	}
+
+	full := false
+
+	for _, m := range scope.metrics {
+
+		if _, ok := b.units[m.name]; !ok {
+			b.names = append(b.names, m.name)
+			b.units[m.name] = m.unit
+		}
+
+		b.values[m.name] = append(b.values[m.name], m.value)
+		full = full || len(b.values[m.name]) >= maxValuesPerMetric
+	}
+
+	logger.recorded++
+
+	if full || logger.recorded >= logger.config.BatchSize {
+		logger.flushLocked()
+	}
+}
+
+// FlushIfDue flushes the current batch if it is older than the maximum batch age
+func (logger *Logger) FlushIfDue() {
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	if len(logger.batches) == 0 {
+		return
+	}
+
+	if logger.config.MaxBatchAge <= 0 || logger.config.Now().Sub(logger.started) >= logger.config.MaxBatchAge {
+		logger.flushLocked()
+	}
+}
+
+// Flush writes all batched metrics
+func (logger *Logger) Flush() {
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	logger.flushLocked()
+}
+
+func (logger *Logger) flushLocked() {
+
+	for _, key := range logger.order {
+		logger.write(logger.batches[key])
+	}
+
+	logger.batches = map[string]*batch{}
+	logger.order = nil
+	logger.recorded = 0
+}
+
+func (logger *Logger) write(b *batch) {
+
+	type metricDefinition struct {
+		Name string `json:"Name"`
+		Unit Unit   `json:"Unit,omitempty"`
+	}
+
+	type directive struct {
+		Namespace  string             `json:"Namespace"`
+		Dimensions [][]string         `json:"Dimensions"`
+		Metrics    []metricDefinition `json:"Metrics"`
+	}
+
+	d := directive{
+		Namespace:  logger.config.Namespace,
+		Dimensions: logger.dimensionSets(b.dimensions),
+	}
+
+	line := map[string]interface{}{}
+
+	for k, v := range b.dimensions {
+		line[k] = v
+	}
+
+	for _, name := range b.names {
+
+		d.Metrics = append(d.Metrics, metricDefinition{Name: name, Unit: b.units[name]})
+
+		if values := b.values[name]; len(values) == 1 {
+			line[name] = values[0]
+		} else {
+			line[name] = values
+		}
+	}
+
+	line["_aws"] = struct {
+		Timestamp         int64       `json:"Timestamp"`
+		CloudWatchMetrics []directive `json:"CloudWatchMetrics"`
+	}{
+		Timestamp:         b.timestamp.UnixNano() / int64(time.Millisecond),
+		CloudWatchMetrics: []directive{d},
+	}
+
+	raw, err := json.Marshal(line)
+
+	if err != nil {
+		return
+	}
+
+	logger.config.Writer.Write(append(raw, '\n'))
+}
+
+// Returns the configured dimension sets for which every dimension has a value, each prefixed with the default dimensions
+func (logger *Logger) dimensionSets(dimensions map[string]string) [][]string {
+
+	var defaults []string
+
+	for k := range logger.config.DefaultDimensions {
+		defaults = append(defaults, k)
+	}
+
+	sort.Strings(defaults)
+
+	sets := [][]string{}
+
+	if len(defaults) > 0 {
+		sets = append(sets, defaults)
+	}
+
+	for _, set := range logger.config.DimensionSets {
+
+		complete := true
+
+		for _, name := range set {
+			if _, ok := dimensions[name]; !ok {
+				complete = false
+				break
+			}
+		}
+
+		if complete {
+			sets = append(sets, append(append([]string{}, defaults...), set...))
+		}
+	}
+
+	return sets
+}
+
+func dimensionKey(dimensions map[string]string) string {
+
+	var parts []string
+
+	for k, v := range dimensions {
+		parts = append(parts, k+"="+v)
+	}
+
+	sort.Strings(parts)
+
+	return strings.Join(parts, "\x00")
+}
+
+// Scope collects dimension values and metrics for a single unit of work
+//
+// A nil Scope is valid and discards everything, so handlers need not check whether metrics are enabled
+type Scope struct {
+	logger     *Logger
+	mu         sync.Mutex
+	dimensions map[string]string
+	metrics    []metric
+}
+
+type metric struct {
+	name  string
+	value float64
+	unit  Unit
+}
+
+// SetDimension sets a dimension value for all metrics in the scope
+func (scope *Scope) SetDimension(name, value string) {
+
+	if scope == nil {
+		return
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.dimensions[name] = value
+}
+
+// Put records a metric value with a unit
+func (scope *Scope) Put(name string, value float64, unit Unit) {
+
+	if scope == nil {
+		return
+	}
+
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	scope.metrics = append(scope.metrics, metric{name: name, value: value, unit: unit})
+}
+
+// Count records a counter metric
+func (scope *Scope) Count(name string, n int) {
+	scope.Put(name, float64(n), Count)
+}
+
+// Timer starts a timer and returns a function which records the elapsed time in milliseconds when called
+func (scope *Scope) Timer(name string) func() {
+
+	if scope == nil {
+		return func() {}
+	}
+
+	start := scope.logger.config.Now()
+
+	return func() {
+		scope.Put(name, float64(scope.logger.config.Now().Sub(start))/float64(time.Millisecond), Milliseconds)
+	}
+}
+
+type key struct{}
+
+// NewContext returns a context carrying a Scope
+func NewContext(parent context.Context, scope *Scope) context.Context {
+	return context.WithValue(parent, key{}, scope)
+}
+
+// FromContext returns the Scope carried by a context, or a nil Scope which discards metrics
+func FromContext(ctx context.Context) *Scope {
+
+	scope, _ := ctx.Value(key{}).(*Scope)
+
+	return scope
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+var testTime = time.Date(2019, 1, 2, 14, 52, 36, 0, time.UTC)
+
+func testLogger(buf *bytes.Buffer, batchSize int) *Logger {
+	return NewLogger(Config{
+		Namespace:         "Test",
+		DimensionSets:     [][]string{{"Route"}, {"Route", "Op"}},
+		DefaultDimensions: map[string]string{"Platform": "test"},
+		BatchSize:         batchSize,
+		Writer:            buf,
+		Now:               func() time.Time { return testTime },
+	})
+}
+
+func TestRecordSingleScope(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := testLogger(buf, 1)
+
+	scope := logger.NewScope()
+	scope.SetDimension("Route", "GET/status")
+	scope.Count("Requests", 1)
+	scope.Put("Latency", 12.5, Milliseconds)
+
+	logger.Record(scope)
+
+	expected := `{"Latency":12.5,"Platform":"test","Requests":1,"Route":"GET/status",` +
+		`"_aws":{"Timestamp":1546440756000,"CloudWatchMetrics":[{"Namespace":"Test",` +
+		`"Dimensions":[["Platform"],["Platform","Route"]],` +
+		`"Metrics":[{"Name":"Requests","Unit":"Count"},{"Name":"Latency","Unit":"Milliseconds"}]}]}}` + "\n"
+
+	utils.AssertEquals(t, "EMF log line", expected, buf.String())
+}
+
+func TestRecordBatching(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := testLogger(buf, 3)
+
+	for _, op := range []string{"add", "add", "divide"} {
+		scope := logger.NewScope()
+		scope.SetDimension("Route", "GET/calc/{op}")
+		scope.SetDimension("Op", op)
+		scope.Count("Requests", 1)
+		logger.Record(scope)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	utils.AssertEquals(t, "Number of EMF lines after batch is full", 2, len(lines))
+
+	var first struct {
+		Op       string
+		Requests []float64
+	}
+
+	err := json.Unmarshal([]byte(lines[0]), &first)
+
+	utils.AssertNoError(t, "First EMF line parses", err)
+	utils.AssertEquals(t, "First EMF line op", "add", first.Op)
+	utils.AssertEquals(t, "First EMF line batched values", 2, len(first.Requests))
+	utils.AssertTrue(t, "Second EMF line has op dimension set", strings.Contains(lines[1], `["Platform","Route","Op"]`))
+}
+
+func TestFlushIfDue(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	now := testTime
+
+	logger := NewLogger(Config{
+		Namespace:   "Test",
+		BatchSize:   10,
+		MaxBatchAge: time.Minute,
+		Writer:      buf,
+		Now:         func() time.Time { return now },
+	})
+
+	scope := logger.NewScope()
+	scope.Count("Requests", 1)
+	logger.Record(scope)
+	logger.FlushIfDue()
+
+	utils.AssertEquals(t, "Nothing written before the batch is due", "", buf.String())
+
+	now = now.Add(time.Minute)
+	logger.FlushIfDue()
+
+	utils.AssertTrue(t, "Batch written when due", strings.Contains(buf.String(), `"Requests":1`))
+}
+
+func TestScopeContext(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	logger := testLogger(buf, 1)
+
+	scope := logger.NewScope()
+	ctx := NewContext(context.Background(), scope)
+
+	utils.AssertTrue(t, "Scope from context", FromContext(ctx) == scope)
+
+	missing := FromContext(context.Background())
+
+	missing.Count("Ignored", 1)
+	missing.SetDimension("Ignored", "yes")
+	missing.Timer("Ignored")()
+
+	utils.AssertTrue(t, "Missing scope is nil", missing == nil)
+
+	stop := scope.Timer("Compute")
+	stop()
+	logger.Record(scope)
+
+	utils.AssertTrue(t, "Timer recorded", strings.Contains(buf.String(), `{"Name":"Compute","Unit":"Milliseconds"}`))
+}
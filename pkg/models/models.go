@@ -5,6 +5,9 @@ import (
 	"net/http"
 )
 
+// The models in api.go are generated from the Swagger definition in api.yaml
+//go:generate go run ../../cmd/models --template ../../api.yaml --out api.go
+
 type ApiError interface {
 	Error() string
 	StatusCode() int
@@ -2,8 +2,11 @@ package models
 
 import (
 	"errors"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/swagger"
 	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
 )
 
@@ -66,3 +69,23 @@ func TestErrorWrap(t *testing.T) {
 	utils.AssertEquals(t, "API error code", 123, err2.StatusCode())
 	utils.AssertEquals(t, "API error body", errBody2, err2.ErrorBody())
 }
+
+func TestModelsMatchTemplate(t *testing.T) {
+
+	template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+
+	utils.AssertNoError(t, "Read template", err)
+
+	doc, err := swagger.ParseTemplate(template, map[string]string{"Platform": "test"})
+
+	utils.AssertNoError(t, "Parse template", err)
+
+	generated, err := swagger.GenerateModels("models", doc.Definitions)
+
+	utils.AssertNoError(t, "Generate models", err)
+
+	current, err := ioutil.ReadFile("api.go")
+
+	utils.AssertNoError(t, "Read models", err)
+	utils.AssertTrue(t, "api.go is up to date with api.yaml: run go generate ./pkg/models", string(generated) == string(current))
+}
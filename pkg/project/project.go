@@ -0,0 +1,450 @@
+// The project package reads the project configuration file, which defines the environments (platforms) that the API
+// can be deployed to and the rules for deploying to each.
+package project
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The default path of the project configuration file
+const DefaultPath = "deploy.yaml"
+
+// The default directory of the deployment history
+const DefaultHistory = ".deploy/history"
+
+// The default artifact bucket name pattern
+const DefaultArtifactBucket = "{project}-artifacts-{account}-{region}"
+
+var namePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// Config is the project configuration
+type Config struct {
+	// The project name, which prefixes its artifacts
+	Project string `yaml:"project"`
+	// The CloudFormation template to deploy
+	Template string `yaml:"template"`
+	// The environment used when none is specified
+	DefaultEnvironment string `yaml:"defaultEnvironment"`
+	// The environments, by name. The name is passed to the template as the Platform parameter
+	Environments map[string]*Environment `yaml:"environments"`
+	// Where deployments are recorded: a directory, or an S3 location of the form s3://bucket/prefix
+	History string `yaml:"history"`
+	// Where lambda artifacts are kept
+	Artifacts Artifacts `yaml:"artifacts"`
+}
+
+// Artifacts configures the long-lived S3 bucket in each region in which lambda artifacts are kept, under a prefix per
+// project and platform
+type Artifacts struct {
+	// The bucket name pattern, in which {project}, {account} and {region} are replaced by the project name, AWS account
+	// ID and region. The bucket is created if it does not exist
+	Bucket string `yaml:"bucket"`
+	// If positive, a lifecycle rule expires artifacts this many days after upload, even if deployments refer to them.
+	// The gc command removes only artifacts which no recorded deployment refers to
+	ExpireDays int `yaml:"expireDays"`
+}
+
+// Environment defines how to deploy to a platform
+type Environment struct {
+	Name string `yaml:"-"`
+	// The subdomain pattern, in which {base} is replaced by the subdomain base and {platform} by the environment name
+	Subdomain string `yaml:"subdomain"`
+	// The stack name pattern, in which {platform} is replaced by the environment name
+	Stack string `yaml:"stack"`
+	// If set, deployments must be from a branch matching one of these patterns, which may use * wildcards
+	Branches []string `yaml:"branches"`
+	// If set, deployments must be exactly on a tag matching this regular expression
+	TagPattern string `yaml:"tagPattern"`
+	// If true, the release tag must be signed with a key trusted by git verify-tag. Requires TagPattern
+	SignedTag bool `yaml:"signedTag"`
+	// If set, this committed changelog file must have a heading for the release tag version. Requires TagPattern
+	Changelog string `yaml:"changelog"`
+	// If true, the branch must be in sync with its upstream on origin
+	RequireSync bool `yaml:"requireSync"`
+	// If true, the user must confirm the deployment
+	Confirm bool `yaml:"confirm"`
+	// The AWS region and profile to deploy with. Empty values use the AWS CLI defaults
+	Region  string `yaml:"region"`
+	Profile string `yaml:"profile"`
+	// Extra template parameters
+	Parameters map[string]string `yaml:"parameters"`
+	// The API Gateway endpoint type: edge (the default) or regional
+	Endpoint string `yaml:"endpoint"`
+	// How Route 53 routes the custom domain to the regions: simple (the default), latency or failover. Latency and
+	// failover routing require regional endpoints
+	Routing string `yaml:"routing"`
+	// The regions to deploy the same release to, in order, instead of Region
+	Regions []Region `yaml:"regions"`
+	// If true, the regions are deployed in parallel rather than in sequence
+	Parallel bool `yaml:"parallel"`
+	// The lambda custom runtime: provided.al2023 (the default) or provided.al2
+	Runtime string `yaml:"runtime"`
+	// The lambda architecture: x86_64 (the default) or arm64, for Graviton
+	Architecture string `yaml:"architecture"`
+}
+
+// Region is a region an environment is deployed to
+type Region struct {
+	Name string `yaml:"name"`
+	// For failover routing, primary or secondary
+	Failover string `yaml:"failover"`
+	// Extra template parameters for this region, overriding those of the environment
+	Parameters map[string]string `yaml:"parameters"`
+}
+
+// Endpoint types
+const (
+	EndpointEdge     = "edge"
+	EndpointRegional = "regional"
+)
+
+// Routing policies
+const (
+	RoutingSimple   = "simple"
+	RoutingLatency  = "latency"
+	RoutingFailover = "failover"
+)
+
+// Lambda runtimes
+const (
+	RuntimeAL2    = "provided.al2"
+	RuntimeAL2023 = "provided.al2023"
+)
+
+// Lambda architectures
+const (
+	ArchitectureX86   = "x86_64"
+	ArchitectureArm64 = "arm64"
+)
+
+// Failover roles
+const (
+	FailoverPrimary   = "primary"
+	FailoverSecondary = "secondary"
+)
+
+// Default returns the configuration used when there is no configuration file: test, stage and live environments with
+// live deployed without a suffix from a vN.N.N tag on master in sync with origin, after confirmation
+func Default() Config {
+
+	config := Config{
+		Template:           "api.yaml",
+		DefaultEnvironment: "test",
+		Environments: map[string]*Environment{
+			"test":  {},
+			"stage": {},
+			"live": {
+				Subdomain:   "{base}",
+				Branches:    []string{"master"},
+				TagPattern:  `^v[0-9]+\.[0-9]+\.[0-9]+$`,
+				RequireSync: true,
+				Confirm:     true,
+			},
+		},
+	}
+
+	config.applyDefaults()
+
+	return config
+}
+
+// Load reads the configuration file at path, or returns the default configuration if it does not exist
+func Load(path string) (Config, error) {
+
+	raw, err := ioutil.ReadFile(path)
+
+	if os.IsNotExist(err) {
+		return Default(), nil
+	}
+
+	if err != nil {
+		return Config{}, err
+	}
+
+	return Parse(raw)
+}
+
+// Parse parses and validates a configuration
+func Parse(raw []byte) (config Config, err error) {
+
+	if err = yaml.Unmarshal(raw, &config); err != nil {
+		return config, fmt.Errorf("Invalid project configuration: %v", err)
+	}
+
+	config.applyDefaults()
+
+	return config, config.Validate()
+}
+
+func (config *Config) applyDefaults() {
+
+	if config.Project == "" {
+		config.Project = "api"
+	}
+
+	if config.Template == "" {
+		config.Template = "api.yaml"
+	}
+
+	if config.Artifacts.Bucket == "" {
+		config.Artifacts.Bucket = DefaultArtifactBucket
+	}
+
+	if config.History == "" {
+		config.History = DefaultHistory
+	}
+
+	for name, env := range config.Environments {
+
+		if env == nil {
+			env = &Environment{}
+			config.Environments[name] = env
+		}
+
+		env.Name = name
+
+		if env.Subdomain == "" {
+			env.Subdomain = "{base}-{platform}"
+		}
+
+		if env.Stack == "" {
+			env.Stack = "api-stack-{platform}"
+		}
+
+		if env.Endpoint == "" {
+			env.Endpoint = EndpointEdge
+		}
+
+		if env.Routing == "" {
+			env.Routing = RoutingSimple
+		}
+
+		if env.Runtime == "" {
+			env.Runtime = RuntimeAL2023
+		}
+
+		if env.Architecture == "" {
+			env.Architecture = ArchitectureX86
+		}
+	}
+}
+
+// Validate returns an error listing every problem with the configuration
+func (config Config) Validate() error {
+
+	var problems []string
+
+	if len(config.Environments) == 0 {
+		problems = append(problems, "no environments are defined")
+	}
+
+	if !namePattern.MatchString(config.Project) {
+		problems = append(problems, fmt.Sprintf("project name %v does not match pattern %v", config.Project, namePattern))
+	}
+
+	// Stacks can only use code in their own region
+	if !strings.Contains(config.Artifacts.Bucket, "{region}") {
+		problems = append(problems, fmt.Sprintf("artifact bucket %v does not contain {region}", config.Artifacts.Bucket))
+	}
+
+	if config.Artifacts.ExpireDays < 0 {
+		problems = append(problems, "artifact expiry days must not be negative")
+	}
+
+	if config.DefaultEnvironment != "" {
+		if _, ok := config.Environments[config.DefaultEnvironment]; !ok {
+			problems = append(problems, fmt.Sprintf("default environment %v is not defined", config.DefaultEnvironment))
+		}
+	}
+
+	for _, name := range config.EnvironmentNames() {
+
+		env := config.Environments[name]
+
+		if !namePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("environment name %v does not match pattern %v", name, namePattern))
+		}
+
+		if !strings.Contains(env.Subdomain, "{base}") {
+			problems = append(problems, fmt.Sprintf("%v subdomain %v does not contain {base}", name, env.Subdomain))
+		}
+
+		if env.TagPattern != "" {
+			if _, err := regexp.Compile(env.TagPattern); err != nil {
+				problems = append(problems, fmt.Sprintf("%v tag pattern is invalid: %v", name, err))
+			}
+		} else if env.SignedTag || env.Changelog != "" {
+			problems = append(problems, fmt.Sprintf("%v requires a tag pattern for signed tag or changelog checks", name))
+		}
+
+		if env.Runtime != RuntimeAL2 && env.Runtime != RuntimeAL2023 {
+			problems = append(problems, fmt.Sprintf("%v runtime must be %v or %v, not %v", name, RuntimeAL2023, RuntimeAL2, env.Runtime))
+		}
+
+		if env.Architecture != ArchitectureX86 && env.Architecture != ArchitectureArm64 {
+			problems = append(problems, fmt.Sprintf("%v architecture must be %v or %v, not %v", name, ArchitectureX86, ArchitectureArm64, env.Architecture))
+		}
+
+		problems = append(problems, env.regionProblems()...)
+	}
+
+	if len(problems) > 0 {
+		return errors.New("Invalid project configuration: " + strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func (env Environment) regionProblems() []string {
+
+	var problems []string
+
+	if env.Endpoint != EndpointEdge && env.Endpoint != EndpointRegional {
+		problems = append(problems, fmt.Sprintf("%v endpoint must be edge or regional, not %v", env.Name, env.Endpoint))
+	}
+
+	switch env.Routing {
+	case RoutingSimple:
+		if len(env.Regions) > 1 {
+			problems = append(problems, fmt.Sprintf("%v has several regions, so needs latency or failover routing", env.Name))
+		}
+	case RoutingLatency, RoutingFailover:
+		if env.Endpoint != EndpointRegional {
+			problems = append(problems, fmt.Sprintf("%v %v routing requires a regional endpoint", env.Name, env.Routing))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%v routing must be simple, latency or failover, not %v", env.Name, env.Routing))
+	}
+
+	if env.Region != "" && len(env.Regions) > 0 {
+		problems = append(problems, fmt.Sprintf("%v sets both region and regions", env.Name))
+	}
+
+	seen := map[string]bool{}
+	primaries := 0
+
+	for _, region := range env.Regions {
+
+		if region.Name == "" {
+			problems = append(problems, fmt.Sprintf("%v has a region without a name", env.Name))
+		} else if seen[region.Name] {
+			problems = append(problems, fmt.Sprintf("%v has region %v more than once", env.Name, region.Name))
+		}
+
+		seen[region.Name] = true
+
+		switch {
+		case env.Routing != RoutingFailover && region.Failover != "":
+			problems = append(problems, fmt.Sprintf("%v region %v sets failover without failover routing", env.Name, region.Name))
+		case env.Routing == RoutingFailover && region.Failover == FailoverPrimary:
+			primaries++
+		case env.Routing == RoutingFailover && region.Failover != FailoverSecondary:
+			problems = append(problems, fmt.Sprintf("%v region %v failover must be primary or secondary", env.Name, region.Name))
+		}
+	}
+
+	if env.Routing == RoutingFailover && primaries != 1 {
+		problems = append(problems, fmt.Sprintf("%v failover routing needs exactly one primary region", env.Name))
+	}
+
+	return problems
+}
+
+// EnvironmentNames returns the names of the environments in sorted order
+func (config Config) EnvironmentNames() []string {
+
+	names := make([]string, 0, len(config.Environments))
+
+	for name := range config.Environments {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Environment returns the named environment, or the default environment if name is empty
+func (config Config) Environment(name string) (*Environment, error) {
+
+	if name == "" {
+		name = config.DefaultEnvironment
+	}
+
+	env, ok := config.Environments[name]
+
+	if !ok {
+		return nil, fmt.Errorf("Platform must be one of %v", strings.Join(config.EnvironmentNames(), ", "))
+	}
+
+	return env, nil
+}
+
+// SubdomainFor returns the subdomain for a subdomain base in this environment
+func (env Environment) SubdomainFor(base string) string {
+	return env.expand(env.Subdomain, base)
+}
+
+// StackName returns the CloudFormation stack name for this environment
+func (env Environment) StackName() string {
+	return env.expand(env.Stack, "")
+}
+
+func (env Environment) expand(pattern, base string) string {
+	return strings.NewReplacer("{base}", base, "{platform}", env.Name).Replace(pattern)
+}
+
+// Targets returns the regions to deploy to: the configured regions, or else a single region of Region, which is empty
+// for the AWS CLI default
+func (env Environment) Targets() []Region {
+
+	if len(env.Regions) > 0 {
+		return env.Regions
+	}
+
+	return []Region{{Name: env.Region}}
+}
+
+// GOARCH returns the Go architecture to build the lambda for
+func (env Environment) GOARCH() string {
+
+	if env.Architecture == ArchitectureArm64 {
+		return "arm64"
+	}
+
+	return "amd64"
+}
+
+// ArtifactBucket returns the name of the artifact bucket for an AWS account and region
+func (config Config) ArtifactBucket(account, region string) string {
+	return strings.NewReplacer("{project}", config.Project, "{account}", account, "{region}", region).Replace(config.Artifacts.Bucket)
+}
+
+// ArtifactPrefix returns the key prefix of the artifacts of a platform
+func (config Config) ArtifactPrefix(platform string) string {
+	return config.Project + "/" + platform + "/"
+}
+
+// AwsEnv returns environment variables selecting the region and profile for the AWS CLI
+func (env Environment) AwsEnv() []string {
+
+	var vars []string
+
+	if env.Region != "" {
+		vars = append(vars, "AWS_REGION="+env.Region, "AWS_DEFAULT_REGION="+env.Region)
+	}
+
+	if env.Profile != "" {
+		vars = append(vars, "AWS_PROFILE="+env.Profile)
+	}
+
+	return vars
+}
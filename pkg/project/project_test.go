@@ -0,0 +1,215 @@
+package project
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const testConfig = `
+template: api.yaml
+defaultEnvironment: dev
+environments:
+  dev:
+    region: eu-west-2
+    profile: sandbox
+    runtime: provided.al2
+    architecture: arm64
+  qa:
+    subdomain: "qa-{base}"
+    stack: "sample-{platform}"
+    parameters:
+      ApiLambdaNameBase: QaLambda
+  prod:
+    subdomain: "{base}"
+    branches: [main]
+    signedTag: true
+    changelog: CHANGELOG.md
+    tagPattern: '^release-[0-9]+$'
+    requireSync: true
+    confirm: true
+`
+
+func TestParse(t *testing.T) {
+
+	config, err := Parse([]byte(testConfig))
+
+	utils.AssertNoError(t, "Parse", err)
+
+	dev, err := config.Environment("")
+
+	utils.AssertNoError(t, "Default environment", err)
+	utils.AssertEquals(t, "Default environment name", "dev", dev.Name)
+	utils.AssertEquals(t, "Default subdomain", "api-dev", dev.SubdomainFor("api"))
+	utils.AssertEquals(t, "Default stack name", "api-stack-dev", dev.StackName())
+	utils.AssertEquals(t, "Configured runtime", RuntimeAL2, dev.Runtime)
+	utils.AssertEquals(t, "Arm GOARCH", "arm64", dev.GOARCH())
+	utils.AssertEquals(t, "AWS environment", "AWS_REGION=eu-west-2 AWS_DEFAULT_REGION=eu-west-2 AWS_PROFILE=sandbox", strings.Join(dev.AwsEnv(), " "))
+
+	qa, _ := config.Environment("qa")
+
+	utils.AssertEquals(t, "Custom subdomain", "qa-api", qa.SubdomainFor("api"))
+	utils.AssertEquals(t, "Custom stack name", "sample-qa", qa.StackName())
+	utils.AssertEquals(t, "Extra parameter", "QaLambda", qa.Parameters["ApiLambdaNameBase"])
+	utils.AssertEquals(t, "Default runtime", RuntimeAL2023, qa.Runtime)
+	utils.AssertEquals(t, "Default GOARCH", "amd64", qa.GOARCH())
+
+	prod, _ := config.Environment("prod")
+
+	utils.AssertEquals(t, "Prod subdomain", "api", prod.SubdomainFor("api"))
+	utils.AssertEquals(t, "Prod branch", "main", prod.Branches[0])
+	utils.AssertEquals(t, "Prod changelog", "CHANGELOG.md", prod.Changelog)
+	utils.AssertTrue(t, "Prod signed tag", prod.SignedTag)
+	utils.AssertTrue(t, "Prod confirmation", prod.Confirm)
+	utils.AssertTrue(t, "Prod sync", prod.RequireSync)
+
+	_, err = config.Environment("live")
+
+	utils.AssertErrorEquals(t, "Unknown environment", "Platform must be one of dev, prod, qa", err)
+}
+
+func TestValidate(t *testing.T) {
+
+	_, err := Parse([]byte(`
+project: My_API
+defaultEnvironment: missing
+artifacts:
+  bucket: api-artifacts
+  expireDays: -1
+environments:
+  Bad_Name:
+    subdomain: fixed
+    tagPattern: "["
+  other:
+    signedTag: true
+    runtime: go1.x
+    architecture: arm
+`))
+
+	utils.AssertErrorEquals(t, "Invalid configuration", "Invalid project configuration: project name My_API does not match pattern ^[a-z0-9-]+$; "+
+		"artifact bucket api-artifacts does not contain {region}; artifact expiry days must not be negative; default environment missing is not defined; "+
+		"environment name Bad_Name does not match pattern ^[a-z0-9-]+$; Bad_Name subdomain fixed does not contain {base}; "+
+		"Bad_Name tag pattern is invalid: error parsing regexp: missing closing ]: `[`; "+
+		"other requires a tag pattern for signed tag or changelog checks; "+
+		"other runtime must be provided.al2023 or provided.al2, not go1.x; "+
+		"other architecture must be x86_64 or arm64, not arm", err)
+
+	_, err = Parse([]byte(`environments: []`))
+
+	utils.AssertTrue(t, "Malformed configuration", err != nil)
+}
+
+func TestLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "project")
+
+	utils.AssertNoError(t, "Temp dir", err)
+
+	defer os.RemoveAll(dir)
+
+	config, err := Load(filepath.Join(dir, "missing.yaml"))
+
+	utils.AssertNoError(t, "Load missing", err)
+
+	live, err := config.Environment("live")
+
+	utils.AssertNoError(t, "Default live environment", err)
+	utils.AssertEquals(t, "Default live subdomain", "api", live.SubdomainFor("api"))
+	utils.AssertEquals(t, "Default live stack", "api-stack-live", live.StackName())
+
+	test, _ := config.Environment("")
+
+	utils.AssertEquals(t, "Default test subdomain", "api-test", test.SubdomainFor("api"))
+
+	path := filepath.Join(dir, "deploy.yaml")
+	ioutil.WriteFile(path, []byte(testConfig), 0644)
+
+	config, err = Load(path)
+
+	utils.AssertNoError(t, "Load file", err)
+	utils.AssertEquals(t, "Loaded default environment", "dev", config.DefaultEnvironment)
+	utils.AssertEquals(t, "Default history", DefaultHistory, config.History)
+	utils.AssertEquals(t, "Default artifact bucket", "api-artifacts-123456789012-eu-west-2", config.ArtifactBucket("123456789012", "eu-west-2"))
+	utils.AssertEquals(t, "Artifact prefix", "api/dev/", config.ArtifactPrefix("dev"))
+}
+
+func TestRepositoryConfigMatchesDefault(t *testing.T) {
+
+	config, err := Load(filepath.Join("..", "..", DefaultPath))
+
+	utils.AssertNoError(t, "Load repository configuration", err)
+	utils.AssertTrue(t, "Repository configuration matches default", reflect.DeepEqual(Default(), config))
+}
+
+func TestRegions(t *testing.T) {
+
+	config, err := Parse([]byte(`
+environments:
+  live:
+    endpoint: regional
+    routing: failover
+    parallel: true
+    regions:
+    - name: eu-west-1
+      failover: primary
+    - name: us-east-1
+      failover: secondary
+      parameters:
+        ApiLambdaNameBase: UsLambda
+  test:
+    region: eu-west-2
+`))
+
+	utils.AssertNoError(t, "Parse regions", err)
+
+	live := config.Environments["live"]
+
+	utils.AssertEquals(t, "Live target count", 2, len(live.Targets()))
+	utils.AssertEquals(t, "Secondary region", "us-east-1", live.Targets()[1].Name)
+	utils.AssertEquals(t, "Region parameter", "UsLambda", live.Targets()[1].Parameters["ApiLambdaNameBase"])
+	utils.AssertTrue(t, "Parallel", live.Parallel)
+
+	test := config.Environments["test"]
+
+	utils.AssertEquals(t, "Default endpoint", EndpointEdge, test.Endpoint)
+	utils.AssertEquals(t, "Default routing", RoutingSimple, test.Routing)
+	utils.AssertEquals(t, "Single target", "eu-west-2", test.Targets()[0].Name)
+
+	_, err = Parse([]byte(`
+environments:
+  edge:
+    routing: latency
+    region: eu-west-1
+    regions:
+    - name: eu-west-1
+    - name: eu-west-1
+  simple:
+    endpoint: private
+    regions:
+    - name: eu-west-1
+      failover: primary
+    - name: us-east-1
+  failover:
+    endpoint: regional
+    routing: failover
+    regions:
+    - name: eu-west-1
+    - failover: secondary
+`))
+
+	utils.AssertErrorEquals(t, "Region problems", "Invalid project configuration: "+
+		"edge latency routing requires a regional endpoint; "+
+		"edge sets both region and regions; "+
+		"edge has region eu-west-1 more than once; "+
+		"failover region eu-west-1 failover must be primary or secondary; "+
+		"failover has a region without a name; "+
+		"failover failover routing needs exactly one primary region; "+
+		"simple endpoint must be edge or regional, not private; "+
+		"simple has several regions, so needs latency or failover routing; "+
+		"simple region eu-west-1 sets failover without failover routing", err)
+}
@@ -0,0 +1,138 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/guard"
+)
+
+// Plan is a release resolved from the repository before it is tagged
+type Plan struct {
+	// The latest release tag, or empty if there is none
+	Previous string
+	Bump     Bump
+	Version  Version
+	// The messages of the commits since the previous release
+	Commits []string
+}
+
+// Releaser computes and tags releases of a git repository
+type Releaser struct {
+	Repo guard.Repo
+	// The remote which release tags are pushed to. Defaults to origin
+	Remote string
+}
+
+// Plan computes the next release of HEAD. If bump is None, it is computed from the conventional commit messages since
+// the previous release. A non-empty label makes the release a numbered pre-release, such as v1.3.0-rc.1
+func (releaser Releaser) Plan(ctx context.Context, bump Bump, label string) (plan Plan, err error) {
+
+	out, err := releaser.Repo.Git(ctx, "tag", "--list", "v*")
+
+	if err != nil {
+		return
+	}
+
+	versions := Versions(strings.Fields(out))
+
+	if latest, ok := Latest(versions); ok {
+		plan.Previous = latest.String()
+	}
+
+	if err = releaser.checkUnreleased(ctx); err != nil {
+		return
+	}
+
+	if plan.Commits, err = releaser.commits(ctx, plan.Previous); err != nil {
+		return
+	}
+
+	plan.Bump = bump
+
+	if plan.Bump == None {
+
+		if len(plan.Commits) == 0 {
+			return plan, fmt.Errorf("No commits since %v", plan.Previous)
+		}
+
+		plan.Bump = ConventionalBump(plan.Commits)
+	}
+
+	plan.Version = Next(versions, plan.Bump, label)
+
+	return
+}
+
+func (releaser Releaser) checkUnreleased(ctx context.Context) error {
+
+	out, err := releaser.Repo.Git(ctx, "tag", "--points-at", "HEAD")
+
+	if err != nil {
+		return err
+	}
+
+	for _, version := range Versions(strings.Fields(out)) {
+		if !version.IsPreRelease() {
+			return fmt.Errorf("HEAD is already released as %v", version)
+		}
+	}
+
+	return nil
+}
+
+func (releaser Releaser) commits(ctx context.Context, previous string) ([]string, error) {
+
+	revisions := "HEAD"
+
+	if previous != "" {
+		revisions = previous + "..HEAD"
+	}
+
+	out, err := releaser.Repo.Git(ctx, "log", "--format=%B%x00", revisions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []string
+
+	for _, message := range strings.Split(out, "\x00") {
+		if message = strings.TrimSpace(message); message != "" {
+			commits = append(commits, message)
+		}
+	}
+
+	return commits, nil
+}
+
+// Tag creates an annotated tag for the planned version on HEAD, and pushes it if push is true. The deploy command
+// passes the tag to the API as its release
+func (releaser Releaser) Tag(ctx context.Context, plan Plan, push bool) error {
+
+	if plan.Bump == None {
+		return errors.New("Nothing to release")
+	}
+
+	tag := plan.Version.String()
+
+	if _, err := releaser.Repo.Git(ctx, "tag", "-a", "-m", "Release "+tag, tag); err != nil {
+		return err
+	}
+
+	if !push {
+		return nil
+	}
+
+	remote := releaser.Remote
+
+	if remote == "" {
+		remote = "origin"
+	}
+
+	_, err := releaser.Repo.Git(ctx, "push", remote, tag)
+
+	return err
+}
@@ -0,0 +1,103 @@
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/guard"
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+type fakeGit struct {
+	outputs map[string]string
+	calls   []string
+}
+
+func (git *fakeGit) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
+
+	command := strings.Join(append([]string{name}, args...), " ")
+	git.calls = append(git.calls, command)
+
+	if strings.HasPrefix(command, "git log") || strings.HasPrefix(command, "git tag --") {
+		return []byte(git.outputs[command]), nil
+	}
+
+	if output, ok := git.outputs[command]; ok {
+		return []byte(output), nil
+	}
+
+	return nil, errors.New("unexpected command " + command)
+}
+
+func testReleaser() (Releaser, *fakeGit) {
+
+	git := &fakeGit{outputs: map[string]string{
+		"git tag --list v*":                             "v1.0.0\nv1.1.0\nv1.2.0-rc.1\n",
+		"git log --format=%B%x00 v1.1.0..HEAD":          "fix: handle NaN\n\x00\nfeat(calc): add pow\n\nCloses #4\n\x00\n",
+		"git tag -a -m Release v1.2.0-rc.2 v1.2.0-rc.2": "",
+		"git tag -a -m Release v1.2.0 v1.2.0":           "",
+		"git push origin v1.2.0":                        "",
+	}}
+
+	return Releaser{Repo: guard.Repo{Runner: git}}, git
+}
+
+func TestReleasePlan(t *testing.T) {
+
+	releaser, _ := testReleaser()
+
+	plan, err := releaser.Plan(context.Background(), None, "")
+
+	utils.AssertNoError(t, "Conventional plan", err)
+	utils.AssertEquals(t, "Previous", "v1.1.0", plan.Previous)
+	utils.AssertEquals(t, "Conventional bump", Minor, plan.Bump)
+	utils.AssertEquals(t, "Conventional version", "v1.2.0", plan.Version.String())
+	utils.AssertEquals(t, "Commits", "[fix: handle NaN feat(calc): add pow\n\nCloses #4]", fmt.Sprint(plan.Commits))
+
+	plan, err = releaser.Plan(context.Background(), Patch, "rc")
+
+	utils.AssertNoError(t, "Explicit pre-release plan", err)
+	utils.AssertEquals(t, "Explicit pre-release version", "v1.1.1-rc.1", plan.Version.String())
+
+	plan, err = releaser.Plan(context.Background(), None, "rc")
+
+	utils.AssertNoError(t, "Conventional pre-release plan", err)
+	utils.AssertEquals(t, "Conventional pre-release version", "v1.2.0-rc.2", plan.Version.String())
+}
+
+func TestReleasePlanFailures(t *testing.T) {
+
+	releaser, git := testReleaser()
+
+	git.outputs["git log --format=%B%x00 v1.1.0..HEAD"] = ""
+
+	_, err := releaser.Plan(context.Background(), None, "")
+
+	utils.AssertErrorEquals(t, "No commits", "No commits since v1.1.0", err)
+
+	git.outputs["git tag --points-at HEAD"] = "v1.2.0-rc.1\nv1.1.0\n"
+
+	_, err = releaser.Plan(context.Background(), Patch, "")
+
+	utils.AssertErrorEquals(t, "Already released", "HEAD is already released as v1.1.0", err)
+}
+
+func TestReleaseTag(t *testing.T) {
+
+	releaser, git := testReleaser()
+
+	plan, _ := releaser.Plan(context.Background(), None, "")
+
+	utils.AssertNoError(t, "Tag and push", releaser.Tag(context.Background(), plan, true))
+	utils.AssertEquals(t, "Push command", "git push origin v1.2.0", git.calls[len(git.calls)-1])
+
+	plan, _ = releaser.Plan(context.Background(), None, "rc")
+
+	utils.AssertNoError(t, "Tag pre-release", releaser.Tag(context.Background(), plan, false))
+	utils.AssertEquals(t, "Tag command", "git tag -a -m Release v1.2.0-rc.2 v1.2.0-rc.2", git.calls[len(git.calls)-1])
+
+	utils.AssertErrorEquals(t, "Nothing to release", "Nothing to release", releaser.Tag(context.Background(), Plan{}, false))
+}
@@ -0,0 +1,237 @@
+// The release package computes semantic versions for releases from the existing version tags of a git repository,
+// either by an explicit major, minor or patch bump or from the conventional commit messages since the last release,
+// and creates annotated release tags.
+package release
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var versionPattern = regexp.MustCompile(`^v([0-9]+)\.([0-9]+)\.([0-9]+)(?:-([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version, tagged as vMAJOR.MINOR.PATCH with an optional -PRE pre-release suffix
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// ParseVersion parses a version tag
+func ParseVersion(tag string) (Version, error) {
+
+	match := versionPattern.FindStringSubmatch(tag)
+
+	if match == nil {
+		return Version{}, fmt.Errorf("%v is not a version tag of the form vN.N.N", tag)
+	}
+
+	var version Version
+
+	version.Major, _ = strconv.Atoi(match[1])
+	version.Minor, _ = strconv.Atoi(match[2])
+	version.Patch, _ = strconv.Atoi(match[3])
+	version.Pre = match[4]
+
+	return version, nil
+}
+
+func (version Version) String() string {
+
+	tag := fmt.Sprintf("v%d.%d.%d", version.Major, version.Minor, version.Patch)
+
+	if version.Pre != "" {
+		tag += "-" + version.Pre
+	}
+
+	return tag
+}
+
+// IsPreRelease returns true if the version has a pre-release suffix
+func (version Version) IsPreRelease() bool {
+	return version.Pre != ""
+}
+
+// Less returns true if the version has lower precedence than other. A pre-release has lower precedence than its
+// release, and pre-release identifiers are compared as numbers if both are numeric
+func (version Version) Less(other Version) bool {
+
+	if version.Major != other.Major {
+		return version.Major < other.Major
+	}
+
+	if version.Minor != other.Minor {
+		return version.Minor < other.Minor
+	}
+
+	if version.Patch != other.Patch {
+		return version.Patch < other.Patch
+	}
+
+	if version.Pre == "" || other.Pre == "" {
+		return version.Pre != "" && other.Pre == ""
+	}
+
+	return lessPre(strings.Split(version.Pre, "."), strings.Split(other.Pre, "."))
+}
+
+func lessPre(a, b []string) bool {
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+
+		if a[i] == b[i] {
+			continue
+		}
+
+		an, aErr := strconv.Atoi(a[i])
+		bn, bErr := strconv.Atoi(b[i])
+
+		switch {
+		case aErr == nil && bErr == nil:
+			return an < bn
+		case aErr == nil:
+			return true
+		case bErr == nil:
+			return false
+		default:
+			return a[i] < b[i]
+		}
+	}
+
+	return len(a) < len(b)
+}
+
+// Bump is a kind of version increment
+type Bump int
+
+const (
+	None Bump = iota
+	Patch
+	Minor
+	Major
+)
+
+// ParseBump parses major, minor or patch
+func ParseBump(s string) (Bump, error) {
+
+	switch s {
+	case "major":
+		return Major, nil
+	case "minor":
+		return Minor, nil
+	case "patch":
+		return Patch, nil
+	}
+
+	return None, fmt.Errorf("Bump must be one of major, minor, patch")
+}
+
+func (bump Bump) String() string {
+	return [...]string{"none", "patch", "minor", "major"}[bump]
+}
+
+// Bump returns the release version incremented by bump, without a pre-release suffix
+func (version Version) Bump(bump Bump) Version {
+
+	switch bump {
+	case Major:
+		return Version{Major: version.Major + 1}
+	case Minor:
+		return Version{Major: version.Major, Minor: version.Minor + 1}
+	case Patch:
+		return Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch + 1}
+	}
+
+	return Version{Major: version.Major, Minor: version.Minor, Patch: version.Patch}
+}
+
+var conventionalPattern = regexp.MustCompile(`^([a-z]+)(\([^)]*\))?(!)?:`)
+
+// ConventionalBump returns the bump implied by conventional commit messages: major for a breaking change (a "!" after
+// the type or a BREAKING CHANGE footer), minor for a feat, patch for any other commit and None if there are none
+func ConventionalBump(messages []string) Bump {
+
+	bump := None
+
+	for _, message := range messages {
+
+		next := Patch
+		match := conventionalPattern.FindStringSubmatch(message)
+
+		switch {
+		case match != nil && match[3] == "!",
+			strings.Contains(message, "BREAKING CHANGE:"),
+			strings.Contains(message, "BREAKING-CHANGE:"):
+			next = Major
+		case match != nil && match[1] == "feat":
+			next = Minor
+		}
+
+		if next > bump {
+			bump = next
+		}
+	}
+
+	return bump
+}
+
+// Versions returns the version tags among tags in ascending order, ignoring tags which are not versions
+func Versions(tags []string) []Version {
+
+	var versions []Version
+
+	for _, tag := range tags {
+		if version, err := ParseVersion(tag); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Less(versions[j])
+	})
+
+	return versions
+}
+
+// Latest returns the highest release (not pre-release) version among versions, and false if there is none
+func Latest(versions []Version) (Version, bool) {
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].IsPreRelease() {
+			return versions[i], true
+		}
+	}
+
+	return Version{}, false
+}
+
+// Next returns the next version after the latest release among versions. With a pre-release label, the version is the
+// next numbered pre-release of the bumped version, such as v1.3.0-rc.2 after v1.3.0-rc.1
+func Next(versions []Version, bump Bump, label string) Version {
+
+	latest, _ := Latest(versions)
+	next := latest.Bump(bump)
+
+	if label == "" {
+		return next
+	}
+
+	number := 0
+
+	for _, version := range versions {
+
+		if version.Major != next.Major || version.Minor != next.Minor || version.Patch != next.Patch {
+			continue
+		}
+
+		if n, err := strconv.Atoi(strings.TrimPrefix(version.Pre, label+".")); err == nil && strings.HasPrefix(version.Pre, label+".") && n > number {
+			number = n
+		}
+	}
+
+	next.Pre = fmt.Sprintf("%v.%d", label, number+1)
+
+	return next
+}
@@ -0,0 +1,79 @@
+package release
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestParseVersion(t *testing.T) {
+
+	version, err := ParseVersion("v1.12.3-rc.2")
+
+	utils.AssertNoError(t, "Parse pre-release", err)
+	utils.AssertEquals(t, "Parsed pre-release", Version{Major: 1, Minor: 12, Patch: 3, Pre: "rc.2"}, version)
+	utils.AssertEquals(t, "Formatted pre-release", "v1.12.3-rc.2", version.String())
+
+	_, err = ParseVersion("1.2.3")
+
+	utils.AssertErrorEquals(t, "Missing v", "1.2.3 is not a version tag of the form vN.N.N", err)
+
+	_, err = ParseVersion("v1.2")
+
+	utils.AssertErrorEquals(t, "Missing patch", "v1.2 is not a version tag of the form vN.N.N", err)
+}
+
+func TestVersionOrder(t *testing.T) {
+
+	versions := Versions([]string{"v1.10.0", "latest", "v1.2.0", "v1.10.0-rc.10", "v1.10.0-rc.2", "v1.10.0-beta", "v0.9.9"})
+
+	var tags []string
+
+	for _, version := range versions {
+		tags = append(tags, version.String())
+	}
+
+	utils.AssertEquals(t, "Sorted versions", "[v0.9.9 v1.2.0 v1.10.0-beta v1.10.0-rc.2 v1.10.0-rc.10 v1.10.0]", fmt.Sprint(tags))
+
+	latest, ok := Latest(versions)
+
+	utils.AssertTrue(t, "Has latest", ok)
+	utils.AssertEquals(t, "Latest", "v1.10.0", latest.String())
+
+	_, ok = Latest(Versions([]string{"v1.0.0-rc.1"}))
+
+	utils.AssertFalse(t, "No latest release", ok)
+}
+
+func TestConventionalBump(t *testing.T) {
+
+	scenarios := []struct {
+		messages []string
+		expected Bump
+	}{
+		{nil, None},
+		{[]string{"Update README"}, Patch},
+		{[]string{"fix(calc): handle NaN", "docs: describe calc"}, Patch},
+		{[]string{"fix: handle NaN", "feat(calc): add pow"}, Minor},
+		{[]string{"feat!: remove /calc/add"}, Major},
+		{[]string{"refactor: rename handler\n\nBREAKING CHANGE: routes renamed"}, Major},
+	}
+
+	for _, scenario := range scenarios {
+		utils.AssertEquals(t, fmt.Sprint(scenario.messages), scenario.expected, ConventionalBump(scenario.messages))
+	}
+}
+
+func TestNext(t *testing.T) {
+
+	versions := Versions([]string{"v1.2.3", "v1.3.0-rc.1", "v1.3.0-rc.2", "v1.3.0-beta.4"})
+
+	utils.AssertEquals(t, "Patch", "v1.2.4", Next(versions, Patch, "").String())
+	utils.AssertEquals(t, "Minor", "v1.3.0", Next(versions, Minor, "").String())
+	utils.AssertEquals(t, "Major", "v2.0.0", Next(versions, Major, "").String())
+	utils.AssertEquals(t, "Next rc", "v1.3.0-rc.3", Next(versions, Minor, "rc").String())
+	utils.AssertEquals(t, "Next beta", "v1.3.0-beta.5", Next(versions, Minor, "beta").String())
+	utils.AssertEquals(t, "First rc", "v2.0.0-rc.1", Next(versions, Major, "rc").String())
+	utils.AssertEquals(t, "First release", "v0.1.0", Next(nil, Minor, "").String())
+}
@@ -0,0 +1,274 @@
+package swagger
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// How the client formats parameters of each Go type, where %v is the value
+var parameterFormats = map[string]string{
+	"string":  "%v",
+	"float64": "strconv.FormatFloat(%v, 'f', -1, 64)",
+	"int":     "strconv.Itoa(%v)",
+	"bool":    "strconv.FormatBool(%v)",
+}
+
+var pathVariable = regexp.MustCompile(`\{([^}]+)\}`)
+
+// GenerateClient generates a Go source file with a method of a Client type for every operation of the API which
+// integrates with a lambda. A method receives the operation's parameters in a struct if it has any, and returns the
+// schema of its successful response decoded into a type of the models package at modelsImport. The package must
+// declare the Client type with a method of the form
+//
+//	do(ctx context.Context, method, path string, query url.Values, header http.Header, result interface{}) error
+//
+// which makes the request and decodes a successful response into result, unless it is nil
+func GenerateClient(pkg, modelsImport string, doc Document) ([]byte, error) {
+
+	imports := map[string]bool{"context": true}
+	body := &strings.Builder{}
+
+	for _, route := range integratedRoutes(doc) {
+		if err := writeClientMethod(body, imports, route); err != nil {
+			return nil, fmt.Errorf("Cannot generate %v %v: %v", route.Method, route.Path, err)
+		}
+	}
+
+	var paths []string
+
+	for path := range imports {
+		if path != "models" {
+			paths = append(paths, path)
+		}
+	}
+
+	sort.Strings(paths)
+
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "%v\n\npackage %v\n\nimport (\n", header, pkg)
+
+	for _, path := range paths {
+		fmt.Fprintf(buf, "\t%q\n", path)
+	}
+
+	if imports["models"] {
+		fmt.Fprintf(buf, "\n\t%q\n", modelsImport)
+	}
+
+	fmt.Fprintf(buf, ")\n%v", body)
+
+	return format.Source(buf.Bytes())
+}
+
+// Writes the client method of a route, and its parameters struct, adding the imports they need
+func writeClientMethod(buf *strings.Builder, imports map[string]bool, route Route) error {
+
+	parameters, err := routeParameters(route)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := successType(route)
+
+	if err != nil {
+		return err
+	}
+
+	name := operationName(route)
+	signature := "ctx context.Context"
+	query := &strings.Builder{}
+	headers := &strings.Builder{}
+	pathParameters := map[string]string{}
+
+	if len(parameters) > 0 {
+		signature += ", params " + name + "Params"
+		writeParamsStruct(buf, name, route, parameters)
+	}
+
+	for _, parameter := range parameters {
+
+		value := "params." + parameter.field
+
+		if !parameter.Required {
+			value = "*" + value
+		}
+
+		formatted := fmt.Sprintf(parameterFormats[parameter.goType], value)
+
+		if parameter.goType != "string" {
+			imports["strconv"] = true
+		}
+
+		var set string
+
+		switch parameter.In {
+		case "path":
+			if !parameter.Required {
+				return fmt.Errorf("path parameter %v is not required", parameter.Name)
+			}
+			imports["net/url"] = true
+			pathParameters[parameter.Name] = "url.PathEscape(" + formatted + ")"
+			continue
+		case "query":
+			set = fmt.Sprintf("query.Set(%q, %v)", parameter.Name, formatted)
+			imports["net/url"] = true
+		case "header":
+			set = fmt.Sprintf("header.Set(%q, %v)", parameter.Name, formatted)
+			imports["net/http"] = true
+		}
+
+		target := query
+
+		if parameter.In == "header" {
+			target = headers
+		}
+
+		if parameter.Required {
+			fmt.Fprintf(target, "\t%v\n", set)
+			continue
+		}
+
+		fmt.Fprintf(target, "\n\tif params.%v != nil {\n\t\t%v\n\t}\n\n", parameter.field, set)
+	}
+
+	path, err := pathExpression(route.Path, pathParameters)
+
+	if err != nil {
+		return err
+	}
+
+	queryArg, headerArg := "nil", "nil"
+	statements := &strings.Builder{}
+
+	if query.Len() > 0 {
+		queryArg = "query"
+		fmt.Fprintf(statements, "\n\tquery := url.Values{}\n\n%v", query)
+	}
+
+	if headers.Len() > 0 {
+		headerArg = "header"
+		fmt.Fprintf(statements, "\n\theader := http.Header{}\n\n%v", headers)
+	}
+
+	description := fmt.Sprintf("%v calls %v %v", name, route.Method, route.Path)
+
+	if route.Summary != "" {
+		description += ": " + oneLine(route.Summary)
+	}
+
+	if result == "" {
+		fmt.Fprintf(buf, `
+// %v
+func (client *Client) %v(%v) error {
+%v
+	return client.do(ctx, %q, %v, %v, %v, nil)
+}
+`, description, name, signature, statements, route.Method, path, queryArg, headerArg)
+		return nil
+	}
+
+	if strings.Contains(result, "models.") {
+		imports["models"] = true
+	}
+
+	fmt.Fprintf(buf, `
+// %v
+func (client *Client) %v(%v) (result %v, err error) {
+%v
+	err = client.do(ctx, %q, %v, %v, %v, &result)
+
+	return result, err
+}
+`, description, name, signature, result, statements, route.Method, path, queryArg, headerArg)
+
+	return nil
+}
+
+// Returns a Go expression of a path with its parameters substituted by the expressions of their values
+func pathExpression(path string, values map[string]string) (string, error) {
+
+	var parts []string
+	last := 0
+
+	for _, match := range pathVariable.FindAllStringSubmatchIndex(path, -1) {
+
+		value, ok := values[path[match[2]:match[3]]]
+
+		if !ok {
+			return "", fmt.Errorf("path parameter %v is not defined", path[match[2]:match[3]])
+		}
+
+		if match[0] > last {
+			parts = append(parts, fmt.Sprintf("%q", path[last:match[0]]))
+		}
+
+		parts = append(parts, value)
+		last = match[1]
+	}
+
+	if last < len(path) || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", path[last:]))
+	}
+
+	return strings.Join(parts, "+"), nil
+}
+
+// Returns the Go type of the schema of the first successful response of an operation, or empty if it has none
+func successType(route Route) (string, error) {
+
+	var codes []string
+
+	for code := range route.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+
+	sort.Strings(codes)
+
+	if len(codes) == 0 {
+		return "", nil
+	}
+
+	return resultType(route.Responses[codes[0]].Schema)
+}
+
+// Returns the Go type of a response schema, in which references are to the models package
+func resultType(schema *Schema) (string, error) {
+
+	if schema == nil {
+		return "", nil
+	}
+
+	if schema.Ref != "" {
+
+		ref := schema.RefName()
+
+		if ref == "" {
+			return "", fmt.Errorf("reference %v is not to a definition", schema.Ref)
+		}
+
+		return "models." + GoName(ref), nil
+	}
+
+	switch schema.Type {
+	case "array":
+		items, err := resultType(schema.Items)
+		if items == "" {
+			items = "interface{}"
+		}
+		return "[]" + items, err
+	case "object":
+		return "map[string]interface{}", nil
+	case "":
+		return "interface{}", nil
+	}
+
+	return fieldType(nil, "", schema)
+}
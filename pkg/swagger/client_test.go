@@ -0,0 +1,44 @@
+package swagger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestGenerateClient(t *testing.T) {
+
+	doc, err := ParseJSON([]byte(routesDefinition))
+
+	utils.AssertNoError(t, "Parse", err)
+
+	doc.Paths["/orders/{id}"].Get.Responses["200"] = Response{Schema: &Schema{Type: "array", Items: &Schema{Ref: "#/definitions/order-line"}}}
+
+	raw, err := GenerateClient("client", "example.com/models", doc)
+
+	utils.AssertNoError(t, "Generate", err)
+
+	client := string(raw)
+
+	for _, expected := range []string{
+		"func (client *Client) GetRoot(ctx context.Context) error {\n",
+		"func (client *Client) GetOrder(ctx context.Context, params GetOrderParams) (result []models.OrderLine, err error) {\n",
+		"\t// id in the path\n\tId int\n",
+		"\tif params.Lines != nil {\n\t\tquery.Set(\"lines\", strconv.FormatBool(*params.Lines))\n\t}\n",
+		"\tif params.XTrace != nil {\n\t\theader.Set(\"X-Trace\", *params.XTrace)\n\t}\n",
+		"\terr = client.do(ctx, \"GET\", \"/orders/\"+url.PathEscape(strconv.Itoa(params.Id)), query, header, &result)\n",
+		"\treturn client.do(ctx, \"GET\", \"/\", nil, nil, nil)\n",
+		"\t\"strconv\"\n\n\t\"example.com/models\"\n",
+	} {
+		utils.AssertTrue(t, "Client contains "+expected, strings.Contains(client, expected))
+	}
+
+	utils.AssertFalse(t, "Mock integration not called", strings.Contains(client, "OPTIONS"))
+
+	doc.Paths["/orders/{id}"].Get.Parameters = []Parameter{{Name: "order", In: "body"}}
+
+	_, err = GenerateClient("client", "example.com/models", doc)
+
+	utils.AssertErrorEquals(t, "Body parameter", "Cannot generate GET /orders/{id}: body parameters are not supported", err)
+}
@@ -0,0 +1,246 @@
+package swagger
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The header of generated files, which marks them as generated
+const header = "// Code generated from the Swagger definition of the API. DO NOT EDIT."
+
+// GenerateModels generates a Go source file declaring a type for every definition, in name order. Object definitions
+// become structs with a field for every property in name order, which is omitted from JSON if empty unless it is
+// required. Inline object properties become types named by the definition and property, and string enums constants
+// named by the type, property and value
+func GenerateModels(pkg string, definitions map[string]*Schema) ([]byte, error) {
+
+	generated := map[string]string{}
+
+	for name, schema := range definitions {
+		if err := generateType(generated, GoName(name), schema); err != nil {
+			return nil, fmt.Errorf("Cannot generate %v: %v", name, err)
+		}
+	}
+
+	names := make([]string, 0, len(generated))
+
+	for name := range generated {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, "%v\n\npackage %v\n", header, pkg)
+
+	for _, name := range names {
+		buf.WriteString("\n" + generated[name])
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// Adds the declaration of a named type for a schema, and of any types for its inline objects
+func generateType(generated map[string]string, name string, schema *Schema) error {
+
+	if schema == nil {
+		schema = &Schema{}
+	}
+
+	if _, ok := generated[name]; ok {
+		return fmt.Errorf("type %v is declared twice", name)
+	}
+
+	description := schema.Description
+
+	if description == "" {
+		description = "(No description)"
+	}
+
+	buf := &strings.Builder{}
+
+	fmt.Fprintf(buf, "// %v: %v\n", name, oneLine(description))
+
+	if !isStruct(schema) {
+
+		goType, err := fieldType(generated, name, schema)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(buf, "type %v %v\n", name, goType)
+		generated[name] = buf.String()
+
+		return nil
+	}
+
+	// Reserve the name before generating the types of inline properties, which may clash with it
+	generated[name] = ""
+
+	properties := make([]string, 0, len(schema.Properties))
+
+	for property := range schema.Properties {
+		properties = append(properties, property)
+	}
+
+	sort.Slice(properties, func(i, j int) bool {
+		return GoName(properties[i]) < GoName(properties[j])
+	})
+
+	required := map[string]bool{}
+
+	for _, property := range schema.Required {
+		required[property] = true
+	}
+
+	enums := &strings.Builder{}
+
+	fmt.Fprintf(buf, "type %v struct {\n", name)
+
+	for _, property := range properties {
+
+		field := GoName(property)
+		propertySchema := schema.Properties[property]
+		goType, err := fieldType(generated, name+field, propertySchema)
+
+		if err != nil {
+			return err
+		}
+
+		if propertySchema != nil && propertySchema.Description != "" {
+			fmt.Fprintf(buf, "\t// %v\n", oneLine(propertySchema.Description))
+		}
+
+		tag := property
+
+		if !required[property] {
+			tag += ",omitempty"
+		}
+
+		fmt.Fprintf(buf, "\t%v %v `json:\"%v\"`\n", field, goType, tag)
+
+		if goType == "string" && len(propertySchema.Enum) > 0 {
+			writeEnum(enums, name+field, propertySchema.Enum)
+		}
+	}
+
+	buf.WriteString("}\n")
+
+	if enums.Len() > 0 {
+		fmt.Fprintf(buf, "\n// Values of %v\nconst (\n%v)\n", name, enums)
+	}
+
+	generated[name] = buf.String()
+
+	return nil
+}
+
+// Writes a constant declaration for every value of an enum
+func writeEnum(buf *strings.Builder, prefix string, values []interface{}) {
+
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			fmt.Fprintf(buf, "\t%v%v = %q\n", prefix, GoName(s), s)
+		}
+	}
+}
+
+// Returns whether a schema is an object with properties, or an object without a type for other properties
+func isStruct(schema *Schema) bool {
+	return schema.Ref == "" && (schema.Type == "object" || schema.Type == "") && (len(schema.Properties) > 0 || schema.AdditionalProperties == nil)
+}
+
+// Returns the Go type of a schema, declaring a type of the given name if it is an inline object with properties
+func fieldType(generated map[string]string, name string, schema *Schema) (string, error) {
+
+	if schema == nil {
+		return "interface{}", nil
+	}
+
+	if schema.Ref != "" {
+
+		ref := schema.RefName()
+
+		if ref == "" {
+			return "", fmt.Errorf("reference %v is not to a definition", schema.Ref)
+		}
+
+		return GoName(ref), nil
+	}
+
+	switch schema.Type {
+	case "string":
+		return "string", nil
+	case "boolean":
+		return "bool", nil
+	case "integer":
+		switch schema.Format {
+		case "int32":
+			return "int32", nil
+		case "int64":
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		if schema.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "array":
+		items, err := fieldType(generated, name+"Item", schema.Items)
+		return "[]" + items, err
+	case "object", "":
+		if len(schema.Properties) > 0 {
+			return name, generateType(generated, name, schema)
+		}
+		if schema.AdditionalProperties != nil {
+			values, err := fieldType(generated, name+"Value", schema.AdditionalProperties)
+			return "map[string]" + values, err
+		}
+		if schema.Type == "object" {
+			return "map[string]interface{}", nil
+		}
+		return "interface{}", nil
+	}
+
+	return "", fmt.Errorf("type %v is not supported", schema.Type)
+}
+
+// GoName returns an exported Go identifier for a Swagger name, capitalising its first letter and the letter after
+// every character which cannot be in an identifier, which is dropped
+func GoName(name string) string {
+
+	buf := &strings.Builder{}
+	upper := true
+
+	for _, r := range name {
+
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if buf.Len() == 0 && unicode.IsDigit(r) {
+			buf.WriteRune('N')
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		buf.WriteRune(r)
+	}
+
+	return buf.String()
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,91 @@
+package swagger
+
+import (
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestGenerateModels(t *testing.T) {
+
+	doc, err := ParseJSON([]byte(`{
+		"swagger": "2.0",
+		"info": {"title": "Sample-API-test", "version": "test"},
+		"definitions": {
+			"Order": {
+				"type": "object",
+				"description": "An order\nwith lines",
+				"required": ["id", "lines", "status"],
+				"properties": {
+					"id": {"type": "integer", "format": "int64"},
+					"lines": {"type": "array", "items": {"$ref": "#/definitions/Line"}},
+					"tags": {"type": "object", "additionalProperties": {"type": "string"}},
+					"delivery": {"type": "object", "properties": {"post-code": {"type": "string"}}},
+					"extra": {},
+					"status": {"type": "string", "enum": ["open", "shipped"], "description": "Where the order is"}
+				}
+			},
+			"Line": {
+				"type": "object",
+				"properties": {"quantity": {"type": "integer"}, "price": {"type": "number", "format": "float"}, "gift": {"type": "boolean"}}
+			},
+			"Codes": {"type": "array", "items": {"type": "string"}, "description": "Codes"}
+		}
+	}`))
+
+	utils.AssertNoError(t, "Parse", err)
+
+	raw, err := GenerateModels("models", doc.Definitions)
+
+	utils.AssertNoError(t, "Generate", err)
+	utils.AssertEquals(t, "Models", "// Code generated from the Swagger definition of the API. DO NOT EDIT.\n"+`
+package models
+
+// Codes: Codes
+type Codes []string
+
+// Line: (No description)
+type Line struct {
+	Gift     bool    `+"`json:\"gift,omitempty\"`"+`
+	Price    float32 `+"`json:\"price,omitempty\"`"+`
+	Quantity int     `+"`json:\"quantity,omitempty\"`"+`
+}
+
+// Order: An order with lines
+type Order struct {
+	Delivery OrderDelivery `+"`json:\"delivery,omitempty\"`"+`
+	Extra    interface{}   `+"`json:\"extra,omitempty\"`"+`
+	Id       int64         `+"`json:\"id\"`"+`
+	Lines    []Line        `+"`json:\"lines\"`"+`
+	// Where the order is
+	Status string            `+"`json:\"status\"`"+`
+	Tags   map[string]string `+"`json:\"tags,omitempty\"`"+`
+}
+
+// Values of Order
+const (
+	OrderStatusOpen    = "open"
+	OrderStatusShipped = "shipped"
+)
+
+// OrderDelivery: (No description)
+type OrderDelivery struct {
+	PostCode string `+"`json:\"post-code,omitempty\"`"+`
+}
+`, string(raw))
+
+	_, err = GenerateModels("models", map[string]*Schema{"Bad": {Type: "object", Properties: map[string]*Schema{"file": {Type: "file"}}}})
+
+	utils.AssertErrorEquals(t, "Unsupported type", "Cannot generate Bad: type file is not supported", err)
+
+	_, err = ParseJSON([]byte(`{"openapi": "3.0.1"}`))
+
+	utils.AssertErrorEquals(t, "Not Swagger 2.0", `Invalid Swagger definition: version is "", not 2.0`, err)
+}
+
+func TestGoName(t *testing.T) {
+
+	utils.AssertEquals(t, "Camel case", "ReleaseId", GoName("releaseId"))
+	utils.AssertEquals(t, "Separators", "AcceptLanguage", GoName("Accept-Language"))
+	utils.AssertEquals(t, "Leading digit", "N2fa", GoName("2fa"))
+}
@@ -0,0 +1,1134 @@
+package swagger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIVersion is the version of the OpenAPI definitions Swagger 2.0 definitions are converted to by default
+const OpenAPIVersion = "3.1.0"
+
+// A JSON object of an API definition
+type object = map[string]interface{}
+
+// The prefixes of references in Swagger 2.0 definitions and the OpenAPI 3 components they are converted to
+var componentRefs = [][2]string{
+	{"#/definitions/", "#/components/schemas/"},
+	{"#/parameters/", "#/components/parameters/"},
+	{"#/responses/", "#/components/responses/"},
+}
+
+// The keys of a Swagger 2.0 parameter, header or items object which are the keys of its schema in OpenAPI 3
+var schemaKeys = map[string]bool{
+	"type": true, "format": true, "items": true, "default": true, "enum": true, "multipleOf": true,
+	"maximum": true, "exclusiveMaximum": true, "minimum": true, "exclusiveMinimum": true,
+	"maxLength": true, "minLength": true, "pattern": true, "maxItems": true, "minItems": true, "uniqueItems": true,
+}
+
+// The keys of the operations of a path item. OpenAPI 3 also has trace, which Swagger 2.0 and API Gateway do not
+var methodKeys = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true,
+}
+
+// The Swagger 2.0 OAuth2 flows and their OpenAPI 3 names
+var oauthFlows = [][2]string{
+	{"implicit", "implicit"},
+	{"password", "password"},
+	{"application", "clientCredentials"},
+	{"accessCode", "authorizationCode"},
+}
+
+// The extension of an OpenAPI request body which keeps the name of the Swagger 2.0 body parameter it is converted from
+const bodyName = "x-codegen-request-body-name"
+
+// The content types of request bodies which are sent as Swagger 2.0 formData parameters
+var formTypes = map[string]bool{
+	"application/x-www-form-urlencoded": true,
+	"multipart/form-data":               true,
+}
+
+// Parse parses a Swagger 2.0 or OpenAPI 3 API definition in JSON or YAML. An OpenAPI definition is converted to
+// Swagger 2.0 by ToSwagger, so code is generated from either version the same way
+func Parse(raw []byte) (Document, error) {
+
+	definition, version, err := decodeDefinition(raw)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	if version != "2.0" {
+		if definition, err = openAPIToSwagger(definition); err != nil {
+			return Document{}, err
+		}
+	}
+
+	converted, err := json.Marshal(definition)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	return ParseJSON(converted)
+}
+
+// ToOpenAPI converts a Swagger 2.0 API definition in JSON or YAML to an OpenAPI definition of a 3.x version in JSON.
+// Extensions such as x-amazon-apigateway-integration are kept where they are
+func ToOpenAPI(raw []byte, version string) ([]byte, error) {
+
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("Cannot convert to OpenAPI %v: only 3.x versions are supported", version)
+	}
+
+	definition, from, err := decodeDefinition(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if from != "2.0" {
+		return nil, fmt.Errorf("Cannot convert to OpenAPI %v: the definition is already OpenAPI %v", version, from)
+	}
+
+	converted, err := swaggerToOpenAPI(definition, version)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(converted, "", "  ")
+}
+
+// ToSwagger converts an OpenAPI 3 API definition in JSON or YAML to a Swagger 2.0 definition in JSON. Extensions such
+// as x-amazon-apigateway-integration are kept where they are, and what Swagger 2.0 cannot describe, such as links,
+// callbacks and servers other than the first, is dropped. Request bodies become the last parameters of operations
+func ToSwagger(raw []byte) ([]byte, error) {
+
+	definition, from, err := decodeDefinition(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if from == "2.0" {
+		return nil, errors.New("Cannot convert to Swagger 2.0: the definition is already Swagger 2.0")
+	}
+
+	converted, err := openAPIToSwagger(definition)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(converted, "", "  ")
+}
+
+// Decodes an API definition in JSON or YAML, returning its Swagger or OpenAPI version
+func decodeDefinition(raw []byte) (object, string, error) {
+
+	var definition object
+
+	if err := json.Unmarshal(raw, &definition); err != nil {
+		if yamlErr := yaml.Unmarshal(raw, &definition); yamlErr != nil {
+			return nil, "", fmt.Errorf("Invalid API definition: %v", yamlErr)
+		}
+	}
+
+	if version, ok := definition["swagger"].(string); ok && version == "2.0" {
+		return definition, version, nil
+	}
+
+	if version, ok := definition["openapi"].(string); ok && strings.HasPrefix(version, "3.") {
+		return definition, version, nil
+	}
+
+	return nil, "", errors.New("Invalid API definition: it is neither Swagger 2.0 nor OpenAPI 3")
+}
+
+// Converts a decoded Swagger 2.0 definition to OpenAPI
+func swaggerToOpenAPI(definition object, version string) (object, error) {
+
+	converter := swaggerConverter{
+		consumes:   stringList(definition["consumes"]),
+		produces:   stringList(definition["produces"]),
+		parameters: asObject(definition["parameters"]),
+	}
+
+	converted := object{"openapi": version}
+	components := object{}
+
+	for key, value := range definition {
+		switch key {
+		case "swagger", "host", "basePath", "schemes", "consumes", "produces":
+			// Converted to servers and content below
+		case "definitions":
+			components["schemas"] = value
+		case "parameters":
+			parameters, requestBodies := object{}, object{}
+			for name, parameter := range asObject(value) {
+				if in := asObject(parameter)["in"]; in == "body" {
+					requestBodies[name] = converter.requestBody([]interface{}{parameter}, converter.consumes)
+					continue
+				}
+				parameters[name] = convertParameter(asObject(parameter))
+			}
+			putIfAny(components, "parameters", parameters)
+			putIfAny(components, "requestBodies", requestBodies)
+		case "responses":
+			responses := object{}
+			for name, response := range asObject(value) {
+				responses[name] = convertResponse(asObject(response), converter.produces)
+			}
+			components["responses"] = responses
+		case "securityDefinitions":
+			schemes := object{}
+			for name, scheme := range asObject(value) {
+				schemes[name] = convertSecurityDefinition(asObject(scheme))
+			}
+			components["securitySchemes"] = schemes
+		case "paths":
+			paths := object{}
+			for path, item := range asObject(value) {
+				convertedItem, err := converter.pathItem(asObject(item))
+				if err != nil {
+					return nil, fmt.Errorf("Cannot convert %v: %v", path, err)
+				}
+				paths[path] = convertedItem
+			}
+			converted["paths"] = paths
+		default:
+			converted[key] = value
+		}
+	}
+
+	if servers := swaggerServers(definition); len(servers) > 0 {
+		converted["servers"] = servers
+	}
+
+	putIfAny(converted, "components", components)
+
+	for _, refs := range componentRefs {
+		rewriteRefs(converted, refs[0], refs[1])
+	}
+
+	convertNullable(converted, !strings.HasPrefix(version, "3.0"))
+
+	return converted, nil
+}
+
+// Converts the parts of a Swagger 2.0 definition whose content types default to those of the definition
+type swaggerConverter struct {
+	consumes, produces []string
+	// The parameters of the definition, which operations may refer to
+	parameters object
+}
+
+func (converter swaggerConverter) pathItem(item object) (object, error) {
+
+	converted := object{}
+	var shared []interface{}
+
+	for key, value := range item {
+
+		if key == "parameters" {
+			shared = asList(value)
+			continue
+		}
+
+		if !methodKeys[key] {
+			converted[key] = value
+			continue
+		}
+
+		operation, err := converter.operation(asObject(value))
+
+		if err != nil {
+			return nil, fmt.Errorf("%v %v", strings.ToUpper(key), err)
+		}
+
+		converted[key] = operation
+	}
+
+	parameters, bodies := converter.splitParameters(shared)
+
+	putIfAny(converted, "parameters", parameters)
+
+	// A body shared by the operations of a path is the request body of each which does not have its own
+	for key, operation := range converted {
+		if methodKeys[key] && len(bodies) > 0 {
+			if _, ok := asObject(operation)["requestBody"]; !ok {
+				asObject(operation)["requestBody"] = converter.requestBody(bodies, converter.consumes)
+			}
+		}
+	}
+
+	return converted, nil
+}
+
+func (converter swaggerConverter) operation(operation object) (object, error) {
+
+	converted := object{}
+	consumes := converter.consumes
+	produces := converter.produces
+
+	if list, ok := operation["consumes"]; ok {
+		consumes = stringList(list)
+	}
+
+	if list, ok := operation["produces"]; ok {
+		produces = stringList(list)
+	}
+
+	for key, value := range operation {
+		switch key {
+		case "consumes", "produces", "schemes":
+			// Converted to content, and servers which operations of Swagger 2.0 cannot override
+		case "parameters":
+			parameters, bodies := converter.splitParameters(asList(value))
+			putIfAny(converted, "parameters", parameters)
+			if len(bodies) > 0 {
+				converted["requestBody"] = converter.requestBody(bodies, consumes)
+			}
+		case "responses":
+			responses := object{}
+			for code, response := range asObject(value) {
+				responses[code] = convertResponse(asObject(response), produces)
+			}
+			converted["responses"] = responses
+		default:
+			converted[key] = value
+		}
+	}
+
+	return converted, nil
+}
+
+// Returns the parameters of a list other than those of the request body, and those of the request body
+func (converter swaggerConverter) splitParameters(list []interface{}) ([]interface{}, []interface{}) {
+
+	var parameters, bodies []interface{}
+
+	for _, value := range list {
+
+		parameter := asObject(value)
+		resolved := parameter
+
+		if ref, ok := parameter["$ref"].(string); ok && strings.HasPrefix(ref, "#/parameters/") {
+			resolved = asObject(converter.parameters[strings.TrimPrefix(ref, "#/parameters/")])
+		}
+
+		switch resolved["in"] {
+		case "body", "formData":
+			bodies = append(bodies, parameter)
+		default:
+			parameters = append(parameters, convertParameter(parameter))
+		}
+	}
+
+	return parameters, bodies
+}
+
+// Returns the request body of body or formData parameters, with content of the given types
+func (converter swaggerConverter) requestBody(parameters []interface{}, consumes []string) object {
+
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+
+	first := asObject(parameters[0])
+
+	if ref, ok := first["$ref"].(string); ok && len(parameters) == 1 {
+		return object{"$ref": strings.Replace(ref, "#/parameters/", "#/components/requestBodies/", 1)}
+	}
+
+	body := object{}
+	content := object{}
+
+	if first["in"] == "body" {
+
+		for key, value := range first {
+			switch key {
+			case "description", "required":
+				body[key] = value
+			case "name":
+				if value != "body" {
+					body[bodyName] = value
+				}
+			default:
+				if strings.HasPrefix(key, "x-") {
+					body[key] = value
+				}
+			}
+		}
+
+		for _, contentType := range consumes {
+			content[contentType] = object{"schema": first["schema"]}
+		}
+
+		body["content"] = content
+
+		return body
+	}
+
+	// The formData parameters are the properties of an object
+	properties := object{}
+	var required []interface{}
+
+	for _, value := range parameters {
+
+		parameter := asObject(value)
+		name, _ := parameter["name"].(string)
+		schema := object{}
+
+		for key, value := range parameter {
+			if schemaKeys[key] || key == "description" {
+				schema[key] = value
+			}
+		}
+
+		if schema["type"] == "file" {
+			schema["type"] = "string"
+			schema["format"] = "binary"
+		}
+
+		properties[name] = schema
+
+		if parameter["required"] == true {
+			required = append(required, name)
+		}
+	}
+
+	schema := object{"type": "object", "properties": properties}
+
+	if len(required) > 0 {
+		schema["required"] = required
+		body["required"] = true
+	}
+
+	for _, contentType := range consumes {
+		if formTypes[contentType] {
+			content[contentType] = object{"schema": schema}
+		}
+	}
+
+	if len(content) == 0 {
+		content["application/x-www-form-urlencoded"] = object{"schema": schema}
+	}
+
+	body["content"] = content
+
+	return body
+}
+
+// Converts a Swagger 2.0 parameter other than a body or formData parameter, whose type becomes its schema
+func convertParameter(parameter object) object {
+
+	converted := object{}
+	schema := object{}
+
+	for key, value := range parameter {
+		switch {
+		case schemaKeys[key]:
+			schema[key] = value
+		case key == "collectionFormat":
+			switch value {
+			case "multi":
+				converted["style"], converted["explode"] = "form", true
+			case "ssv":
+				converted["style"] = "spaceDelimited"
+			case "pipes":
+				converted["style"] = "pipeDelimited"
+			case "csv":
+				if parameter["in"] == "query" {
+					converted["style"], converted["explode"] = "form", false
+				}
+			}
+		default:
+			converted[key] = value
+		}
+	}
+
+	putIfAny(converted, "schema", schema)
+
+	return converted
+}
+
+// Converts a Swagger 2.0 response, whose schema becomes its content of the given types
+func convertResponse(response object, produces []string) object {
+
+	if _, ok := response["$ref"]; ok {
+		return response
+	}
+
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+
+	converted := object{}
+	examples := asObject(response["examples"])
+
+	for key, value := range response {
+		switch key {
+		case "schema", "examples":
+		case "headers":
+			headers := object{}
+			for name, header := range asObject(value) {
+				headers[name] = convertParameter(asObject(header))
+			}
+			converted["headers"] = headers
+		default:
+			converted[key] = value
+		}
+	}
+
+	if _, ok := converted["description"]; !ok {
+		converted["description"] = ""
+	}
+
+	if schema, ok := response["schema"]; ok {
+
+		content := object{}
+
+		for _, contentType := range produces {
+
+			media := object{"schema": schema}
+
+			if example, ok := examples[contentType]; ok {
+				media["example"] = example
+			}
+
+			content[contentType] = media
+		}
+
+		converted["content"] = content
+	}
+
+	return converted
+}
+
+func convertSecurityDefinition(scheme object) object {
+
+	converted := object{}
+
+	for key, value := range scheme {
+		switch key {
+		case "flow", "authorizationUrl", "tokenUrl", "scopes":
+		default:
+			converted[key] = value
+		}
+	}
+
+	switch scheme["type"] {
+	case "basic":
+		converted["type"], converted["scheme"] = "http", "basic"
+	case "oauth2":
+		flow := object{"scopes": scheme["scopes"]}
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if value, ok := scheme[key]; ok {
+				flow[key] = value
+			}
+		}
+		if flow["scopes"] == nil {
+			flow["scopes"] = object{}
+		}
+		for _, name := range oauthFlows {
+			if scheme["flow"] == name[0] {
+				converted["flows"] = object{name[1]: flow}
+			}
+		}
+	}
+
+	return converted
+}
+
+// Returns the servers of a Swagger 2.0 definition from its schemes, host and base path
+func swaggerServers(definition object) []interface{} {
+
+	host, _ := definition["host"].(string)
+	basePath, _ := definition["basePath"].(string)
+
+	if host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []interface{}{object{"url": basePath}}
+	}
+
+	schemes := stringList(definition["schemes"])
+
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+
+	var servers []interface{}
+
+	for _, scheme := range schemes {
+		servers = append(servers, object{"url": scheme + "://" + host + basePath})
+	}
+
+	return servers
+}
+
+// Converts a decoded OpenAPI 3 definition to Swagger 2.0
+func openAPIToSwagger(definition object) (object, error) {
+
+	converted := object{"swagger": "2.0"}
+	components := asObject(definition["components"])
+	parameters := object{}
+
+	for key, value := range definition {
+		switch key {
+		case "openapi", "components", "webhooks", "jsonSchemaDialect":
+		case "servers":
+			servers := asList(value)
+			if len(servers) > 0 {
+				if err := putServer(converted, asObject(servers[0])); err != nil {
+					return nil, err
+				}
+			}
+		case "paths":
+			paths := object{}
+			for path, item := range asObject(value) {
+				convertedItem, err := convertPathItem(asObject(item))
+				if err != nil {
+					return nil, fmt.Errorf("Cannot convert %v: %v", path, err)
+				}
+				paths[path] = convertedItem
+			}
+			converted["paths"] = paths
+		default:
+			converted[key] = value
+		}
+	}
+
+	for key, value := range components {
+		switch key {
+		case "schemas":
+			converted["definitions"] = value
+		case "parameters":
+			for name, parameter := range asObject(value) {
+				flattened, err := flattenParameter(asObject(parameter))
+				if err != nil {
+					return nil, fmt.Errorf("Cannot convert parameter %v: %v", name, err)
+				}
+				parameters[name] = flattened
+			}
+		case "requestBodies":
+			for name, body := range asObject(value) {
+				bodies, _, err := convertRequestBody(asObject(body))
+				if err != nil {
+					return nil, fmt.Errorf("Cannot convert request body %v: %v", name, err)
+				}
+				if len(bodies) == 1 {
+					if _, ok := asObject(body)[bodyName]; !ok {
+						asObject(bodies[0])["name"] = name
+					}
+					parameters[name] = bodies[0]
+				}
+			}
+		case "responses":
+			responses := object{}
+			for name, response := range asObject(value) {
+				responses[name], _ = flattenResponse(asObject(response))
+			}
+			converted["responses"] = responses
+		case "securitySchemes":
+			schemes := object{}
+			for name, scheme := range asObject(value) {
+				schemes[name] = flattenSecurityScheme(asObject(scheme))
+			}
+			converted["securityDefinitions"] = schemes
+		}
+	}
+
+	putIfAny(converted, "parameters", parameters)
+
+	rewriteRefs(converted, "#/components/requestBodies/", "#/parameters/")
+
+	for _, refs := range componentRefs {
+		rewriteRefs(converted, refs[1], refs[0])
+	}
+
+	flattenNullable(converted)
+
+	return converted, nil
+}
+
+// Sets the schemes, host and base path of a Swagger 2.0 definition from an OpenAPI server
+func putServer(definition object, server object) error {
+
+	address, _ := server["url"].(string)
+
+	for name, variable := range asObject(server["variables"]) {
+		if value, ok := asObject(variable)["default"].(string); ok {
+			address = strings.ReplaceAll(address, "{"+name+"}", value)
+		}
+	}
+
+	parsed, err := url.Parse(address)
+
+	if err != nil {
+		return fmt.Errorf("Cannot convert server %v: %v", address, err)
+	}
+
+	if parsed.Host != "" {
+		definition["host"] = parsed.Host
+		definition["schemes"] = []interface{}{parsed.Scheme}
+	}
+
+	if parsed.Path != "" && parsed.Path != "/" {
+		definition["basePath"] = parsed.Path
+	}
+
+	return nil
+}
+
+func convertPathItem(item object) (object, error) {
+
+	converted := object{}
+
+	for key, value := range item {
+		switch {
+		case key == "parameters":
+			parameters, err := flattenParameters(asList(value))
+			if err != nil {
+				return nil, err
+			}
+			putIfAny(converted, "parameters", parameters)
+		case methodKeys[key]:
+			operation, err := convertOperation(asObject(value))
+			if err != nil {
+				return nil, fmt.Errorf("%v %v", strings.ToUpper(key), err)
+			}
+			converted[key] = operation
+		case key == "$ref" || strings.HasPrefix(key, "x-"):
+			converted[key] = value
+		}
+	}
+
+	return converted, nil
+}
+
+func convertOperation(operation object) (object, error) {
+
+	converted := object{}
+	var parameters []interface{}
+	var produces []string
+
+	for key, value := range operation {
+		switch key {
+		case "servers", "callbacks":
+		case "parameters":
+			flattened, err := flattenParameters(asList(value))
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(flattened, parameters...)
+		case "requestBody":
+			bodies, consumes, err := convertRequestBody(asObject(value))
+			if err != nil {
+				return nil, err
+			}
+			parameters = append(parameters, bodies...)
+			if len(consumes) > 0 {
+				converted["consumes"] = consumes
+			}
+		case "responses":
+			responses := object{}
+			for code, response := range asObject(value) {
+				var types []string
+				responses[code], types = flattenResponse(asObject(response))
+				produces = appendMissing(produces, types...)
+			}
+			converted["responses"] = responses
+		default:
+			converted[key] = value
+		}
+	}
+
+	if len(parameters) > 0 {
+		converted["parameters"] = parameters
+	}
+
+	if len(produces) > 0 {
+		sort.Strings(produces)
+		converted["produces"] = toList(produces)
+	}
+
+	return converted, nil
+}
+
+func flattenParameters(list []interface{}) ([]interface{}, error) {
+
+	var flattened []interface{}
+
+	for _, value := range list {
+
+		parameter, err := flattenParameter(asObject(value))
+
+		if err != nil {
+			return nil, err
+		}
+
+		flattened = append(flattened, parameter)
+	}
+
+	return flattened, nil
+}
+
+// Converts an OpenAPI parameter or header, whose schema becomes its type
+func flattenParameter(parameter object) (object, error) {
+
+	flattened := object{}
+
+	for key, value := range parameter {
+		switch key {
+		case "schema":
+			schema := asObject(value)
+			if ref, ok := schema["$ref"]; ok {
+				return nil, fmt.Errorf("parameter %v has a schema reference %v, which Swagger 2.0 does not support", parameter["name"], ref)
+			}
+			for key, value := range schema {
+				if schemaKeys[key] {
+					flattened[key] = value
+				}
+			}
+		case "style", "explode", "content", "example", "examples", "deprecated":
+		default:
+			flattened[key] = value
+		}
+	}
+
+	switch parameter["style"] {
+	case "spaceDelimited":
+		flattened["collectionFormat"] = "ssv"
+	case "pipeDelimited":
+		flattened["collectionFormat"] = "pipes"
+	case "form":
+		if parameter["explode"] != false && flattened["type"] == "array" {
+			flattened["collectionFormat"] = "multi"
+		}
+	}
+
+	return flattened, nil
+}
+
+// Converts an OpenAPI request body to a body parameter or formData parameters, and the content types it consumes
+func convertRequestBody(body object) ([]interface{}, []interface{}, error) {
+
+	if ref, ok := body["$ref"]; ok {
+		return []interface{}{object{"$ref": ref}}, nil, nil
+	}
+
+	content := asObject(body["content"])
+	types := sortedKeys(content)
+
+	if len(types) == 0 {
+		return nil, nil, errors.New("has a request body without content")
+	}
+
+	schema := asObject(asObject(content[types[0]])["schema"])
+
+	if formTypes[types[0]] {
+
+		var parameters []interface{}
+		required := map[interface{}]bool{}
+
+		for _, name := range asList(schema["required"]) {
+			required[name] = true
+		}
+
+		for _, name := range sortedKeys(asObject(schema["properties"])) {
+
+			property := asObject(asObject(schema["properties"])[name])
+			parameter := object{"name": name, "in": "formData", "required": required[name]}
+
+			for key, value := range property {
+				if schemaKeys[key] || key == "description" {
+					parameter[key] = value
+				}
+			}
+
+			if property["format"] == "binary" {
+				parameter["type"] = "file"
+				delete(parameter, "format")
+			}
+
+			parameters = append(parameters, parameter)
+		}
+
+		return parameters, toList(types), nil
+	}
+
+	parameter := object{"name": "body", "in": "body", "schema": schema}
+
+	for key, value := range body {
+		switch {
+		case key == bodyName:
+			parameter["name"] = value
+		case key == "description" || key == "required" || strings.HasPrefix(key, "x-"):
+			parameter[key] = value
+		}
+	}
+
+	return []interface{}{parameter}, toList(types), nil
+}
+
+// Converts an OpenAPI response, whose content becomes its schema, returning the content types it produces
+func flattenResponse(response object) (object, []string) {
+
+	flattened := object{}
+	content := asObject(response["content"])
+	types := sortedKeys(content)
+
+	for key, value := range response {
+		switch key {
+		case "content", "links":
+		case "headers":
+			headers := object{}
+			for name, header := range asObject(value) {
+				if flattenedHeader, err := flattenParameter(asObject(header)); err == nil {
+					headers[name] = flattenedHeader
+				}
+			}
+			flattened["headers"] = headers
+		default:
+			flattened[key] = value
+		}
+	}
+
+	if len(types) == 0 {
+		return flattened, nil
+	}
+
+	// JSON is preferred for the schema of a response with several types of content
+	preferred := types[0]
+
+	if _, ok := content["application/json"]; ok {
+		preferred = "application/json"
+	}
+
+	if schema, ok := asObject(content[preferred])["schema"]; ok {
+		flattened["schema"] = schema
+	}
+
+	examples := object{}
+
+	for _, contentType := range types {
+		if example, ok := asObject(content[contentType])["example"]; ok {
+			examples[contentType] = example
+		}
+	}
+
+	putIfAny(flattened, "examples", examples)
+
+	return flattened, types
+}
+
+func flattenSecurityScheme(scheme object) object {
+
+	flattened := object{}
+
+	for key, value := range scheme {
+		switch key {
+		case "scheme", "bearerFormat", "flows", "openIdConnectUrl":
+		default:
+			flattened[key] = value
+		}
+	}
+
+	switch scheme["type"] {
+	case "http":
+		// Swagger 2.0 has no bearer scheme, which API Gateway authorizers describe as an API key header
+		if scheme["scheme"] == "basic" {
+			flattened["type"] = "basic"
+		} else {
+			flattened["type"], flattened["in"], flattened["name"] = "apiKey", "header", "Authorization"
+		}
+	case "oauth2":
+		flows := asObject(scheme["flows"])
+		for _, flow := range oauthFlows {
+			if value, ok := flows[flow[1]]; ok {
+				flattened["flow"] = flow[0]
+				for key, value := range asObject(value) {
+					flattened[key] = value
+				}
+				break
+			}
+		}
+	}
+
+	return flattened
+}
+
+// Replaces a prefix of the references in a decoded definition
+func rewriteRefs(value interface{}, from, to string) {
+
+	switch value := value.(type) {
+	case object:
+		for key, item := range value {
+			if ref, ok := item.(string); ok && key == "$ref" && strings.HasPrefix(ref, from) {
+				value[key] = to + strings.TrimPrefix(ref, from)
+				continue
+			}
+			rewriteRefs(item, from, to)
+		}
+	case []interface{}:
+		for _, item := range value {
+			rewriteRefs(item, from, to)
+		}
+	}
+}
+
+// Converts the x-nullable extension of Swagger 2.0 schemas to the nullable keyword of OpenAPI 3.0, or a null type in
+// OpenAPI 3.1
+func convertNullable(value interface{}, typeList bool) {
+
+	switch value := value.(type) {
+	case object:
+		if value["x-nullable"] == true {
+			delete(value, "x-nullable")
+			if kind, ok := value["type"].(string); ok && typeList {
+				value["type"] = []interface{}{kind, "null"}
+			} else {
+				value["nullable"] = true
+			}
+		}
+		for _, item := range value {
+			convertNullable(item, typeList)
+		}
+	case []interface{}:
+		for _, item := range value {
+			convertNullable(item, typeList)
+		}
+	}
+}
+
+// Converts the nullable keyword of OpenAPI 3.0 schemas and null types of OpenAPI 3.1 schemas to x-nullable
+func flattenNullable(value interface{}) {
+
+	switch value := value.(type) {
+	case object:
+		if value["nullable"] == true {
+			delete(value, "nullable")
+			value["x-nullable"] = true
+		}
+		if types, ok := value["type"].([]interface{}); ok {
+			var kinds []interface{}
+			for _, kind := range types {
+				if kind == "null" {
+					value["x-nullable"] = true
+					continue
+				}
+				kinds = append(kinds, kind)
+			}
+			if len(kinds) == 1 {
+				value["type"] = kinds[0]
+			}
+		}
+		for _, item := range value {
+			flattenNullable(item)
+		}
+	case []interface{}:
+		for _, item := range value {
+			flattenNullable(item)
+		}
+	}
+}
+
+func asObject(value interface{}) object {
+
+	if value, ok := value.(object); ok {
+		return value
+	}
+
+	return object{}
+}
+
+func asList(value interface{}) []interface{} {
+
+	list, _ := value.([]interface{})
+
+	return list
+}
+
+func stringList(value interface{}) []string {
+
+	var list []string
+
+	for _, item := range asList(value) {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
+
+func toList(values []string) []interface{} {
+
+	list := make([]interface{}, len(values))
+
+	for i, value := range values {
+		list[i] = value
+	}
+
+	return list
+}
+
+func sortedKeys(value object) []string {
+
+	keys := make([]string, 0, len(value))
+
+	for key := range value {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func appendMissing(list []string, values ...string) []string {
+
+	for _, value := range values {
+
+		found := false
+
+		for _, item := range list {
+			if item == value {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			list = append(list, value)
+		}
+	}
+
+	return list
+}
+
+// Sets a key of an object to a value unless it is empty
+func putIfAny(target object, key string, value interface{}) {
+
+	switch value := value.(type) {
+	case object:
+		if len(value) == 0 {
+			return
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			return
+		}
+	}
+
+	target[key] = value
+}
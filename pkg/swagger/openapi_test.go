@@ -0,0 +1,212 @@
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const conversionDefinition = `{
+	"swagger": "2.0",
+	"info": {"title": "Orders", "version": "1"},
+	"host": "abc123.execute-api.eu-west-1.amazonaws.com",
+	"basePath": "/live",
+	"schemes": ["https"],
+	"x-amazon-apigateway-binary-media-types": ["image/png"],
+	"securityDefinitions": {
+		"authorizer": {
+			"type": "apiKey",
+			"name": "Authorization",
+			"in": "header",
+			"x-amazon-apigateway-authtype": "custom"
+		}
+	},
+	"parameters": {
+		"trace": {"name": "X-Trace", "in": "header", "type": "string"},
+		"order": {"name": "order", "in": "body", "required": true, "schema": {"$ref": "#/definitions/Order"}}
+	},
+	"paths": {
+		"/orders/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "type": "integer"}],
+			"put": {
+				"consumes": ["application/json"],
+				"produces": ["application/json"],
+				"parameters": [{"$ref": "#/parameters/trace"}, {"$ref": "#/parameters/order"}],
+				"responses": {
+					"200": {"description": "Updated", "schema": {"$ref": "#/definitions/Order"}, "examples": {"application/json": {"id": 1}}},
+					"400": {"$ref": "#/responses/Error"}
+				},
+				"x-amazon-apigateway-integration": {"type": "aws_proxy", "responses": {"default": {"statusCode": "200"}}}
+			}
+		},
+		"/notes": {
+			"post": {
+				"consumes": ["application/x-www-form-urlencoded"],
+				"parameters": [
+					{"name": "tags", "in": "query", "type": "array", "items": {"type": "string"}, "collectionFormat": "multi"},
+					{"name": "text", "in": "formData", "required": true, "type": "string"}
+				],
+				"responses": {"204": {"description": "Noted"}}
+			}
+		}
+	},
+	"responses": {"Error": {"description": "Error", "schema": {"$ref": "#/definitions/Error"}}},
+	"definitions": {
+		"Order": {"type": "object", "properties": {"id": {"type": "integer"}, "note": {"type": "string", "x-nullable": true}}},
+		"Error": {"type": "object", "properties": {"message": {"type": "string"}}}
+	}
+}`
+
+// Returns the value at a path of keys in a decoded definition
+func lookup(value interface{}, keys ...string) interface{} {
+
+	for _, key := range keys {
+		value = asObject(value)[key]
+	}
+
+	return value
+}
+
+func TestToOpenAPI(t *testing.T) {
+
+	raw, err := ToOpenAPI([]byte(conversionDefinition), OpenAPIVersion)
+
+	utils.AssertNoError(t, "Convert", err)
+
+	var converted object
+
+	utils.AssertNoError(t, "JSON", json.Unmarshal(raw, &converted))
+
+	put := lookup(converted, "paths", "/orders/{id}", "put")
+
+	for _, check := range []struct {
+		name     string
+		keys     []string
+		expected string
+	}{
+		{"Version", []string{"openapi"}, "3.1.0"},
+		{"Extension", []string{"x-amazon-apigateway-binary-media-types"}, "[image/png]"},
+		{"Server", []string{"servers"}, "[map[url:https://abc123.execute-api.eu-west-1.amazonaws.com/live]]"},
+		{"Schema", []string{"components", "schemas", "Order", "properties", "id", "type"}, "integer"},
+		{"Nullable", []string{"components", "schemas", "Order", "properties", "note", "type"}, "[string null]"},
+		{"Security extension", []string{"components", "securitySchemes", "authorizer", "x-amazon-apigateway-authtype"}, "custom"},
+		{"Parameter schema", []string{"components", "parameters", "trace", "schema", "type"}, "string"},
+		{"Request body", []string{"components", "requestBodies", "order", "content", "application/json", "schema", "$ref"}, "#/components/schemas/Order"},
+		{"Response", []string{"components", "responses", "Error", "content", "application/json", "schema", "$ref"}, "#/components/schemas/Error"},
+		{"Path parameter", []string{"paths", "/orders/{id}", "parameters"}, "[map[in:path name:id required:true schema:map[type:integer]]]"},
+	} {
+		utils.AssertEquals(t, check.name, check.expected, fmt.Sprint(lookup(converted, check.keys...)))
+	}
+
+	for _, check := range []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"Parameter reference", lookup(put, "parameters"), "[map[$ref:#/components/parameters/trace]]"},
+		{"Request body reference", lookup(put, "requestBody", "$ref"), "#/components/requestBodies/order"},
+		{"Response content", lookup(put, "responses", "200", "content", "application/json", "schema", "$ref"), "#/components/schemas/Order"},
+		{"Example", lookup(put, "responses", "200", "content", "application/json", "example"), "map[id:1]"},
+		{"Response reference", lookup(put, "responses", "400"), "map[$ref:#/components/responses/Error]"},
+		{"Integration", lookup(put, "x-amazon-apigateway-integration"), "map[responses:map[default:map[statusCode:200]] type:aws_proxy]"},
+		{"Form", lookup(converted, "paths", "/notes", "post", "requestBody"), "map[content:map[application/x-www-form-urlencoded:map[schema:map[properties:map[text:map[type:string]] required:[text] type:object]]] required:true]"},
+		{"Collection format", lookup(converted, "paths", "/notes", "post", "parameters"), "[map[explode:true in:query name:tags schema:map[items:map[type:string] type:array] style:form]]"},
+	} {
+		utils.AssertEquals(t, check.name, check.expected, fmt.Sprint(check.value))
+	}
+
+	raw, err = ToOpenAPI([]byte(conversionDefinition), "3.0.3")
+
+	utils.AssertNoError(t, "Convert to 3.0", err)
+	utils.AssertNoError(t, "JSON", json.Unmarshal(raw, &converted))
+	utils.AssertEquals(t, "Nullable in 3.0", true, lookup(converted, "components", "schemas", "Order", "properties", "note", "nullable"))
+
+	_, err = ToOpenAPI(raw, OpenAPIVersion)
+
+	utils.AssertErrorEquals(t, "Already OpenAPI", "Cannot convert to OpenAPI 3.1.0: the definition is already OpenAPI 3.0.3", err)
+
+	_, err = ToOpenAPI([]byte(conversionDefinition), "2.0")
+
+	utils.AssertErrorEquals(t, "Not OpenAPI 3", "Cannot convert to OpenAPI 2.0: only 3.x versions are supported", err)
+
+	_, err = ToOpenAPI([]byte(`{"info": {}}`), OpenAPIVersion)
+
+	utils.AssertErrorEquals(t, "Not a definition", "Invalid API definition: it is neither Swagger 2.0 nor OpenAPI 3", err)
+}
+
+func TestToSwaggerRoundTrip(t *testing.T) {
+
+	for _, version := range []string{"3.0.3", OpenAPIVersion} {
+
+		openAPI, err := ToOpenAPI([]byte(conversionDefinition), version)
+
+		utils.AssertNoError(t, "To OpenAPI "+version, err)
+
+		raw, err := ToSwagger(openAPI)
+
+		utils.AssertNoError(t, "To Swagger from "+version, err)
+
+		var original, converted object
+
+		json.Unmarshal([]byte(conversionDefinition), &original)
+		json.Unmarshal(raw, &converted)
+
+		// The content types of a reference to a request body cannot be recovered
+		delete(asObject(lookup(original, "paths", "/orders/{id}", "put")), "consumes")
+
+		utils.AssertTrue(t, "Round trip from "+version+" gives "+string(raw), reflect.DeepEqual(original, converted))
+	}
+
+	_, err := ToSwagger([]byte(conversionDefinition))
+
+	utils.AssertErrorEquals(t, "Already Swagger", "Cannot convert to Swagger 2.0: the definition is already Swagger 2.0", err)
+}
+
+func TestParseEitherVersion(t *testing.T) {
+
+	template, err := ioutil.ReadFile(filepath.Join("..", "..", "api.yaml"))
+
+	utils.AssertNoError(t, "Read template", err)
+
+	definition, err := FromTemplate(template, nil)
+
+	utils.AssertNoError(t, "From template", err)
+
+	openAPI, err := ToOpenAPI(definition, OpenAPIVersion)
+
+	utils.AssertNoError(t, "To OpenAPI", err)
+
+	fromSwagger, err := Parse(definition)
+
+	utils.AssertNoError(t, "Parse Swagger", err)
+
+	fromOpenAPI, err := Parse(openAPI)
+
+	utils.AssertNoError(t, "Parse OpenAPI", err)
+
+	for name, generate := range map[string]func(doc Document) ([]byte, error){
+		"Models": func(doc Document) ([]byte, error) { return GenerateModels("models", doc.Definitions) },
+		"Routes": func(doc Document) ([]byte, error) { return GenerateRoutes("front", "example.com/models", doc) },
+		"Client": func(doc Document) ([]byte, error) { return GenerateClient("client", "example.com/models", doc) },
+	} {
+
+		expected, err := generate(fromSwagger)
+
+		utils.AssertNoError(t, name+" from Swagger", err)
+
+		actual, err := generate(fromOpenAPI)
+
+		utils.AssertNoError(t, name+" from OpenAPI", err)
+		utils.AssertEquals(t, name+" from either version", string(expected), string(actual))
+	}
+
+	doc, err := Parse([]byte("openapi: 3.0.3\ninfo: {title: YAML, version: '1'}\npaths: {}\ncomponents:\n  schemas:\n    Note: {type: string}\n"))
+
+	utils.AssertNoError(t, "Parse YAML", err)
+	utils.AssertEquals(t, "YAML schemas", "string", doc.Definitions["Note"].Type)
+}
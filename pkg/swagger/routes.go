@@ -0,0 +1,435 @@
+package swagger
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"strings"
+)
+
+// The parameter locations routes are generated for, and where an APIGatewayProxyRequest has them
+var parameterSources = map[string]string{
+	"path":   "request.PathParameters",
+	"query":  "request.QueryStringParameters",
+	"header": "request.Headers",
+}
+
+// The Go types of parameter types, and the functions which parse them
+var parameterTypes = map[string]struct{ goType, parse string }{
+	"string":  {"string", ""},
+	"number":  {"float64", "parseNumber"},
+	"integer": {"int", "parseInteger"},
+	"boolean": {"bool", "parseBoolean"},
+}
+
+// The helpers of the generated routes, which decode parameters, in the order they are generated. Only those a
+// definition needs are generated
+var routeHelpers = []struct{ name, source string }{
+	{"requiredParameter", `
+// Returns a parameter, or an error if it is not given. Header names are matched case-insensitively
+func requiredParameter(values map[string]string, name string, header bool) (string, models.ApiError) {
+
+	if value := optionalParameter(values, name, header); value != nil {
+		return *value, nil
+	}
+
+	return "", models.ConstructApiError(http.StatusBadRequest, "Missing parameter %v", name)
+}
+`},
+	{"optionalParameter", `
+// Returns a parameter, or nil if it is not given. Header names are matched case-insensitively
+func optionalParameter(values map[string]string, name string, header bool) *string {
+
+	if value, ok := values[name]; ok {
+		return &value
+	}
+
+	if header {
+		for k, v := range values {
+			if strings.EqualFold(k, name) {
+				return &v
+			}
+		}
+	}
+
+	return nil
+}
+`},
+	{"enumParameter", `
+// Returns an error if a parameter is not one of its allowed values
+func enumParameter(name, value string, allowed ...string) models.ApiError {
+
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	return models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not one of %v", name, value, strings.Join(allowed, ", "))
+}
+`},
+	{"parseNumber", `
+func parseNumber(name, value string) (float64, models.ApiError) {
+
+	number, err := strconv.ParseFloat(value, 64)
+
+	if err != nil {
+		return 0, models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not a number", name, value)
+	}
+
+	return number, nil
+}
+`},
+	{"parseInteger", `
+func parseInteger(name, value string) (int, models.ApiError) {
+
+	integer, err := strconv.Atoi(value)
+
+	if err != nil {
+		return 0, models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not an integer", name, value)
+	}
+
+	return integer, nil
+}
+`},
+	{"parseBoolean", `
+func parseBoolean(name, value string) (bool, models.ApiError) {
+
+	boolean, err := strconv.ParseBool(value)
+
+	if err != nil {
+		return false, models.ConstructApiError(http.StatusBadRequest, "Invalid parameter %v: %v is not true or false", name, value)
+	}
+
+	return boolean, nil
+}
+`},
+}
+
+// Returns the imports and the source of the helpers used by the generated routes
+func usedHelpers(used map[string]bool) (imports []string, source string) {
+
+	if used["requiredParameter"] {
+		used["optionalParameter"] = true
+	}
+
+	buf := &strings.Builder{}
+
+	for _, helper := range routeHelpers {
+		if used[helper.name] {
+			buf.WriteString(helper.source)
+		}
+	}
+
+	source = buf.String()
+
+	for _, pkg := range []string{"net/http", "strconv", "strings"} {
+		if strings.Contains(source, path.Base(pkg)+".") {
+			imports = append(imports, pkg)
+		}
+	}
+
+	return imports, source
+}
+
+// GenerateRoutes generates a Go source file routing API Gateway proxy requests to the operations of the API which
+// integrate with a lambda. It declares an Operations interface with a method for every operation, which receives the
+// operation's parameters decoded into a struct if it has any, and an operationHandler function returning the handler
+// of a route such as GET/calc/{op}, or nil. The package must declare the innerHandler type of its handlers, and import
+// the models package for errors from modelsImport
+func GenerateRoutes(pkg, modelsImport string, doc Document) ([]byte, error) {
+
+	routes := integratedRoutes(doc)
+
+	methods := &strings.Builder{}
+	types := &strings.Builder{}
+	cases := &strings.Builder{}
+	used := map[string]bool{}
+
+	for _, route := range routes {
+
+		name := operationName(route)
+
+		fmt.Fprintf(methods, "\t// %v handles %v %v\n", name, route.Method, route.Path)
+
+		if len(route.Parameters) == 0 {
+			fmt.Fprintf(methods, "\t%v(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)\n", name)
+			fmt.Fprintf(cases, "\n\tcase %q:\n\t\treturn operations.%v\n", route.Key(), name)
+			continue
+		}
+
+		fmt.Fprintf(methods, "\t%v(ctx context.Context, request events.APIGatewayProxyRequest, params %vParams) (interface{}, models.ApiError)\n", name, name)
+
+		if err := writeParams(types, name, route, used); err != nil {
+			return nil, fmt.Errorf("Cannot generate %v %v: %v", route.Method, route.Path, err)
+		}
+
+		fmt.Fprintf(cases, `
+	case %q:
+		return func(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError) {
+
+			params, apiErr := decode%vParams(request)
+
+			if apiErr != nil {
+				return nil, apiErr
+			}
+
+			return operations.%v(ctx, request, params)
+		}
+`, route.Key(), name, name)
+	}
+
+	imports, helpers := usedHelpers(used)
+
+	for i, pkg := range imports {
+		imports[i] = fmt.Sprintf("\t%q\n", pkg)
+	}
+
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintf(buf, `%v
+
+package %v
+
+import (
+	"context"
+%v
+	"github.com/aws/aws-lambda-go/events"
+
+	%q
+)
+
+// Operations handles the operations of the API. Adding an operation to the API adds a method which must be implemented
+type Operations interface {
+%v}
+%v
+// Returns the handler of the operation of a route of the form METHOD/path, or nil if the API has no such operation
+func operationHandler(operations Operations, route string) innerHandler {
+
+	switch route {
+%v
+	}
+
+	return nil
+}
+%v`, header, pkg, strings.Join(imports, ""), modelsImport, methods, types, cases, helpers)
+
+	return format.Source(buf.Bytes())
+}
+
+// Returns the routes of the operations of the API which are not answered by API Gateway itself with a mock integration
+func integratedRoutes(doc Document) []Route {
+
+	var routes []Route
+
+	for _, route := range doc.Routes() {
+		if !route.Mock() {
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+// A parameter of a route with the Go field and type it is generated as
+type routeParameter struct {
+	Parameter
+	// The field of the parameter in the parameters struct
+	field string
+	// The Go type of the parameter, and the function of the routes which parses it
+	goType, parse string
+	// The allowed values of the parameter, if it is an enum
+	enum []string
+}
+
+// Returns the parameters of a route with their Go fields and types, or an error if any are not supported
+func routeParameters(route Route) ([]routeParameter, error) {
+
+	parameters := make([]routeParameter, len(route.Parameters))
+
+	for i, parameter := range route.Parameters {
+
+		if _, ok := parameterSources[parameter.In]; !ok {
+			return nil, fmt.Errorf("%v parameters are not supported", parameter.In)
+		}
+
+		kind, ok := parameterTypes[parameter.Type]
+
+		if !ok {
+			return nil, fmt.Errorf("parameter %v of type %v is not supported", parameter.Name, parameter.Type)
+		}
+
+		parameters[i] = routeParameter{
+			Parameter: parameter,
+			field:     GoName(parameter.Name),
+			goType:    kind.goType,
+			parse:     kind.parse,
+		}
+
+		for _, value := range parameter.Enum {
+
+			s, ok := value.(string)
+
+			if !ok || parameter.Type != "string" {
+				return nil, fmt.Errorf("parameter %v has an enum of type %v, but only string enums are supported", parameter.Name, parameter.Type)
+			}
+
+			parameters[i].enum = append(parameters[i].enum, s)
+		}
+	}
+
+	return parameters, nil
+}
+
+// Writes the parameters struct of a route
+func writeParamsStruct(buf *strings.Builder, name string, route Route, parameters []routeParameter) {
+
+	fmt.Fprintf(buf, "\n// %vParams are the parameters of %v %v\ntype %vParams struct {\n", name, route.Method, route.Path, name)
+
+	for _, parameter := range parameters {
+
+		description := fmt.Sprintf("%v in the %v", parameter.Name, parameter.In)
+
+		if parameter.Description != "" {
+			description += ": " + oneLine(parameter.Description)
+		}
+
+		if parameter.Required {
+			fmt.Fprintf(buf, "\t// %v\n\t%v %v\n", description, parameter.field, parameter.goType)
+			continue
+		}
+
+		fmt.Fprintf(buf, "\t// %v, or nil if it is not given\n\t%v *%v\n", description, parameter.field, parameter.goType)
+	}
+
+	buf.WriteString("}\n")
+}
+
+// Writes the parameters struct of a route and the function decoding them from a request, recording the helpers it uses
+func writeParams(buf *strings.Builder, name string, route Route, used map[string]bool) error {
+
+	parameters, err := routeParameters(route)
+
+	if err != nil {
+		return err
+	}
+
+	decode := &strings.Builder{}
+
+	for _, parameter := range parameters {
+
+		source := parameterSources[parameter.In]
+		field := parameter.field
+		raw := "raw" + field
+		header := parameter.In == "header"
+
+		if parameter.parse != "" {
+			used[parameter.parse] = true
+		}
+
+		if len(parameter.enum) > 0 {
+			used["enumParameter"] = true
+		}
+
+		if parameter.Required {
+
+			used["requiredParameter"] = true
+
+			if parameter.parse == "" {
+
+				fmt.Fprintf(decode, "\n\tif params.%v, apiErr = requiredParameter(%v, %q, %v); apiErr != nil {\n\t\treturn params, apiErr\n\t}\n",
+					field, source, parameter.Name, header)
+
+				if len(parameter.enum) > 0 {
+					fmt.Fprintf(decode, "\n\tif apiErr = enumParameter(%q, params.%v, %v); apiErr != nil {\n\t\treturn params, apiErr\n\t}\n",
+						parameter.Name, field, quotedList(parameter.enum))
+				}
+
+				continue
+			}
+
+			fmt.Fprintf(decode, "\n\t%v, apiErr := requiredParameter(%v, %q, %v)\n\n\tif apiErr != nil {\n\t\treturn params, apiErr\n\t}\n", raw, source, parameter.Name, header)
+			fmt.Fprintf(decode, "\n\tif params.%v, apiErr = %v(%q, %v); apiErr != nil {\n\t\treturn params, apiErr\n\t}\n", field, parameter.parse, parameter.Name, raw)
+			continue
+		}
+
+		used["optionalParameter"] = true
+
+		if parameter.parse == "" {
+
+			if len(parameter.enum) == 0 {
+				fmt.Fprintf(decode, "\n\tparams.%v = optionalParameter(%v, %q, %v)\n", field, source, parameter.Name, header)
+				continue
+			}
+
+			fmt.Fprintf(decode, `
+	if params.%v = optionalParameter(%v, %q, %v); params.%v != nil {
+		if apiErr = enumParameter(%q, *params.%v, %v); apiErr != nil {
+			return params, apiErr
+		}
+	}
+`, field, source, parameter.Name, header, field, parameter.Name, field, quotedList(parameter.enum))
+			continue
+		}
+
+		value := "parsed" + field
+
+		fmt.Fprintf(decode, `
+	if %v := optionalParameter(%v, %q, %v); %v != nil {
+
+		%v, apiErr := %v(%q, *%v)
+
+		if apiErr != nil {
+			return params, apiErr
+		}
+
+		params.%v = &%v
+	}
+`, raw, source, parameter.Name, header, raw, value, parameter.parse, parameter.Name, raw, field, value)
+	}
+
+	writeParamsStruct(buf, name, route, parameters)
+
+	fmt.Fprintf(buf, `
+// Decodes the parameters of %v %v, returning a bad request error if any are missing or invalid
+func decode%vParams(request events.APIGatewayProxyRequest) (params %vParams, apiErr models.ApiError) {
+%v
+	return params, nil
+}
+`, route.Method, route.Path, name, name, decode)
+
+	return nil
+}
+
+// Returns strings as a list of Go string literals
+func quotedList(values []string) string {
+
+	quoted := make([]string, len(values))
+
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// Returns the method name of an operation: its operation ID, or its method and path, such as GetCalcOp
+func operationName(route Route) string {
+
+	if route.OperationID != "" {
+		return GoName(route.OperationID)
+	}
+
+	name := GoName(strings.ToLower(route.Method))
+
+	for _, segment := range strings.Split(route.Path, "/") {
+		name += GoName(segment)
+	}
+
+	if name == GoName(strings.ToLower(route.Method)) {
+		name += "Root"
+	}
+
+	return name
+}
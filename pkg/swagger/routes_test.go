@@ -0,0 +1,84 @@
+package swagger
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const routesDefinition = `{
+	"swagger": "2.0",
+	"info": {"title": "Sample-API-test", "version": "test"},
+	"paths": {
+		"/orders/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "type": "integer"}],
+			"get": {
+				"operationId": "getOrder",
+				"parameters": [
+					{"name": "lines", "in": "query", "type": "boolean", "description": "Whether to include\nthe lines"},
+					{"name": "X-Trace", "in": "header", "type": "string", "enum": ["on", "off"]}
+				],
+				"responses": {"200": {"description": "200 response"}}
+			},
+			"options": {
+				"responses": {"200": {"description": "200 response"}},
+				"x-amazon-apigateway-integration": {"type": "mock"}
+			}
+		},
+		"/": {
+			"get": {"responses": {"200": {"description": "200 response"}}}
+		}
+	}
+}`
+
+func TestGenerateRoutes(t *testing.T) {
+
+	doc, err := ParseJSON([]byte(routesDefinition))
+
+	utils.AssertNoError(t, "Parse", err)
+	utils.AssertEquals(t, "Routes", 3, len(doc.Routes()))
+
+	raw, err := GenerateRoutes("front", "example.com/models", doc)
+
+	utils.AssertNoError(t, "Generate", err)
+
+	routes := string(raw)
+
+	for _, expected := range []string{
+		"GetRoot(ctx context.Context, request events.APIGatewayProxyRequest) (interface{}, models.ApiError)\n",
+		"GetOrder(ctx context.Context, request events.APIGatewayProxyRequest, params GetOrderParams) (interface{}, models.ApiError)\n",
+		"\t// id in the path\n\tId int\n",
+		"\t// lines in the query: Whether to include the lines, or nil if it is not given\n\tLines *bool\n",
+		"\tXTrace *string\n",
+		"\tif params.Id, apiErr = parseInteger(\"id\", rawId); apiErr != nil {\n",
+		"\tif rawLines := optionalParameter(request.QueryStringParameters, \"lines\", false); rawLines != nil {\n",
+		"\tif params.XTrace = optionalParameter(request.Headers, \"X-Trace\", true); params.XTrace != nil {\n" +
+			"\t\tif apiErr = enumParameter(\"X-Trace\", *params.XTrace, \"on\", \"off\"); apiErr != nil {\n",
+		"func parseInteger(",
+		"func parseBoolean(",
+		"\tcase \"GET/\":\n\t\treturn operations.GetRoot\n",
+		"\tcase \"GET/orders/{id}\":\n",
+		"\t\"example.com/models\"\n",
+	} {
+		utils.AssertTrue(t, "Routes contain "+expected, strings.Contains(routes, expected))
+	}
+
+	utils.AssertFalse(t, "Mock integration not routed", strings.Contains(routes, "OPTIONS"))
+	utils.AssertFalse(t, "Unused helper not generated", strings.Contains(routes, "parseNumber"))
+
+	doc.Paths["/orders/{id}"].Parameters[0].Enum = []interface{}{1, 2}
+
+	_, err = GenerateRoutes("front", "example.com/models", doc)
+
+	utils.AssertErrorEquals(t, "Integer enum",
+		"Cannot generate GET /orders/{id}: parameter id has an enum of type integer, but only string enums are supported", err)
+
+	doc.Paths["/orders/{id}"].Parameters[0].Enum = nil
+
+	doc.Paths["/orders/{id}"].Get.Parameters = []Parameter{{Name: "order", In: "body"}}
+
+	_, err = GenerateRoutes("front", "example.com/models", doc)
+
+	utils.AssertErrorEquals(t, "Body parameter", "Cannot generate GET /orders/{id}: body parameters are not supported", err)
+}
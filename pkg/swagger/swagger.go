@@ -0,0 +1,193 @@
+// The swagger package reads Swagger 2.0 API definitions, converts them to and from OpenAPI 3, and generates Go code
+// from them
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The prefix of references to definitions
+const definitionRef = "#/definitions/"
+
+// Document is a Swagger 2.0 API definition. Only the parts code is generated from are parsed
+type Document struct {
+	Swagger     string              `json:"swagger" yaml:"swagger"`
+	Info        Info                `json:"info" yaml:"info"`
+	Paths       map[string]PathItem `json:"paths" yaml:"paths"`
+	Definitions map[string]*Schema  `json:"definitions" yaml:"definitions"`
+}
+
+// Info describes an API
+type Info struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// The HTTP methods of path item operations, in the order they are listed
+var methods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH"}
+
+// PathItem is the operations of a path
+type PathItem struct {
+	Get     *Operation `json:"get,omitempty" yaml:"get,omitempty"`
+	Put     *Operation `json:"put,omitempty" yaml:"put,omitempty"`
+	Post    *Operation `json:"post,omitempty" yaml:"post,omitempty"`
+	Delete  *Operation `json:"delete,omitempty" yaml:"delete,omitempty"`
+	Options *Operation `json:"options,omitempty" yaml:"options,omitempty"`
+	Head    *Operation `json:"head,omitempty" yaml:"head,omitempty"`
+	Patch   *Operation `json:"patch,omitempty" yaml:"patch,omitempty"`
+	// Parameters of every operation of the path
+	Parameters []Parameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// Operation returns the operation of an upper case HTTP method, or nil
+func (item PathItem) Operation(method string) *Operation {
+
+	switch method {
+	case "GET":
+		return item.Get
+	case "PUT":
+		return item.Put
+	case "POST":
+		return item.Post
+	case "DELETE":
+		return item.Delete
+	case "OPTIONS":
+		return item.Options
+	case "HEAD":
+		return item.Head
+	case "PATCH":
+		return item.Patch
+	}
+
+	return nil
+}
+
+// Operation is an API operation
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Description string              `json:"description,omitempty" yaml:"description,omitempty"`
+	Produces    []string            `json:"produces,omitempty" yaml:"produces,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses" yaml:"responses"`
+	// How API Gateway integrates the operation with its backend
+	Integration *Integration `json:"x-amazon-apigateway-integration,omitempty" yaml:"x-amazon-apigateway-integration,omitempty"`
+}
+
+// Integration is the API Gateway integration of an operation. Only its type is parsed
+type Integration struct {
+	// aws_proxy for a lambda proxy, or mock for a response from API Gateway itself
+	Type string `json:"type" yaml:"type"`
+}
+
+// Parameter is an operation parameter
+type Parameter struct {
+	Name        string        `json:"name" yaml:"name"`
+	In          string        `json:"in" yaml:"in"`
+	Description string        `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool          `json:"required,omitempty" yaml:"required,omitempty"`
+	Type        string        `json:"type,omitempty" yaml:"type,omitempty"`
+	Format      string        `json:"format,omitempty" yaml:"format,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+// Response is an operation response
+type Response struct {
+	Description string  `json:"description" yaml:"description"`
+	Schema      *Schema `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// Route is an operation and where it is
+type Route struct {
+	Method string
+	Path   string
+	// The parameters of the path and the operation
+	Parameters []Parameter
+	*Operation
+}
+
+// Key returns the route in the form Front routes requests by, such as GET/calc/{op}
+func (route Route) Key() string {
+	return route.Method + route.Path
+}
+
+// Mock returns whether API Gateway answers the operation itself with a mock integration, rather than the lambda
+func (route Route) Mock() bool {
+	return route.Integration != nil && route.Integration.Type == "mock"
+}
+
+// Routes returns the operations of the API in path and method order
+func (doc Document) Routes() []Route {
+
+	paths := make([]string, 0, len(doc.Paths))
+
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	var routes []Route
+
+	for _, path := range paths {
+
+		item := doc.Paths[path]
+
+		for _, method := range methods {
+			if operation := item.Operation(method); operation != nil {
+				routes = append(routes, Route{
+					Method:     method,
+					Path:       path,
+					Parameters: append(append([]Parameter{}, item.Parameters...), operation.Parameters...),
+					Operation:  operation,
+				})
+			}
+		}
+	}
+
+	return routes
+}
+
+// Schema is a Swagger schema
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Title                string             `json:"title,omitempty" yaml:"title,omitempty"`
+	Description          string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Required             []string           `json:"required,omitempty" yaml:"required,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// RefName returns the name of the definition a schema refers to, or empty if it is not a reference to a definition
+func (schema Schema) RefName() string {
+
+	if !strings.HasPrefix(schema.Ref, definitionRef) {
+		return ""
+	}
+
+	return strings.TrimPrefix(schema.Ref, definitionRef)
+}
+
+// ParseJSON parses a Swagger 2.0 API definition in JSON
+func ParseJSON(raw []byte) (Document, error) {
+
+	var doc Document
+
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return doc, fmt.Errorf("Invalid Swagger definition: %v", err)
+	}
+
+	if doc.Swagger != "2.0" {
+		return doc, fmt.Errorf("Invalid Swagger definition: version is %q, not 2.0", doc.Swagger)
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,207 @@
+package swagger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The resource types whose definition body is a Swagger or OpenAPI definition
+var apiTypes = map[string]string{
+	"AWS::Serverless::Api":     "DefinitionBody",
+	"AWS::ApiGateway::RestApi": "Body",
+}
+
+var subVariable = regexp.MustCompile(`\$\{([^}!]+)\}`)
+
+// FromTemplate returns the Swagger or OpenAPI definition of the API in a CloudFormation template as JSON, without deploying it.
+// CloudFormation intrinsic functions are resolved with placeholder values: a reference to a template parameter is
+// replaced by its value in values or its default, and any other reference by its name in braces, such as
+// {AWS::Region}
+func FromTemplate(template []byte, values map[string]string) ([]byte, error) {
+
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(template, &doc); err != nil {
+		return nil, errors.New("Invalid template: " + err.Error())
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, errors.New("Invalid template: it is empty")
+	}
+
+	root := doc.Content[0]
+	resolver := intrinsicResolver{values: map[string]string{}}
+
+	if parameters := mappingValue(root, "Parameters"); parameters != nil {
+		for i := 0; i+1 < len(parameters.Content); i += 2 {
+			if value := mappingValue(parameters.Content[i+1], "Default"); value != nil {
+				resolver.values[parameters.Content[i].Value] = value.Value
+			}
+		}
+	}
+
+	for name, value := range values {
+		resolver.values[name] = value
+	}
+
+	resources := mappingValue(root, "Resources")
+
+	for i := 1; resources != nil && i < len(resources.Content); i += 2 {
+
+		resource := resources.Content[i]
+		kind := mappingValue(resource, "Type")
+
+		if kind == nil {
+			continue
+		}
+
+		if body := mappingValue(mappingValue(resource, "Properties"), apiTypes[kind.Value]); body != nil {
+
+			definition, err := resolver.resolve(body)
+
+			if err != nil {
+				return nil, fmt.Errorf("Invalid definition of %v: %v", resources.Content[i-1].Value, err)
+			}
+
+			return json.MarshalIndent(definition, "", "  ")
+		}
+	}
+
+	return nil, errors.New("Invalid template: it has no API resource with a definition body")
+}
+
+// ParseTemplate returns the Swagger 2.0 or OpenAPI 3 definition of the API in a CloudFormation template, as
+// FromTemplate, as a Swagger 2.0 Document
+func ParseTemplate(template []byte, values map[string]string) (Document, error) {
+
+	raw, err := FromTemplate(template, values)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	return Parse(raw)
+}
+
+// Resolves CloudFormation intrinsic functions in YAML with placeholder values
+type intrinsicResolver struct {
+	values map[string]string
+}
+
+func (resolver intrinsicResolver) value(name string) string {
+
+	if value, ok := resolver.values[name]; ok {
+		return value
+	}
+
+	return "{" + name + "}"
+}
+
+func (resolver intrinsicResolver) sub(s string) string {
+	return subVariable.ReplaceAllStringFunc(s, func(variable string) string {
+		return resolver.value(strings.TrimSpace(variable[2 : len(variable)-1]))
+	})
+}
+
+// Returns the value of a node as JSON-compatible Go values
+func (resolver intrinsicResolver) resolve(node *yaml.Node) (interface{}, error) {
+
+	switch node.Tag {
+	case "!Sub":
+		if node.Kind == yaml.SequenceNode && len(node.Content) > 0 {
+			return resolver.sub(node.Content[0].Value), nil
+		}
+		return resolver.sub(node.Value), nil
+	case "!Ref":
+		return resolver.value(node.Value), nil
+	case "!GetAtt":
+		if node.Kind == yaml.SequenceNode {
+			var parts []string
+			for _, part := range node.Content {
+				parts = append(parts, part.Value)
+			}
+			return resolver.value(strings.Join(parts, ".")), nil
+		}
+		return resolver.value(node.Value), nil
+	}
+
+	// Standard tags start with !!
+	if strings.HasPrefix(node.Tag, "!") && !strings.HasPrefix(node.Tag, "!!") {
+		return nil, fmt.Errorf("%v at line %v is not supported", node.Tag, node.Line)
+	}
+
+	switch node.Kind {
+	case yaml.AliasNode:
+		return resolver.resolve(node.Alias)
+	case yaml.SequenceNode:
+		list := make([]interface{}, len(node.Content))
+		for i, item := range node.Content {
+			value, err := resolver.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = value
+		}
+		return list, nil
+	case yaml.MappingNode:
+		return resolver.resolveMapping(node)
+	}
+
+	var value interface{}
+
+	err := node.Decode(&value)
+
+	return value, err
+}
+
+func (resolver intrinsicResolver) resolveMapping(node *yaml.Node) (interface{}, error) {
+
+	// The long forms of the intrinsic functions
+	if len(node.Content) == 2 {
+
+		value := node.Content[1]
+
+		switch node.Content[0].Value {
+		case "Fn::Sub", "Ref", "Fn::GetAtt":
+			short := *value
+			short.Tag = "!" + strings.TrimPrefix(node.Content[0].Value, "Fn::")
+			return resolver.resolve(&short)
+		}
+	}
+
+	mapping := make(map[string]interface{}, len(node.Content)/2)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+
+		value, err := resolver.resolve(node.Content[i+1])
+
+		if err != nil {
+			return nil, err
+		}
+
+		mapping[node.Content[i].Value] = value
+	}
+
+	return mapping, nil
+}
+
+// Returns the value of a key in a YAML mapping, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
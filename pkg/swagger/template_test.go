@@ -0,0 +1,79 @@
+package swagger
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+const testTemplate = `
+Parameters:
+  Platform:
+    Type: String
+  Owner:
+    Type: String
+    Default: platform-team
+Resources:
+  Function:
+    Type: AWS::Serverless::Function
+  Api:
+    Type: AWS::Serverless::Api
+    Properties:
+      DefinitionBody:
+        swagger: "2.0"
+        info:
+          title: !Sub Sample-API-${Platform}
+          version: !Ref Platform
+          description:
+            Fn::Sub: "Owned by ${Owner} in ${AWS::Region}"
+        x-function: !GetAtt Function.Arn
+        x-function-list: !GetAtt [Function, Arn]
+        paths: {}
+        definitions:
+          Empty:
+            type: object
+`
+
+func TestFromTemplate(t *testing.T) {
+
+	raw, err := FromTemplate([]byte(testTemplate), map[string]string{"Platform": "live"})
+
+	utils.AssertNoError(t, "From template", err)
+
+	var definition map[string]interface{}
+
+	err = json.Unmarshal(raw, &definition)
+
+	utils.AssertNoError(t, "JSON", err)
+
+	info := definition["info"].(map[string]interface{})
+
+	utils.AssertEquals(t, "Sub", "Sample-API-live", info["title"])
+	utils.AssertEquals(t, "Ref", "live", info["version"])
+	utils.AssertEquals(t, "Long form and default", "Owned by platform-team in {AWS::Region}", info["description"])
+	utils.AssertEquals(t, "GetAtt", "{Function.Arn}", definition["x-function"])
+	utils.AssertEquals(t, "GetAtt list", "{Function.Arn}", definition["x-function-list"])
+
+	doc, err := ParseTemplate([]byte(testTemplate), nil)
+
+	utils.AssertNoError(t, "Parse template", err)
+	utils.AssertEquals(t, "Placeholder", "Sample-API-{Platform}", doc.Info.Title)
+	utils.AssertEquals(t, "Definitions", 1, len(doc.Definitions))
+
+	_, err = FromTemplate([]byte(`
+Resources:
+  Api:
+    Type: AWS::Serverless::Api
+    Properties:
+      DefinitionBody:
+        info:
+          title: !If [IsLive, Live, Test]
+`), nil)
+
+	utils.AssertErrorEquals(t, "Unsupported function", "Invalid definition of Api: !If at line 8 is not supported", err)
+
+	_, err = FromTemplate([]byte("Resources: {}"), nil)
+
+	utils.AssertErrorEquals(t, "No API", "Invalid template: it has no API resource with a definition body", err)
+}
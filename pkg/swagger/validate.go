@@ -0,0 +1,169 @@
+package swagger
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Validate returns the ways a value decoded from JSON does not conform to a schema of the document, each starting
+// with where it is in the value, such as $.checks.lambda[0].status. Properties which an object schema does not
+// define are reported as undocumented unless it has additionalProperties
+func (doc Document) Validate(value interface{}, schema *Schema) []string {
+
+	var problems []string
+
+	doc.validate("$", value, schema, &problems)
+
+	return problems
+}
+
+func (doc Document) validate(path string, value interface{}, schema *Schema, problems *[]string) {
+
+	if schema == nil {
+		return
+	}
+
+	if schema.Ref != "" {
+
+		definition := doc.Definitions[schema.RefName()]
+
+		if definition == nil {
+			*problems = append(*problems, fmt.Sprintf("%v has the schema %v, which is not a definition", path, schema.Ref))
+			return
+		}
+
+		doc.validate(path, value, definition, problems)
+		return
+	}
+
+	if !hasType(value, schema.Type) {
+		*problems = append(*problems, fmt.Sprintf("%v is %v, not %v", path, describe(value), article(schema.Type)))
+		return
+	}
+
+	if len(schema.Enum) > 0 && !inEnum(value, schema.Enum) {
+		*problems = append(*problems, fmt.Sprintf("%v is %v, which is not one of %v", path, describe(value), enumList(schema.Enum)))
+	}
+
+	switch value := value.(type) {
+	case []interface{}:
+		for i, item := range value {
+			doc.validate(fmt.Sprintf("%v[%v]", path, i), item, schema.Items, problems)
+		}
+	case map[string]interface{}:
+		doc.validateObject(path, value, schema, problems)
+	}
+}
+
+func (doc Document) validateObject(path string, value map[string]interface{}, schema *Schema, problems *[]string) {
+
+	for _, name := range schema.Required {
+		if _, ok := value[name]; !ok {
+			*problems = append(*problems, fmt.Sprintf("%v.%v is required but missing", path, name))
+		}
+	}
+
+	names := make([]string, 0, len(value))
+
+	for name := range value {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+
+		property, ok := schema.Properties[name]
+
+		if !ok {
+			property = schema.AdditionalProperties
+		}
+
+		// An object without properties, such as Empty, may have any
+		if property == nil && len(schema.Properties) > 0 {
+			*problems = append(*problems, fmt.Sprintf("%v.%v is not documented", path, name))
+			continue
+		}
+
+		doc.validate(path+"."+name, value[name], property, problems)
+	}
+}
+
+// Returns whether a value decoded from JSON has a schema type, which is any type if empty
+func hasType(value interface{}, kind string) bool {
+
+	switch kind {
+	case "":
+		return true
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		number, ok := value.(float64)
+		return ok && number == math.Trunc(number)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	}
+
+	return false
+}
+
+func inEnum(value interface{}, enum []interface{}) bool {
+
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func enumList(enum []interface{}) string {
+
+	values := make([]string, len(enum))
+
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+
+	return strings.Join(values, ", ")
+}
+
+// Describes a value decoded from JSON in a message
+func describe(value interface{}) string {
+
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", value)
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	}
+
+	return fmt.Sprint(value)
+}
+
+func article(kind string) string {
+
+	if strings.IndexAny(kind[:1], "aeiou") == 0 {
+		return "an " + kind
+	}
+
+	return "a " + kind
+}
@@ -0,0 +1,58 @@
+package swagger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestValidate(t *testing.T) {
+
+	doc := Document{
+		Definitions: map[string]*Schema{
+			"Health": {
+				Type:     "object",
+				Required: []string{"status"},
+				Properties: map[string]*Schema{
+					"status": {Type: "string", Enum: []interface{}{"pass", "fail"}},
+					"checks": {Type: "object", AdditionalProperties: &Schema{Type: "array", Items: &Schema{Ref: "#/definitions/Check"}}},
+				},
+			},
+			"Check": {
+				Type:       "object",
+				Properties: map[string]*Schema{"observedValue": {Type: "number"}, "attempts": {Type: "integer"}},
+			},
+			"Empty": {Type: "object"},
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		body     string
+		schema   string
+		expected []string
+	}{
+		{"Valid", `{"status": "pass", "checks": {"lambda": [{"observedValue": 1.5, "attempts": 2}]}}`, "Health", nil},
+		{"Missing", `{}`, "Health", []string{"$.status is required but missing"}},
+		{"Enum", `{"status": "ok"}`, "Health", []string{`$.status is "ok", which is not one of pass, fail`}},
+		{"Nested", `{"status": "fail", "checks": {"lambda": [{"observedValue": "1", "attempts": 1.5}]}}`, "Health", []string{
+			"$.checks.lambda[0].attempts is 1.5, not an integer",
+			`$.checks.lambda[0].observedValue is "1", not a number`,
+		}},
+		{"Undocumented", `{"status": "pass", "version": "1"}`, "Health", []string{"$.version is not documented"}},
+		{"Type", `[]`, "Health", []string{"$ is an array, not an object"}},
+		{"Any properties", `{"anything": null}`, "Empty", nil},
+		{"Unknown definition", `{}`, "Missing", []string{"$ has the schema #/definitions/Missing, which is not a definition"}},
+	} {
+
+		var value interface{}
+
+		utils.AssertNoError(t, test.name+" JSON", json.Unmarshal([]byte(test.body), &value))
+
+		problems := doc.Validate(value, &Schema{Ref: definitionRef + test.schema})
+
+		utils.AssertEquals(t, test.name, strings.Join(test.expected, "\n"), strings.Join(problems, "\n"))
+	}
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TraceparentHeader = "traceparent"
+	XRayHeader        = "X-Amzn-Trace-Id"
+)
+
+// Extract returns a context carrying the remote span context of W3C traceparent or, failing that, AWS X-Ray trace
+// headers, so that the next span started from it continues the trace. Header names are matched case-insensitively.
+// The context is returned unchanged if neither header parses
+func Extract(ctx context.Context, headers map[string]string) context.Context {
+
+	// The carrier is looked up by lower case names
+	carrier := propagation.MapCarrier{}
+
+	for k, v := range headers {
+		carrier[strings.ToLower(k)] = v
+	}
+
+	if extracted := (propagation.TraceContext{}).Extract(ctx, carrier); trace.SpanContextFromContext(extracted).IsValid() {
+		return extracted
+	}
+
+	if sc, ok := ParseXRay(carrier.Get(strings.ToLower(XRayHeader))); ok {
+		return trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+
+	return ctx
+}
+
+// ParseXRay parses an X-Amzn-Trace-Id header value such as
+// Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1
+//
+// The X-Ray root's timestamp and random parts together form the 16-byte trace ID
+func ParseXRay(value string) (trace.SpanContext, bool) {
+
+	config := trace.SpanContextConfig{TraceFlags: trace.FlagsSampled, Remote: true}
+
+	for _, field := range strings.Split(value, ";") {
+
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+
+		case "Root":
+
+			root := strings.Split(kv[1], "-")
+
+			if len(root) != 3 || root[0] != "1" || !decodeHex(root[1]+root[2], config.TraceID[:]) {
+				return trace.SpanContext{}, false
+			}
+
+		case "Parent":
+
+			if !decodeHex(kv[1], config.SpanID[:]) {
+				return trace.SpanContext{}, false
+			}
+
+		case "Sampled":
+
+			if kv[1] == "0" {
+				config.TraceFlags = 0
+			}
+		}
+	}
+
+	sc := trace.NewSpanContext(config)
+
+	return sc, sc.IsValid()
+}
+
+func decodeHex(s string, dst []byte) bool {
+
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return false
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+
+	return err == nil
+}
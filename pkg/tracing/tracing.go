@@ -0,0 +1,28 @@
+// The tracing package sets up OpenTelemetry tracing for the lambda: a tracer provider which exports each span as it
+// ends, as a JSON line to standard output or to any other OpenTelemetry exporter, and the remote parent of a request
+// from W3C traceparent or AWS X-Ray headers. Spans are created and annotated with the OpenTelemetry trace API.
+package tracing
+
+import (
+	"io"
+
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// The name of the instrumentation which traces requests, for TracerProvider.Tracer
+const InstrumentationName = "github.com/merlincox/aws-api-gateway-deploy/api/front"
+
+// NewProvider returns a tracer provider which samples every request unless its remote parent was not sampled, and
+// exports each span synchronously as it ends, as a lambda may be frozen as soon as an invocation returns
+func NewProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+}
+
+// NewWriterExporter returns an exporter which writes each span as a line of JSON, e.g. to os.Stdout for CloudWatch logs
+func NewWriterExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
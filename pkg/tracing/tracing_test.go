@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/merlincox/aws-api-gateway-deploy/pkg/utils"
+)
+
+func TestRemoteParent(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := NewProvider(exporter).Tracer(InstrumentationName)
+
+	ctx := Extract(context.Background(), map[string]string{
+		"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})
+
+	_, span := tracer.Start(ctx, "handler")
+	span.End()
+
+	spans := exporter.GetSpans()
+
+	utils.AssertEquals(t, "Number of exported spans", 1, len(spans))
+	utils.AssertEquals(t, "Remote trace ID", "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext.TraceID().String())
+	utils.AssertEquals(t, "Remote parent ID", "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	utils.AssertTrue(t, "Remote parent", spans[0].Parent.IsRemote())
+
+	unsampled := Extract(context.Background(), map[string]string{
+		TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+	})
+
+	exporter.Reset()
+	_, span = tracer.Start(unsampled, "handler")
+	span.End()
+
+	utils.AssertEquals(t, "Unsampled spans are not exported", 0, len(exporter.GetSpans()))
+
+	_, span = tracer.Start(Extract(context.Background(), nil), "root")
+	span.End()
+
+	utils.AssertFalse(t, "Root span without headers", exporter.GetSpans()[0].Parent.IsValid())
+}
+
+func TestExtractXRay(t *testing.T) {
+
+	sc := trace.SpanContextFromContext(Extract(context.Background(), map[string]string{
+		"x-amzn-trace-id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	}))
+
+	utils.AssertTrue(t, "X-Ray span context is valid", sc.IsValid())
+	utils.AssertEquals(t, "X-Ray trace ID", "5759e988bd862e3fe1be46a994272793", sc.TraceID().String())
+	utils.AssertEquals(t, "X-Ray parent ID", "53995c3f42cd8ad8", sc.SpanID().String())
+	utils.AssertTrue(t, "X-Ray sampled", sc.IsSampled())
+	utils.AssertTrue(t, "X-Ray remote", sc.IsRemote())
+
+	sc, ok := ParseXRay("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0")
+
+	utils.AssertTrue(t, "Unsampled X-Ray header parses", ok)
+	utils.AssertFalse(t, "X-Ray not sampled", sc.IsSampled())
+
+	_, ok = ParseXRay("Root=1-5759e988-bd862e3fe1be46a994272793")
+
+	utils.AssertFalse(t, "Root-only X-Ray header has no parent", ok)
+
+	sc = trace.SpanContextFromContext(Extract(context.Background(), map[string]string{
+		"traceparent":     "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+	}))
+
+	utils.AssertEquals(t, "Traceparent takes precedence", "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+}
+
+func TestWriterExporter(t *testing.T) {
+
+	buf := &bytes.Buffer{}
+	exporter, err := NewWriterExporter(buf)
+
+	utils.AssertNoError(t, "Writer exporter", err)
+
+	_, span := NewProvider(exporter).Tracer(InstrumentationName).Start(context.Background(), "written")
+	span.End()
+
+	var data struct {
+		Name string
+	}
+
+	utils.AssertEquals(t, "One line per span", 1, bytes.Count(buf.Bytes(), []byte("\n")))
+	utils.AssertNoError(t, "Written span parses", json.Unmarshal(buf.Bytes(), &data))
+	utils.AssertEquals(t, "Written span name", "written", data.Name)
+}
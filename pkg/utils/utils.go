@@ -3,6 +3,7 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -26,15 +27,28 @@ func JsonStringify(data interface{}) string {
 	return string(raw[:])
 }
 
-// Converts stack and panic message into JSON for readability on a single log line
-func JsonStack(panicMsg interface{}, rawTrace []byte) string {
+// Renders a panic value of any type as a message: errors and Stringers by their methods, other values with %#v.
+// Errors and Stringers are rendered with fmt, which prints a nil pointer as <nil> and recovers from methods which
+// panic, so rendering the message never panics itself
+func PanicMessage(panicVal interface{}) string {
+
+	switch v := panicVal.(type) {
 
-	msg, ok := panicMsg.(string)
+	case string:
+		return v
 
-	if !ok {
-		msg = "Unprintable"
+	case error, fmt.Stringer:
+		return fmt.Sprint(v)
 	}
 
+	return fmt.Sprintf("%#v", panicVal)
+}
+
+// Converts stack and panic message into JSON for readability on a single log line
+func JsonStack(panicMsg interface{}, rawTrace []byte) string {
+
+	msg := PanicMessage(panicMsg)
+
 	trace := strings.Replace(string(rawTrace), "\t", "", -1)
 
 	lines := strings.Split(trace, "\n")
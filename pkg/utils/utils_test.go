@@ -3,6 +3,8 @@ package utils
 import (
 	"testing"
 	"encoding/json"
+	"errors"
+	"strings"
 )
 
 func TestSlug(t *testing.T) {
@@ -65,10 +67,37 @@ func TestJsonStack(t *testing.T) {
 	AssertEquals(t, "JsonStrack returns correct number of stack lines:", 4, len(traceData.Stack))
 	AssertEquals(t, "JsonStrack correctly strips lines", "line2", traceData.Stack[1])
 
-	out2 := JsonStack(func(){}, mockStack)
+	out2 := JsonStack(errors.New("error panic"), mockStack)
 
 	err2 := json.Unmarshal([]byte(out2), &traceData)
 	AssertNoError(t, "JsonStack parses without error", err2)
-	AssertEquals(t, "JsonStrack returns correct unprintable message:", "Unprintable", traceData.Panic)
+	AssertEquals(t, "JsonStrack returns correct error message:", "error panic", traceData.Panic)
 
 }
+
+type testStringer struct{}
+
+func (testStringer) String() string {
+	return "stringer panic"
+}
+
+type testError struct {
+	message string
+}
+
+func (e testError) Error() string {
+	return e.message
+}
+
+func TestPanicMessage(t *testing.T) {
+
+	var nilError *testError
+
+	AssertEquals(t, "String panic message", "panic", PanicMessage("panic"))
+	AssertEquals(t, "Error panic message", "error panic", PanicMessage(errors.New("error panic")))
+	AssertEquals(t, "Stringer panic message", "stringer panic", PanicMessage(testStringer{}))
+	AssertEquals(t, "Int panic message", "42", PanicMessage(42))
+	AssertEquals(t, "Struct panic message", `struct { Field string }{Field:"value"}`, PanicMessage(struct{ Field string }{Field: "value"}))
+	AssertTrue(t, "Func panic message", strings.HasPrefix(PanicMessage(func() {}), "(func())"))
+	AssertEquals(t, "Typed nil error panic message", "<nil>", PanicMessage(error(nilError)))
+}